@@ -0,0 +1,154 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+)
+
+func assetGroupsCommand() *command {
+	return &command{
+		name:    "asset-groups",
+		summary: "Inspect asset groups",
+		subcommands: []*command{
+			assetGroupsListCommand(),
+			assetGroupsMembersCommand(),
+		},
+	}
+}
+
+func listAssetGroups(ctx context.Context, client *sdk.ClientWithResponses) ([]sdk.ModelAssetGroup, error) {
+	response, err := client.ListAssetGroupsWithResponse(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, statusError("ListAssetGroups", response.StatusCode(), response.Body)
+	}
+	if response.JSON200.Data == nil || response.JSON200.Data.AssetGroups == nil {
+		return nil, nil
+	}
+	return *response.JSON200.Data.AssetGroups, nil
+}
+
+func assetGroupsListCommand() *command {
+	return &command{
+		name:    "list",
+		summary: "List asset groups",
+		run: func(ctx context.Context, a *app, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			groups, err := listAssetGroups(ctx, client)
+			if err != nil {
+				return err
+			}
+			t := newTable("ID", "NAME", "TAG", "MEMBERS", "SELECTORS", "SYSTEM")
+			for _, group := range groups {
+				selectors := 0
+				if group.Selectors != nil {
+					selectors = len(*group.Selectors)
+				}
+				t.add(integer(group.Id), str(group.Name), str(group.Tag), integer(group.MemberCount), strconv.Itoa(selectors), boolean(group.SystemGroup))
+			}
+			return a.render(groups, t)
+		},
+	}
+}
+
+func assetGroupsMembersCommand() *command {
+	var limit int
+	return &command{
+		name:    "members",
+		args:    "ASSET_GROUP_ID",
+		summary: "List the members of an asset group",
+		flags: func(fs *flag.FlagSet) {
+			fs.IntVar(&limit, "limit", 100, "maximum number of members to list")
+		},
+		complete: func(a *app, args []string) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			client, err := a.client()
+			if err != nil {
+				return nil
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+			defer cancel()
+			groups, err := listAssetGroups(ctx, client)
+			if err != nil {
+				return nil
+			}
+			var ids []string
+			for _, group := range groups {
+				ids = append(ids, integer(group.Id))
+			}
+			return ids
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "an asset group id"); err != nil {
+				return err
+			}
+			id, err := strconv.ParseInt(args[0], 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid asset group id %q", args[0])
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			response, err := client.ListAssetGroupMembersWithResponse(ctx, int32(id), &sdk.ListAssetGroupMembersParams{
+				Limit:  &limit,
+				SortBy: ptr("name"),
+			})
+			if err != nil {
+				return err
+			}
+			if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+				return statusError("ListAssetGroupMembers", response.StatusCode(), response.Body)
+			}
+			var members []sdk.ModelAssetGroupMember
+			if response.JSON200.Data != nil && response.JSON200.Data.Members != nil {
+				members = *response.JSON200.Data.Members
+			}
+
+			t := newTable("OBJECT ID", "NAME", "KIND", "ENVIRONMENT", "CUSTOM")
+			for _, member := range members {
+				kind := str(member.PrimaryKind)
+				if kind == "" && member.Kinds != nil {
+					kind = strings.Join(*member.Kinds, ",")
+				}
+				t.add(str(member.ObjectId), str(member.Name), kind, str(member.EnvironmentId), boolean(member.CustomMember))
+			}
+			if err := a.render(members, t); err != nil {
+				return err
+			}
+			if response.JSON200.Count != nil && *response.JSON200.Count > len(members) && a.format() == formatTable {
+				fmt.Fprintf(a.stderr, "Showing %d of %d members, raise -limit to see more\n", len(members), *response.JSON200.Count)
+			}
+			return nil
+		},
+	}
+}
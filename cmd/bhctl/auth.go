@@ -0,0 +1,322 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+)
+
+func authCommand() *command {
+	return &command{
+		name:    "auth",
+		summary: "Log in and out of a server",
+		subcommands: []*command{
+			authLoginCommand(),
+			authWhoamiCommand(),
+			authCanCommand(),
+			authLogoutCommand(),
+		},
+	}
+}
+
+func authLoginCommand() *command {
+	var tokenID, tokenKeyEnv, tokenFile, username, totpEnv string
+	return &command{
+		name:    "login",
+		summary: "Store credentials in the selected profile after checking them against the server",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&tokenID, "token-id", "", "API token id; the key is read from -token-key-env or stdin")
+			fs.StringVar(&tokenKeyEnv, "token-key-env", "", "environment variable holding the API token key")
+			fs.StringVar(&tokenFile, "token-file", "", "JSON file holding the API token, as written by token rotation")
+			fs.StringVar(&username, "username", "", "log in with a username; the secret is read from $BHCTL_PASSWORD or stdin")
+			fs.StringVar(&totpEnv, "totp-secret-env", "", "environment variable holding the TOTP secret or otpauth URI of a user with MFA")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 0, 0, "no arguments"); err != nil {
+				return err
+			}
+			name, stored, err := a.storedProfile()
+			if err != nil {
+				return err
+			}
+
+			candidate := profile{
+				Server:   stored.Server,
+				Output:   stored.Output,
+				CABundle: stored.CABundle,
+				Proxy:    stored.Proxy,
+			}
+			if a.server != "" {
+				candidate.Server = a.server
+			}
+
+			switch {
+			case tokenFile != "":
+				candidate.TokenFile = tokenFile
+			case tokenID != "" || tokenKeyEnv != "":
+				if tokenID == "" {
+					return errors.New("-token-key-env needs -token-id")
+				}
+				tokenKey, err := a.readSecret(tokenKeyEnv)
+				if err != nil {
+					return err
+				}
+				if tokenKey == "" {
+					return errors.New("the API token key is empty")
+				}
+				candidate.TokenID, candidate.TokenKey = tokenID, tokenKey
+			case username != "":
+				var totp *sdk.TOTP
+				if totpEnv != "" {
+					value := os.Getenv(totpEnv)
+					if value == "" {
+						return fmt.Errorf("%s is not set", totpEnv)
+					}
+					if totp, err = sdk.ParseTOTP(value); err != nil {
+						return err
+					}
+				}
+				secret, err := a.readSecret("BHCTL_PASSWORD")
+				if err != nil {
+					return err
+				}
+				sessionToken, err := login(ctx, &candidate, username, secret, totp)
+				if err != nil {
+					return err
+				}
+				candidate.SessionToken = sessionToken
+			default:
+				return errors.New("one of -token-id, -token-file or -username is required")
+			}
+
+			client, err := newClient(&candidate)
+			if err != nil {
+				return err
+			}
+			user, err := self(ctx, client)
+			if err != nil {
+				return err
+			}
+
+			*stored = candidate
+			if a.config.CurrentProfile == "" {
+				a.config.CurrentProfile = name
+			}
+			if err := a.saveConfig(); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.stderr, "Logged in to %s as %s (profile %s)\n", candidate.Server, str(user.PrincipalName), name)
+			return nil
+		},
+	}
+}
+
+// readSecret reads a secret from the environment variable envVar, when set, or the first line of stdin, so
+// secrets never show up in the process arguments
+func (a *app) readSecret(envVar string) (string, error) {
+	if secret := os.Getenv(envVar); envVar != "" && secret != "" {
+		return secret, nil
+	}
+	if file, ok := a.stdin.(*os.File); ok {
+		if info, err := file.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprint(a.stderr, "Secret: ")
+		}
+	}
+	line, err := bufio.NewReader(a.stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("error reading secret: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func login(ctx context.Context, candidate *profile, username, secret string, totp *sdk.TOTP) (string, error) {
+	client, err := newClient(candidate)
+	if err != nil {
+		return "", err
+	}
+	body := sdk.LoginJSONRequestBody{
+		LoginMethod: sdk.Secret,
+		Username:    username,
+		Secret:      &secret,
+	}
+	if totp != nil {
+		if body, err = totp.LoginBody(ctx, username, secret); err != nil {
+			return "", err
+		}
+	}
+	response, err := client.LoginWithResponse(ctx, nil, body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil || response.JSON200.Data.SessionToken == nil {
+		return "", statusError("Login", response.StatusCode(), response.Body)
+	}
+	if data := response.JSON200.Data; data.AuthExpired != nil && *data.AuthExpired {
+		return "", errors.New("the secret has expired and must be reset in the web interface")
+	}
+	return *response.JSON200.Data.SessionToken, nil
+}
+
+func self(ctx context.Context, client *sdk.ClientWithResponses) (sdk.ModelUser, error) {
+	response, err := client.GetSelfWithResponse(ctx, nil)
+	if err != nil {
+		return sdk.ModelUser{}, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil {
+		return sdk.ModelUser{}, statusError("GetSelf", response.StatusCode(), response.Body)
+	}
+	return response.JSON200.Data.AsModelUser()
+}
+
+func authWhoamiCommand() *command {
+	return &command{
+		name:    "whoami",
+		summary: "Show the user the selected profile authenticates as",
+		run: func(ctx context.Context, a *app, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			user, err := self(ctx, client)
+			if err != nil {
+				return err
+			}
+
+			t := newTable("KEY", "VALUE")
+			if user.Id != nil {
+				t.add("id", user.Id.String())
+			}
+			t.add("principal_name", str(user.PrincipalName))
+			t.add("name", strings.TrimSpace(nullString(user.FirstName)+" "+nullString(user.LastName)))
+			t.add("email", nullString(user.EmailAddress))
+			t.add("roles", strings.Join(roleNames(user.Roles), ", "))
+			t.add("last_login", timestamp(user.LastLogin))
+			return a.render(user, t)
+		},
+	}
+}
+
+func authCanCommand() *command {
+	return &command{
+		name:    "can",
+		args:    "OPERATION...",
+		summary: "Check whether the selected profile holds the permissions of operations such as ListUsers",
+		complete: func(a *app, args []string) []string {
+			var operations []string
+			for operation := range sdk.OperationPermissions {
+				operations = append(operations, operation)
+			}
+			return operations
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, -1, "one or more operation ids"); err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+
+			var missing map[string][]sdk.Permission
+			var denied *sdk.MissingPermissionsError
+			if err := client.RequirePermissions(ctx, args...); errors.As(err, &denied) {
+				missing = denied.Operations
+			} else if err != nil {
+				return err
+			}
+
+			type check struct {
+				Operation string           `json:"operation"`
+				Allowed   bool             `json:"allowed"`
+				Missing   []sdk.Permission `json:"missing,omitempty"`
+			}
+			var checks []check
+			t := newTable("OPERATION", "ALLOWED", "MISSING PERMISSIONS")
+			for _, operation := range args {
+				c := check{Operation: operation, Allowed: len(missing[operation]) == 0, Missing: missing[operation]}
+				checks = append(checks, c)
+				var names []string
+				for _, permission := range c.Missing {
+					names = append(names, permission.String())
+				}
+				t.add(operation, strconv.FormatBool(c.Allowed), strings.Join(names, ", "))
+			}
+			if err := a.render(checks, t); err != nil {
+				return err
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("%d of %d operations not permitted", len(missing), len(args))
+			}
+			return nil
+		},
+	}
+}
+
+func authLogoutCommand() *command {
+	return &command{
+		name:    "logout",
+		summary: "End the session and remove the credentials of the selected profile",
+		run: func(ctx context.Context, a *app, args []string) error {
+			_, stored, err := a.storedProfile()
+			if err != nil {
+				return err
+			}
+			if stored.SessionToken != "" {
+				client, err := a.client()
+				if err != nil {
+					return err
+				}
+				// The session is forgotten locally even when the server no longer knows it
+				if response, err := client.LogoutWithResponse(ctx, nil); err != nil {
+					fmt.Fprintf(a.stderr, "warning: %v\n", err)
+				} else if response.StatusCode() >= 300 && response.StatusCode() != http.StatusUnauthorized {
+					fmt.Fprintf(a.stderr, "warning: %v\n", statusError("Logout", response.StatusCode(), response.Body))
+				}
+			}
+			stored.TokenID, stored.TokenKey, stored.TokenFile, stored.SessionToken = "", "", "", ""
+			return a.saveConfig()
+		},
+	}
+}
+
+func nullString(value *sdk.NullString) string {
+	if value == nil || value.Valid == nil || !*value.Valid {
+		return ""
+	}
+	return str(value.String)
+}
+
+func roleNames(roles *[]sdk.ModelRole) []string {
+	if roles == nil {
+		return nil
+	}
+	names := make([]string, 0, len(*roles))
+	for _, role := range *roles {
+		names = append(names, str(role.Name))
+	}
+	return names
+}
@@ -0,0 +1,225 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// errUsage is returned when a command line is malformed; the usage has already been printed
+var errUsage = errors.New("usage error")
+
+// command is a node of the command tree. Groups have subcommands, leaves have a run function.
+type command struct {
+	name    string
+	args    string
+	summary string
+
+	// flags registers the flags of a leaf command, usually bound to variables captured by run
+	flags func(fs *flag.FlagSet)
+	run   func(ctx context.Context, a *app, args []string) error
+
+	// complete returns candidates for positional arguments
+	complete func(a *app, args []string) []string
+
+	subcommands []*command
+	hidden      bool
+}
+
+func (c *command) find(name string) *command {
+	for _, sub := range c.subcommands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func (c *command) flagSet(a *app, path string) *flag.FlagSet {
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	a.globalFlags(fs)
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	return fs
+}
+
+// execute walks down the tree following args and runs the leaf it reaches
+func (c *command) execute(ctx context.Context, a *app, path string, args []string) error {
+	if len(c.subcommands) > 0 {
+		if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+			c.printUsage(a, path)
+			return nil
+		}
+		sub := c.find(args[0])
+		if sub == nil {
+			fmt.Fprintf(a.stderr, "unknown command %q for %s\n\n", args[0], path)
+			c.printUsage(a, path)
+			return errUsage
+		}
+		return sub.execute(ctx, a, path+" "+sub.name, args[1:])
+	}
+
+	fs := c.flagSet(a, path)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			c.printUsage(a, path)
+			return nil
+		}
+		fmt.Fprintf(a.stderr, "%s: %v\n\n", path, err)
+		c.printUsage(a, path)
+		return errUsage
+	}
+
+	ctx, cancel := a.context(ctx)
+	defer cancel()
+	return c.run(ctx, a, fs.Args())
+}
+
+func (c *command) printUsage(a *app, path string) {
+	w := a.stderr
+	if c.summary != "" {
+		fmt.Fprintf(w, "%s\n\n", c.summary)
+	}
+	if len(c.subcommands) > 0 {
+		fmt.Fprintf(w, "Usage:\n  %s <command> [flags]\n\nCommands:\n", path)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, sub := range c.subcommands {
+			if !sub.hidden {
+				fmt.Fprintf(tw, "  %s\t%s\n", sub.name, sub.summary)
+			}
+		}
+		_ = tw.Flush()
+		fmt.Fprintf(w, "\nRun '%s <command> -h' for help on a command.\n", path)
+		return
+	}
+
+	fmt.Fprintf(w, "Usage:\n  %s [flags] %s\n", path, c.args)
+	if c.flags != nil {
+		fs := flag.NewFlagSet(path, flag.ContinueOnError)
+		c.flags(fs)
+		fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+	fmt.Fprintln(w, "\nGlobal flags:")
+	global := flag.NewFlagSet(path, flag.ContinueOnError)
+	(&app{}).globalFlags(global)
+	global.SetOutput(w)
+	global.PrintDefaults()
+}
+
+// completions returns the candidates for the last word of args, which may be empty
+func (c *command) completions(a *app, args []string) []string {
+	current := ""
+	if len(args) > 0 {
+		current = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+
+	node := c
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		word := args[i]
+		if len(node.subcommands) > 0 {
+			if sub := node.find(word); sub != nil {
+				node = sub
+				continue
+			}
+		}
+		if strings.HasPrefix(word, "-") {
+			// Skip the value of a flag that takes one
+			fs := node.flagSet(a, "")
+			name := strings.TrimLeft(word, "-")
+			if f := fs.Lookup(name); f != nil && !strings.Contains(word, "=") && !isBoolFlag(f) {
+				i++
+			}
+			continue
+		}
+		positional = append(positional, word)
+	}
+
+	var candidates []string
+	switch {
+	case strings.HasPrefix(current, "-"):
+		fs := node.flagSet(a, "")
+		fs.VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, "-"+f.Name)
+		})
+	case len(node.subcommands) > 0:
+		for _, sub := range node.subcommands {
+			if !sub.hidden {
+				candidates = append(candidates, sub.name)
+			}
+		}
+	case node.complete != nil:
+		candidates = node.complete(a, positional)
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// rootCommand builds the whole command tree
+func rootCommand() *command {
+	root := &command{
+		name:    "bhctl",
+		summary: "bhctl administers a BloodHound server.",
+		subcommands: []*command{
+			authCommand(),
+			ingestCommand(),
+			cypherCommand(),
+			queriesCommand(),
+			findingsCommand(),
+			usersCommand(),
+			tokensCommand(),
+			assetGroupsCommand(),
+			schedulesCommand(),
+			jobsCommand(),
+			configCommand(),
+		},
+	}
+	root.subcommands = append(root.subcommands, completionCommand(), completeCommand(root))
+	return root
+}
+
+// requireArgs checks the number of positional arguments
+func requireArgs(args []string, min, max int, usage string) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		return fmt.Errorf("expected %s", usage)
+	}
+	return nil
+}
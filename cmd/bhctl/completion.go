@@ -0,0 +1,87 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// The scripts call the hidden __complete command with the words typed so far, the last one being completed
+const (
+	bashCompletion = `# bash completion for bhctl, load with: source <(bhctl completion bash)
+_bhctl() {
+	local IFS=$'\n'
+	COMPREPLY=($(bhctl __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _bhctl bhctl
+`
+
+	zshCompletion = `#compdef bhctl
+# zsh completion for bhctl, load with: source <(bhctl completion zsh)
+_bhctl() {
+	local -a candidates
+	candidates=("${(@f)$(bhctl __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	if [[ -n ${candidates[1]} ]]; then
+		compadd -a candidates
+	else
+		_files
+	fi
+}
+compdef _bhctl bhctl
+`
+
+	fishCompletion = `# fish completion for bhctl, load with: bhctl completion fish | source
+complete -c bhctl -a '(bhctl __complete -- (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`
+)
+
+func completionCommand() *command {
+	script := func(name, content string) *command {
+		return &command{
+			name:    name,
+			summary: "Print the " + name + " completion script",
+			run: func(ctx context.Context, a *app, args []string) error {
+				_, err := fmt.Fprint(a.stdout, content)
+				return err
+			},
+		}
+	}
+	return &command{
+		name:    "completion",
+		summary: "Print shell completion scripts",
+		subcommands: []*command{
+			script("bash", bashCompletion),
+			script("zsh", zshCompletion),
+			script("fish", fishCompletion),
+		},
+	}
+}
+
+// completeCommand prints the candidates for the command line given after --, one per line
+func completeCommand(root *command) *command {
+	return &command{
+		name:   "__complete",
+		hidden: true,
+		run: func(ctx context.Context, a *app, args []string) error {
+			for _, candidate := range root.completions(a, args) {
+				fmt.Fprintln(a.stdout, candidate)
+			}
+			return nil
+		},
+	}
+}
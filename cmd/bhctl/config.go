@@ -0,0 +1,422 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const defaultProfileName = "default"
+
+// profile holds the connection settings of one server
+type profile struct {
+	Server string `json:"server"`
+
+	// HMAC API token, inline or in a file kept up to date by token rotation
+	TokenID   string `json:"token_id,omitempty"`
+	TokenKey  string `json:"token_key,omitempty"`
+	TokenFile string `json:"token_file,omitempty"`
+
+	// SessionToken is the bearer token of a username and secret login
+	SessionToken string `json:"session_token,omitempty"`
+
+	Output   string `json:"output,omitempty"`
+	CABundle string `json:"ca_bundle,omitempty"`
+	Proxy    string `json:"proxy,omitempty"`
+}
+
+// Settable profile keys, in the order they are shown
+var profileKeys = []string{"server", "token_id", "token_key", "token_file", "session_token", "output", "ca_bundle", "proxy"}
+
+func (p *profile) field(key string) (*string, error) {
+	switch key {
+	case "server":
+		return &p.Server, nil
+	case "token_id":
+		return &p.TokenID, nil
+	case "token_key":
+		return &p.TokenKey, nil
+	case "token_file":
+		return &p.TokenFile, nil
+	case "session_token":
+		return &p.SessionToken, nil
+	case "output":
+		return &p.Output, nil
+	case "ca_bundle":
+		return &p.CABundle, nil
+	case "proxy":
+		return &p.Proxy, nil
+	}
+	return nil, fmt.Errorf("unknown profile key %q", key)
+}
+
+type config struct {
+	CurrentProfile string              `json:"current_profile"`
+	Profiles       map[string]*profile `json:"profiles"`
+}
+
+func (a *app) resolvedConfigPath() (string, error) {
+	if a.configPath != "" {
+		return a.configPath, nil
+	}
+	if path := os.Getenv("BHCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bhctl", "config.json"), nil
+}
+
+// loadConfig reads the configuration file, which may not exist yet
+func (a *app) loadConfig() (*config, error) {
+	if a.config != nil {
+		return a.config, nil
+	}
+	path, err := a.resolvedConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config{Profiles: map[string]*profile{}}
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, cfg); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		if cfg.Profiles == nil {
+			cfg.Profiles = map[string]*profile{}
+		}
+	}
+	a.config = cfg
+	return cfg, nil
+}
+
+// saveConfig writes the configuration file readable by the owner only, as it holds secrets
+func (a *app) saveConfig() error {
+	path, err := a.resolvedConfigPath()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(a.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// A unique temporary file in the same directory, created readable by the owner only, is synced before it
+	// replaces the configuration so concurrent runs and crashes never leave a partial file
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+func (a *app) selectedProfileName() (string, error) {
+	if a.profileName != "" {
+		return a.profileName, nil
+	}
+	if name := os.Getenv("BHCTL_PROFILE"); name != "" {
+		return name, nil
+	}
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return "", err
+	}
+	if cfg.CurrentProfile != "" {
+		return cfg.CurrentProfile, nil
+	}
+	return defaultProfileName, nil
+}
+
+// storedProfile returns the profile as stored in the configuration, creating it when missing
+func (a *app) storedProfile() (string, *profile, error) {
+	name, err := a.selectedProfileName()
+	if err != nil {
+		return "", nil, err
+	}
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return "", nil, err
+	}
+	stored, ok := cfg.Profiles[name]
+	if !ok {
+		stored = &profile{}
+		cfg.Profiles[name] = stored
+	}
+	return name, stored, nil
+}
+
+// profile returns the effective settings: the stored profile overridden by the environment and the flags
+func (a *app) profile() (*profile, error) {
+	name, err := a.selectedProfileName()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	effective := profile{}
+	if stored, ok := cfg.Profiles[name]; ok {
+		effective = *stored
+	} else if a.profileName != "" {
+		return nil, fmt.Errorf("profile %q does not exist", name)
+	}
+
+	if server := os.Getenv("BLOODHOUND_SERVER"); server != "" {
+		effective.Server = server
+	}
+	if id, key := os.Getenv("API_TOKEN_ID"), os.Getenv("API_TOKEN_KEY"); id != "" && key != "" {
+		effective.TokenID, effective.TokenKey, effective.TokenFile, effective.SessionToken = id, key, "", ""
+	}
+	if a.server != "" {
+		effective.Server = a.server
+	}
+	return &effective, nil
+}
+
+func configCommand() *command {
+	return &command{
+		name:    "config",
+		summary: "Manage profiles and settings",
+		subcommands: []*command{
+			configPathCommand(),
+			configProfilesCommand(),
+			configUseCommand(),
+			configShowCommand(),
+			configSetCommand(),
+			configDeleteCommand(),
+		},
+	}
+}
+
+func configPathCommand() *command {
+	return &command{
+		name:    "path",
+		summary: "Print the configuration file path",
+		run: func(ctx context.Context, a *app, args []string) error {
+			path, err := a.resolvedConfigPath()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(a.stdout, path)
+			return nil
+		},
+	}
+}
+
+func configProfilesCommand() *command {
+	return &command{
+		name:    "profiles",
+		summary: "List profiles",
+		run: func(ctx context.Context, a *app, args []string) error {
+			cfg, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			current, err := a.selectedProfileName()
+			if err != nil {
+				return err
+			}
+			type profileSummary struct {
+				Name    string `json:"name"`
+				Server  string `json:"server"`
+				Current bool   `json:"current"`
+			}
+			var summaries []profileSummary
+			t := newTable("CURRENT", "NAME", "SERVER")
+			for _, name := range profileNames(cfg) {
+				marker := ""
+				if name == current {
+					marker = "*"
+				}
+				summaries = append(summaries, profileSummary{Name: name, Server: cfg.Profiles[name].Server, Current: name == current})
+				t.add(marker, name, cfg.Profiles[name].Server)
+			}
+			return a.render(summaries, t)
+		},
+	}
+}
+
+func profileNames(cfg *config) []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func completeProfiles(a *app, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return nil
+	}
+	return profileNames(cfg)
+}
+
+func configUseCommand() *command {
+	return &command{
+		name:     "use",
+		args:     "PROFILE",
+		summary:  "Make a profile the current one",
+		complete: completeProfiles,
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "a profile name"); err != nil {
+				return err
+			}
+			cfg, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("profile %q does not exist", args[0])
+			}
+			cfg.CurrentProfile = args[0]
+			return a.saveConfig()
+		},
+	}
+}
+
+func configShowCommand() *command {
+	var showSecrets bool
+	return &command{
+		name:    "show",
+		summary: "Show the effective settings of the selected profile",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&showSecrets, "show-secrets", false, "print token keys and session tokens")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			effective, err := a.profile()
+			if err != nil {
+				return err
+			}
+			if !showSecrets {
+				effective.TokenKey = redact(effective.TokenKey)
+				effective.SessionToken = redact(effective.SessionToken)
+			}
+			t := newTable("KEY", "VALUE")
+			for _, key := range profileKeys {
+				value, _ := effective.field(key)
+				t.add(key, *value)
+			}
+			return a.render(effective, t)
+		},
+	}
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "********"
+}
+
+func configSetCommand() *command {
+	return &command{
+		name:    "set",
+		args:    "KEY VALUE",
+		summary: "Set a setting of the selected profile, creating the profile if needed",
+		complete: func(a *app, args []string) []string {
+			if len(args) == 0 {
+				return profileKeys
+			}
+			return nil
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 2, 2, "a key and a value"); err != nil {
+				return err
+			}
+			name, stored, err := a.storedProfile()
+			if err != nil {
+				return err
+			}
+			field, err := stored.field(args[0])
+			if err != nil {
+				return err
+			}
+			*field = args[1]
+			if a.config.CurrentProfile == "" {
+				a.config.CurrentProfile = name
+			}
+			return a.saveConfig()
+		},
+	}
+}
+
+func configDeleteCommand() *command {
+	return &command{
+		name:     "delete",
+		args:     "PROFILE",
+		summary:  "Delete a profile",
+		complete: completeProfiles,
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "a profile name"); err != nil {
+				return err
+			}
+			cfg, err := a.loadConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("profile %q does not exist", args[0])
+			}
+			delete(cfg.Profiles, args[0])
+			if cfg.CurrentProfile == args[0] {
+				cfg.CurrentProfile = ""
+			}
+			return a.saveConfig()
+		},
+	}
+}
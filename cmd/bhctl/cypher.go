@@ -0,0 +1,212 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+)
+
+// formatGraph renders cypher results as a Graphviz DOT document
+const formatGraph = "graph"
+
+func cypherCommand() *command {
+	return &command{
+		name:    "cypher",
+		summary: "Run cypher queries",
+		subcommands: []*command{
+			cypherRunCommand(),
+			cypherShellCommand(),
+		},
+	}
+}
+
+func cypherRunCommand() *command {
+	var file string
+	var includeProperties bool
+	return &command{
+		name:    "run",
+		args:    "[QUERY]",
+		summary: "Run a cypher query; output formats are table, json and graph (Graphviz DOT)",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&file, "file", "", "read the query from a file, - for stdin")
+			fs.BoolVar(&includeProperties, "include-properties", false, "include node and edge properties in the results")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 0, 1, "a single query argument"); err != nil {
+				return err
+			}
+			query, err := a.readQuery(file, args)
+			if err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			graph, err := runCypher(ctx, client, query, includeProperties)
+			if err != nil {
+				return err
+			}
+
+			switch format := a.format(); format {
+			case formatJSON:
+				return writeJSON(a.stdout, graph)
+			case formatGraph:
+				return writeDOT(a.stdout, graph)
+			case formatTable:
+				return writeGraphTables(a.stdout, graph)
+			default:
+				return fmt.Errorf("unsupported output format %q for cypher results", format)
+			}
+		},
+	}
+}
+
+// readQuery takes the query from the argument or from the -file flag
+func (a *app) readQuery(file string, args []string) (string, error) {
+	switch {
+	case len(args) == 1 && file != "":
+		return "", errors.New("give either a query or -file, not both")
+	case len(args) == 1:
+		return args[0], nil
+	case file == "-":
+		content, err := io.ReadAll(a.stdin)
+		return string(content), err
+	case file != "":
+		content, err := os.ReadFile(file)
+		return string(content), err
+	default:
+		return "", errors.New("expected a query or -file")
+	}
+}
+
+func runCypher(ctx context.Context, client *sdk.ClientWithResponses, query string, includeProperties bool) (*sdk.ModelUnifiedGraphGraph, error) {
+	response, err := client.RunCypherQueryWithResponse(ctx, nil, sdk.RunCypherQueryJSONRequestBody{
+		Query:             &query,
+		IncludeProperties: &includeProperties,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The server answers 404 when the query matched nothing
+	if response.StatusCode() == http.StatusNotFound {
+		return &sdk.ModelUnifiedGraphGraph{}, nil
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil {
+		return nil, statusError("RunCypherQuery", response.StatusCode(), response.Body)
+	}
+	return response.JSON200.Data, nil
+}
+
+// nodeIds returns the node ids of a graph, numerically ordered when they are numbers
+func nodeIds(graph *sdk.ModelUnifiedGraphGraph) []string {
+	if graph.Nodes == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(*graph.Nodes))
+	for id := range *graph.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		left, leftErr := strconv.ParseInt(ids[i], 10, 64)
+		right, rightErr := strconv.ParseInt(ids[j], 10, 64)
+		if leftErr == nil && rightErr == nil {
+			return left < right
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+func graphEdges(graph *sdk.ModelUnifiedGraphGraph) []sdk.ModelUnifiedGraphEdge {
+	if graph.Edges == nil {
+		return nil
+	}
+	return *graph.Edges
+}
+
+// graphTables lays out the nodes and edges of a graph as two tables
+func graphTables(graph *sdk.ModelUnifiedGraphGraph) (nodes, edges *table) {
+	nodes = newTable("ID", "KIND", "LABEL", "OBJECT ID", "TIER ZERO", "LAST SEEN")
+	for _, id := range nodeIds(graph) {
+		node := (*graph.Nodes)[id]
+		nodes.add(id, str(node.Kind), str(node.Label), str(node.ObjectId), str(node.IsTierZero), timestamp(node.LastSeen))
+	}
+
+	edges = newTable("SOURCE", "KIND", "TARGET", "LAST SEEN")
+	for _, edge := range graphEdges(graph) {
+		edges.add(nodeName(graph, str(edge.Source)), str(edge.Kind), nodeName(graph, str(edge.Target)), timestamp(edge.LastSeen))
+	}
+	return nodes, edges
+}
+
+func writeGraphTables(w io.Writer, graph *sdk.ModelUnifiedGraphGraph) error {
+	nodes, edges := graphTables(graph)
+	if len(nodes.rows) == 0 && len(edges.rows) == 0 {
+		_, err := fmt.Fprintln(w, "No results")
+		return err
+	}
+	if err := nodes.writeText(w); err != nil {
+		return err
+	}
+	if len(edges.rows) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	return edges.writeText(w)
+}
+
+// nodeName labels an edge end with the node label when the graph has it
+func nodeName(graph *sdk.ModelUnifiedGraphGraph, id string) string {
+	if graph.Nodes != nil {
+		if node, ok := (*graph.Nodes)[id]; ok && node.Label != nil && *node.Label != "" {
+			return *node.Label
+		}
+	}
+	return id
+}
+
+// writeDOT renders a graph in the Graphviz DOT language
+func writeDOT(w io.Writer, graph *sdk.ModelUnifiedGraphGraph) error {
+	var b strings.Builder
+	b.WriteString("digraph bloodhound {\n\tnode [shape=box];\n")
+	for _, id := range nodeIds(graph) {
+		node := (*graph.Nodes)[id]
+		label := str(node.Label)
+		if node.Kind != nil {
+			label = *node.Kind + "\n" + label
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s];\n", strconv.Quote(id), strconv.Quote(label))
+	}
+	for _, edge := range graphEdges(graph) {
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", strconv.Quote(str(edge.Source)), strconv.Quote(str(edge.Target)), strconv.Quote(str(edge.Kind)))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
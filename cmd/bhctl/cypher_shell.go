@@ -0,0 +1,508 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+	"golang.org/x/term"
+)
+
+const (
+	shellPrompt             = "cypher> "
+	shellContinuationPrompt = "   ...> "
+
+	// Statements kept in the history file
+	shellHistorySize = 1000
+)
+
+// Cypher keywords offered by completion outside of labels
+var cypherKeywords = []string{
+	"MATCH", "OPTIONAL", "WHERE", "RETURN", "WITH", "UNWIND", "ORDER", "BY", "SKIP", "LIMIT", "DISTINCT",
+	"AND", "OR", "NOT", "XOR", "IN", "IS", "NULL", "AS", "CONTAINS", "STARTS", "ENDS", "CASE", "WHEN", "THEN",
+	"ELSE", "END", "UNION", "ALL", "ASC", "DESC", "COUNT", "COLLECT", "shortestPath", "allShortestPaths",
+}
+
+// Node kinds known without asking the server
+var baseNodeKinds = []string{
+	sdk.KindBase, sdk.KindUser, sdk.KindComputer, sdk.KindGroup, sdk.KindDomain, sdk.KindOU, sdk.KindGPO,
+	sdk.KindContainer, sdk.KindCertTemplate, sdk.KindEnterpriseCA, sdk.KindRootCA, sdk.KindAIACA, sdk.KindNTAuthStore,
+	sdk.KindAZBase, sdk.KindAZUser, sdk.KindAZGroup, sdk.KindAZServicePrincipal, sdk.KindAZApp, sdk.KindAZDevice,
+	sdk.KindAZTenant, sdk.KindAZSubscription, sdk.KindAZResourceGroup, sdk.KindAZVM, sdk.KindAZKeyVault,
+	sdk.KindAZRole, sdk.KindAZManagementGroup,
+}
+
+// lineReader is the input of the shell: a line editor on a terminal, plain lines otherwise
+type lineReader interface {
+	ReadLine() (string, error)
+	SetPrompt(prompt string)
+}
+
+type plainLineReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *plainLineReader) ReadLine() (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *plainLineReader) SetPrompt(prompt string) {}
+
+// cypherShell reads statements, runs them and renders their results
+type cypherShell struct {
+	client *sdk.ClientWithResponses
+	input  lineReader
+	out    io.Writer
+
+	format            string
+	includeProperties bool
+
+	// Completion words: node kinds and edge types, from the server and from results
+	nodeKinds map[string]bool
+	edgeKinds map[string]bool
+
+	history     []string
+	historyPath string
+
+	// lastQuery is the statement :save stores
+	lastQuery string
+
+	// interactive is set when reading from a terminal
+	interactive bool
+}
+
+func cypherShellCommand() *command {
+	var includeProperties, noHistory bool
+	return &command{
+		name:    "shell",
+		summary: "Interactive cypher shell; statements end with ';' or an empty line, ':help' lists commands",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&includeProperties, "include-properties", false, "include node and edge properties in the results")
+			fs.BoolVar(&noHistory, "no-history", false, "do not read or write the history file")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 0, 0, "no arguments"); err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			shell := &cypherShell{
+				client:            client,
+				format:            a.format(),
+				includeProperties: includeProperties,
+				nodeKinds:         map[string]bool{},
+				edgeKinds:         map[string]bool{},
+			}
+			if shell.format != formatJSON && shell.format != formatGraph {
+				shell.format = formatTable
+			}
+			for _, kind := range baseNodeKinds {
+				shell.nodeKinds[kind] = true
+			}
+			if err := shell.loadAttackPathTypes(ctx); err != nil {
+				fmt.Fprintf(a.stderr, "warning: attack path types unavailable for completion: %v\n", err)
+			}
+			if !noHistory {
+				if path, err := a.resolvedConfigPath(); err == nil {
+					shell.historyPath = filepath.Join(filepath.Dir(path), "cypher_history")
+					shell.loadHistory()
+				}
+			}
+
+			stdin, isFile := a.stdin.(*os.File)
+			if !isFile || !term.IsTerminal(int(stdin.Fd())) {
+				shell.input = &plainLineReader{scanner: bufio.NewScanner(a.stdin)}
+				shell.out = a.stdout
+				return shell.run(ctx)
+			}
+
+			state, err := term.MakeRaw(int(stdin.Fd()))
+			if err != nil {
+				return err
+			}
+			defer term.Restore(int(stdin.Fd()), state)
+
+			terminal := term.NewTerminal(struct {
+				io.Reader
+				io.Writer
+			}{stdin, a.stdout}, shellPrompt)
+			if width, height, err := term.GetSize(int(stdin.Fd())); err == nil && width > 0 && height > 0 {
+				_ = terminal.SetSize(width, height)
+			}
+			terminal.AutoCompleteCallback = shell.complete
+			shell.input = terminal
+			shell.interactive = true
+			// The terminal translates newlines for the raw mode
+			shell.out = terminal
+			fmt.Fprintln(terminal, "Connected. End statements with ';' or an empty line, ':help' for commands, Ctrl-D to quit.")
+			return shell.run(ctx)
+		},
+	}
+}
+
+// run is the read, evaluate and print loop
+func (s *cypherShell) run(ctx context.Context) error {
+	var buffer []string
+	for {
+		if len(buffer) == 0 {
+			s.input.SetPrompt(shellPrompt)
+		} else {
+			s.input.SetPrompt(shellContinuationPrompt)
+		}
+
+		line, err := s.input.ReadLine()
+		if errors.Is(err, io.EOF) {
+			if len(buffer) == 0 {
+				return nil
+			}
+			// Ctrl-C and Ctrl-D discard a pending statement before they quit, but piped input ends with it
+			if s.interactive {
+				buffer = nil
+				continue
+			}
+			statement := strings.TrimSpace(strings.Join(buffer, "\n"))
+			s.addHistory(statement)
+			if err := s.execute(ctx, statement); err != nil {
+				fmt.Fprintf(s.out, "error: %v\n", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if name, commandArgs, ok := s.shellCommand(trimmed); ok {
+			if name == "clear" {
+				buffer = nil
+				continue
+			}
+			if len(buffer) == 0 {
+				quit, err := s.runShellCommand(ctx, name, commandArgs)
+				if err != nil {
+					fmt.Fprintf(s.out, "error: %v\n", err)
+				}
+				if quit {
+					return nil
+				}
+				continue
+			}
+		}
+
+		if len(buffer) == 0 && strings.HasPrefix(trimmed, ":") {
+			fmt.Fprintf(s.out, "unknown command %s, ':help' lists commands\n", strings.Fields(trimmed)[0])
+			continue
+		}
+
+		complete := false
+		switch {
+		case trimmed == "":
+			complete = len(buffer) > 0
+		case strings.HasSuffix(trimmed, ";"):
+			buffer = append(buffer, strings.TrimSuffix(strings.TrimRight(line, " \t"), ";"))
+			complete = true
+		default:
+			buffer = append(buffer, line)
+		}
+		if !complete {
+			continue
+		}
+
+		statement := strings.TrimSpace(strings.Join(buffer, "\n"))
+		buffer = nil
+		if statement == "" {
+			continue
+		}
+		s.addHistory(statement)
+		if err := s.execute(ctx, statement); err != nil {
+			fmt.Fprintf(s.out, "error: %v\n", err)
+		}
+	}
+}
+
+func (s *cypherShell) execute(ctx context.Context, statement string) error {
+	graph, err := runCypher(ctx, s.client, statement, s.includeProperties)
+	if err != nil {
+		var statusErr *sdk.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(statusErr.Body)))
+		}
+		return err
+	}
+	s.lastQuery = statement
+	s.observe(graph)
+
+	switch s.format {
+	case formatJSON:
+		return writeJSON(s.out, graph)
+	case formatGraph:
+		return writeDOT(s.out, graph)
+	default:
+		return writeGraphTables(s.out, graph)
+	}
+}
+
+// shellCommand recognizes a ':name args' line naming a known shell command
+func (s *cypherShell) shellCommand(line string) (string, []string, bool) {
+	if !strings.HasPrefix(line, ":") {
+		return "", nil, false
+	}
+	fields := strings.Fields(line[1:])
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	switch fields[0] {
+	case "help", "quit", "exit", "save", "history", "run", "format", "props", "kinds", "clear":
+		return fields[0], fields[1:], true
+	}
+	return "", nil, false
+}
+
+const shellHelp = `Statements end with ';' or an empty line. Tab completes keywords, node kinds and, after ':', edge types.
+
+  :save NAME [DESCRIPTION]  store the last statement as a saved query
+  :history [N]              list the last N statements (default 20)
+  :run N                    run statement N of the history again
+  :format table|json|graph  change how results are shown
+  :props on|off             include properties in results
+  :kinds                    list the node kinds and edge types known to completion
+  :clear                    discard the statement being typed
+  :quit                     leave the shell (or Ctrl-D)
+`
+
+func (s *cypherShell) runShellCommand(ctx context.Context, name string, args []string) (bool, error) {
+	switch name {
+	case "quit", "exit":
+		return true, nil
+	case "help":
+		fmt.Fprint(s.out, shellHelp)
+	case "save":
+		if len(args) == 0 {
+			return false, errors.New("usage: :save NAME [DESCRIPTION]")
+		}
+		if s.lastQuery == "" {
+			return false, errors.New("no statement has run yet")
+		}
+		query := sdk.ModelSavedQuery{Name: ptr(args[0]), Query: ptr(s.lastQuery)}
+		if len(args) > 1 {
+			query.Description = ptr(strings.Join(args[1:], " "))
+		}
+		if err := createSavedQuery(ctx, s.client, query); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(s.out, "Saved query %q\n", args[0])
+	case "history":
+		count := 20
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return false, errors.New("usage: :history [N]")
+			}
+			count = n
+		}
+		first := len(s.history) - count
+		if first < 0 {
+			first = 0
+		}
+		for i := first; i < len(s.history); i++ {
+			fmt.Fprintf(s.out, "%5d  %s\n", i+1, strings.ReplaceAll(s.history[i], "\n", "\n       "))
+		}
+	case "run":
+		if len(args) != 1 {
+			return false, errors.New("usage: :run N")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(s.history) {
+			return false, fmt.Errorf("no history entry %s", args[0])
+		}
+		statement := s.history[n-1]
+		fmt.Fprintln(s.out, statement)
+		s.addHistory(statement)
+		return false, s.execute(ctx, statement)
+	case "format":
+		if len(args) != 1 || (args[0] != formatTable && args[0] != formatJSON && args[0] != formatGraph) {
+			return false, errors.New("usage: :format table|json|graph")
+		}
+		s.format = args[0]
+	case "props":
+		if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+			return false, errors.New("usage: :props on|off")
+		}
+		s.includeProperties = args[0] == "on"
+	case "kinds":
+		fmt.Fprintf(s.out, "Node kinds: %s\n", strings.Join(sortedSet(s.nodeKinds), ", "))
+		fmt.Fprintf(s.out, "Edge types: %s\n", strings.Join(sortedSet(s.edgeKinds), ", "))
+	}
+	return false, nil
+}
+
+// loadAttackPathTypes adds the attack path types the server knows to the completion words
+func (s *cypherShell) loadAttackPathTypes(ctx context.Context) error {
+	response, err := s.client.ListAttackPathTypesWithResponse(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return statusError("ListAttackPathTypes", response.StatusCode(), response.Body)
+	}
+	if response.JSON200.Data != nil {
+		for _, pathType := range *response.JSON200.Data {
+			s.edgeKinds[pathType] = true
+		}
+	}
+	return nil
+}
+
+// observe learns the node kinds and edge types present in a result
+func (s *cypherShell) observe(graph *sdk.ModelUnifiedGraphGraph) {
+	if graph.Nodes != nil {
+		for _, node := range *graph.Nodes {
+			if node.Kind != nil && *node.Kind != "" {
+				s.nodeKinds[*node.Kind] = true
+			}
+		}
+	}
+	for _, edge := range graphEdges(graph) {
+		if edge.Kind != nil && *edge.Kind != "" {
+			s.edgeKinds[*edge.Kind] = true
+		}
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// complete extends the word before the cursor to the longest prefix shared by the matching completion words.
+// After a ':' the words are node kinds and edge types, elsewhere keywords and node kinds.
+func (s *cypherShell) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	runes := []rune(line[:pos])
+	start := len(runes)
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	word := string(runes[start:])
+
+	var words []string
+	if start > 0 && runes[start-1] == ':' {
+		words = append(sortedSet(s.nodeKinds), sortedSet(s.edgeKinds)...)
+	} else {
+		if word == "" {
+			return line, pos, true
+		}
+		words = append(append([]string{}, cypherKeywords...), sortedSet(s.nodeKinds)...)
+	}
+
+	prefix := ""
+	found := false
+	for _, candidate := range words {
+		if !strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(word)) {
+			continue
+		}
+		if !found {
+			prefix, found = candidate, true
+			continue
+		}
+		prefix = commonPrefix(prefix, candidate)
+	}
+	if !found || len(prefix) < len(word) {
+		return line, pos, true
+	}
+
+	head := string(runes[:start])
+	return head + prefix + line[pos:], len(head) + len(prefix), true
+}
+
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
+
+func sortedSet(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// History statements are stored one per line as quoted strings, so multi-line statements survive
+func (s *cypherShell) loadHistory() {
+	content, err := os.ReadFile(s.historyPath)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if statement, err := strconv.Unquote(line); err == nil {
+			s.history = append(s.history, statement)
+		}
+	}
+}
+
+func (s *cypherShell) addHistory(statement string) {
+	if len(s.history) > 0 && s.history[len(s.history)-1] == statement {
+		return
+	}
+	s.history = append(s.history, statement)
+	if len(s.history) > shellHistorySize {
+		s.history = s.history[len(s.history)-shellHistorySize:]
+	}
+	if s.historyPath == "" {
+		return
+	}
+
+	var b strings.Builder
+	for _, entry := range s.history {
+		b.WriteString(strconv.Quote(entry))
+		b.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(s.historyPath), 0700); err == nil {
+		// History is a convenience, failing to write it does not interrupt the shell
+		_ = os.WriteFile(s.historyPath, []byte(b.String()), 0600)
+	}
+}
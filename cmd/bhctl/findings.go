@@ -0,0 +1,84 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+)
+
+func findingsCommand() *command {
+	return &command{
+		name:    "findings",
+		summary: "Export attack path findings",
+		subcommands: []*command{
+			findingsExportCommand(),
+		},
+	}
+}
+
+func findingsExportCommand() *command {
+	var domainId, finding, accepted, out string
+	return &command{
+		name:    "export",
+		summary: "Export the findings of one type in a domain as the CSV the server produces",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&domainId, "domain", "", "domain or tenant id (required)")
+			fs.StringVar(&finding, "finding", "", "finding type, for example LargeDefaultGroupsGenericWrite (required)")
+			fs.StringVar(&accepted, "accepted", string(sdk.All), "risk acceptance filter: all, accepted or unaccepted")
+			fs.StringVar(&out, "out", "", "file to write instead of stdout")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if domainId == "" || finding == "" {
+				return errors.New("-domain and -finding are required")
+			}
+			filter := sdk.EnumRiskAcceptance(accepted)
+			switch filter {
+			case sdk.All, sdk.Accepted, sdk.Unaccepted:
+			default:
+				return fmt.Errorf("invalid -accepted value %q", accepted)
+			}
+
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			response, err := client.ExportAttackPathFindingsWithResponse(ctx, domainId, &sdk.ExportAttackPathFindingsParams{
+				Finding:        finding,
+				FilterAccepted: &filter,
+			})
+			if err != nil {
+				return err
+			}
+			if response.StatusCode() != http.StatusOK {
+				return statusError("ExportAttackPathFindings", response.StatusCode(), response.Body)
+			}
+
+			if out == "" {
+				_, err := a.stdout.Write(response.Body)
+				return err
+			}
+			return os.WriteFile(out, response.Body, 0644)
+		},
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+)
+
+func ingestCommand() *command {
+	return &command{
+		name:    "ingest",
+		summary: "Upload collected data and follow its ingestion",
+		subcommands: []*command{
+			ingestUploadCommand(),
+			ingestStatusCommand(),
+		},
+	}
+}
+
+func ingestUploadCommand() *command {
+	var analyze bool
+	return &command{
+		name:    "upload",
+		args:    "FILE...",
+		summary: "Upload collector output (.zip or .json files) as one file upload job",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&analyze, "analyze", false, "run analysis once the data is ingested and wait for it to complete")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, -1, "at least one file"); err != nil {
+				return err
+			}
+			for _, path := range args {
+				if _, err := sdk.UploadContentType(path); err != nil {
+					return err
+				}
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			jobId, err := client.UploadFiles(ctx, args, func(path string) {
+				fmt.Fprintf(a.stderr, "Uploaded %s\n", path)
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(a.stdout, "%d\n", jobId)
+
+			if !analyze {
+				return nil
+			}
+			run, err := client.RunAnalysis(ctx, sdk.OnAnalysisStatusChange(func(previous, current sdk.DatapipeStatus) {
+				fmt.Fprintf(a.stderr, "Datapipe %s\n", current.Status)
+			}))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(a.stderr, "Analysis completed in %s\n", run.CompletedAt.Sub(run.StartedAt).Round(time.Second))
+			return nil
+		},
+	}
+}
+
+func jobStatus(status *sdk.EnumJobStatus) string {
+	if status == nil {
+		return ""
+	}
+	return status.String()
+}
+
+func ingestStatusCommand() *command {
+	var limit int
+	return &command{
+		name:    "status",
+		summary: "Show the datapipe status and the most recent file upload jobs",
+		flags: func(fs *flag.FlagSet) {
+			fs.IntVar(&limit, "limit", 10, "number of jobs to list")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			status, err := client.DatapipeStatus(ctx)
+			if err != nil {
+				return err
+			}
+			response, err := client.ListFileUploadJobsWithResponse(ctx, &sdk.ListFileUploadJobsParams{
+				SortBy: ptr("-id"),
+				Limit:  &limit,
+			})
+			if err != nil {
+				return err
+			}
+			if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+				return statusError("ListFileUploadJobs", response.StatusCode(), response.Body)
+			}
+			var jobs []sdk.ModelFileUploadJob
+			if response.JSON200.Data != nil {
+				jobs = *response.JSON200.Data
+			}
+
+			if a.format() == formatJSON {
+				return writeJSON(a.stdout, struct {
+					Datapipe sdk.DatapipeStatus       `json:"datapipe"`
+					Jobs     []sdk.ModelFileUploadJob `json:"jobs"`
+				}{status, jobs})
+			}
+
+			if a.format() == formatTable {
+				fmt.Fprintf(a.stdout, "Datapipe: %s, last complete analysis %s\n\n", status.Status, timestamp(&status.LastCompleteAnalysisAt))
+			}
+			t := newTable("ID", "STATUS", "USER", "STARTED", "ENDED", "LAST INGEST", "MESSAGE")
+			for _, job := range jobs {
+				user := ""
+				if job.UserEmailAddress != nil {
+					user = string(*job.UserEmailAddress)
+				}
+				t.add(integer(job.Id), jobStatus(job.Status), user, timestamp(job.StartTime), timestamp(job.EndTime), timestamp(job.LastIngest), str(job.StatusMessage))
+			}
+			return a.render(jobs, t)
+		},
+	}
+}
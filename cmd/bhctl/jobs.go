@@ -0,0 +1,144 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+)
+
+// formatHTML renders reports as an HTML document
+const formatHTML = "html"
+
+func jobsCommand() *command {
+	return &command{
+		name:    "jobs",
+		summary: "Follow and report on collection jobs",
+		subcommands: []*command{
+			jobsLogCommand(),
+			jobsReportCommand(),
+		},
+	}
+}
+
+func jobsLogCommand() *command {
+	var follow bool
+	var interval time.Duration
+	return &command{
+		name:    "log",
+		args:    "JOB_ID",
+		summary: "Print the log of a collection job",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&follow, "follow", false, "keep printing new lines until the job finishes")
+			fs.DurationVar(&interval, "interval", 5*time.Second, "how often the log is polled with -follow")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "a job id"); err != nil {
+				return err
+			}
+			jobId, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid job id %q", args[0])
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			stream, err := sdk.NewJobLogStream(client, jobId)
+			if err != nil {
+				return err
+			}
+
+			if !follow {
+				lines, err := stream.Poll(ctx)
+				if err != nil {
+					return err
+				}
+				for _, line := range lines {
+					fmt.Fprintln(a.stdout, line)
+				}
+				return nil
+			}
+
+			stream.PollInterval = interval
+			stream.Seq(ctx)(func(line string) bool {
+				fmt.Fprintln(a.stdout, line)
+				return true
+			})
+			if err := stream.Err(); err != nil {
+				return err
+			}
+			if stream.Finished() {
+				fmt.Fprintf(a.stderr, "Job %d %s\n", jobId, stream.Status())
+			}
+			return ctx.Err()
+		},
+	}
+}
+
+func jobsReportCommand() *command {
+	var since time.Duration
+	var clients string
+	return &command{
+		name:    "report",
+		summary: "Summarise completed collection jobs per job and per domain; output formats are table, json and html",
+		flags: func(fs *flag.FlagSet) {
+			fs.DurationVar(&since, "since", 7*24*time.Hour, "report on jobs started within this duration")
+			fs.StringVar(&clients, "clients", "", "comma separated client ids to report on, all clients by default")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 0, 0, "no arguments"); err != nil {
+				return err
+			}
+			var format sdk.CollectionJobReportFormat
+			switch a.format() {
+			case formatTable:
+				format = sdk.CollectionJobReportTable
+			case formatJSON:
+				format = sdk.CollectionJobReportJSON
+			case formatHTML:
+				format = sdk.CollectionJobReportHTML
+			default:
+				return fmt.Errorf("unsupported output format %q for job reports", a.format())
+			}
+
+			options := sdk.CollectionJobReportOptions{From: time.Now().Add(-since)}
+			for _, value := range splitList(clients) {
+				id, err := parseUUID(value)
+				if err != nil {
+					return err
+				}
+				options.ClientIds = append(options.ClientIds, id)
+			}
+
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			report, err := sdk.BuildCollectionJobReport(ctx, client, options)
+			if err != nil {
+				return err
+			}
+			return report.Render(a.stdout, format)
+		},
+	}
+}
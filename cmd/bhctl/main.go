@@ -0,0 +1,187 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Command bhctl administers a BloodHound server from the command line.
+//
+// Connection settings come from named profiles in the configuration file, which the environment variables
+// BLOODHOUND_SERVER, API_TOKEN_ID and API_TOKEN_KEY override:
+//
+//	bhctl auth login -server https://bloodhound.example.com -token-id ID -token-key-env API_TOKEN_KEY
+//	bhctl cypher run 'MATCH (n:User) RETURN n LIMIT 5'
+//	bhctl -profile staging ingest upload collection.zip
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+	"github.com/oapi-codegen/oapi-codegen/v2/pkg/securityprovider"
+)
+
+// app holds the global flags and the state shared by commands
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath  string
+	profileName string
+	server      string
+	output      string
+	timeout     time.Duration
+
+	config    *config
+	apiClient *sdk.ClientWithResponses
+}
+
+func (a *app) globalFlags(fs *flag.FlagSet) {
+	fs.StringVar(&a.configPath, "config", a.configPath, "configuration file (default $BHCTL_CONFIG or the user config directory)")
+	fs.StringVar(&a.profileName, "profile", a.profileName, "profile to use instead of the current one")
+	fs.StringVar(&a.server, "server", a.server, "server URL, overriding the profile")
+	fs.StringVar(&a.output, "o", a.output, "output format: table, json or csv, graph for cypher results, html for job reports")
+	fs.DurationVar(&a.timeout, "timeout", a.timeout, "timeout of the whole command, 0 for none")
+}
+
+func main() {
+	a := &app{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+	os.Exit(a.run(os.Args[1:]))
+}
+
+func (a *app) run(args []string) int {
+	root := rootCommand()
+
+	// Global flags may precede the command; leaf commands accept them too
+	fs := flag.NewFlagSet("bhctl", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	a.globalFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			root.printUsage(a, "bhctl")
+			return 0
+		}
+		fmt.Fprintf(a.stderr, "bhctl: %v\n", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := root.execute(ctx, a, "bhctl", fs.Args())
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(a.stderr, "bhctl: %v\n", err)
+		return 1
+	}
+}
+
+// context applies the -timeout flag
+func (a *app) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.timeout > 0 {
+		return context.WithTimeout(ctx, a.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// client builds the API client of the selected profile
+func (a *app) client() (*sdk.ClientWithResponses, error) {
+	if a.apiClient != nil {
+		return a.apiClient, nil
+	}
+	profile, err := a.profile()
+	if err != nil {
+		return nil, err
+	}
+	client, err := newClient(profile)
+	if err != nil {
+		return nil, err
+	}
+	a.apiClient = client
+	return client, nil
+}
+
+// newHTTPClient applies the transport settings of a profile
+func newHTTPClient(profile *profile) (*http.Client, error) {
+	return sdk.NewHTTPClient(sdk.TransportConfig{
+		CABundleFile:               profile.CABundle,
+		ProxyURL:                   profile.Proxy,
+		ResolveLocalhostSubdomains: true,
+	})
+}
+
+func newClient(profile *profile) (*sdk.ClientWithResponses, error) {
+	if profile.Server == "" {
+		return nil, errors.New("no server configured, run 'bhctl auth login' or set BLOODHOUND_SERVER")
+	}
+
+	httpClient, err := newHTTPClient(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []sdk.ClientOption{sdk.WithPreferWaitPolicy(sdk.NewPreferWaitPolicy())}
+	switch {
+	case profile.TokenFile != "":
+		watcher, err := sdk.NewHMACCredentialsFileWatcher(profile.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		credentials, err := sdk.NewSecurityProviderHMACCredentialsFromProvider(watcher)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdk.WithRequestEditorFn(credentials.Intercept), sdk.WithHTTPClient(credentials.ClockSkewDoer(httpClient)))
+	case profile.TokenID != "" || profile.TokenKey != "":
+		credentials, err := sdk.NewSecurityProviderHMACCredentials(profile.TokenKey, profile.TokenID)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdk.WithRequestEditorFn(credentials.Intercept), sdk.WithHTTPClient(credentials.ClockSkewDoer(httpClient)))
+	case profile.SessionToken != "":
+		bearer, err := securityprovider.NewSecurityProviderBearerToken(profile.SessionToken)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdk.WithRequestEditorFn(bearer.Intercept), sdk.WithHTTPClient(httpClient))
+	default:
+		options = append(options, sdk.WithHTTPClient(httpClient))
+	}
+	return sdk.NewClientWithResponses(profile.Server, options...)
+}
+
+// statusError reports an unexpected response the way the SDK does
+func statusError(operation string, statusCode int, body []byte) error {
+	return &sdk.StatusError{Operation: operation, StatusCode: statusCode, Body: body}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
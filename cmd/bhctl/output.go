@@ -0,0 +1,138 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// table is the tabular form of a command result, used by the table and csv formats
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+func newTable(headers ...string) *table {
+	return &table{headers: headers}
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+func (t *table) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.headers, "\t"))
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			// Keep one row per line whatever the cell holds
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func (t *table) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.headers); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// format returns the output format: the -o flag, else the profile setting, else table
+func (a *app) format() string {
+	if a.output != "" {
+		return a.output
+	}
+	if a.config != nil {
+		if name, err := a.selectedProfileName(); err == nil {
+			if stored, ok := a.config.Profiles[name]; ok && stored.Output != "" {
+				return stored.Output
+			}
+		}
+	}
+	return formatTable
+}
+
+// render writes a result in the selected format. The json format encodes value, the others t.
+func (a *app) render(value interface{}, t *table) error {
+	switch format := a.format(); format {
+	case formatJSON:
+		return writeJSON(a.stdout, value)
+	case formatCSV:
+		return t.writeCSV(a.stdout)
+	case formatTable:
+		return t.writeText(a.stdout)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// Cell formatting of optional API fields
+
+func str(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func integer[T int | int32 | int64](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatInt(int64(*value), 10)
+}
+
+func boolean(value *bool) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*value)
+}
+
+func timestamp(value *time.Time) string {
+	if value == nil || value.IsZero() {
+		return ""
+	}
+	return value.Local().Format(time.DateTime)
+}
@@ -0,0 +1,251 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+)
+
+// savedQuery is the file format of pulled and pushed queries, which leaves out server assigned fields
+type savedQuery struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Query       string `json:"query"`
+}
+
+func queriesCommand() *command {
+	return &command{
+		name:    "queries",
+		summary: "Manage saved cypher queries",
+		subcommands: []*command{
+			queriesListCommand(),
+			queriesPullCommand(),
+			queriesPushCommand(),
+		},
+	}
+}
+
+// listSavedQueries fetches every saved query visible to the user, page by page
+func listSavedQueries(ctx context.Context, client *sdk.ClientWithResponses) ([]sdk.ModelSavedQuery, error) {
+	const pageSize = 100
+	var queries []sdk.ModelSavedQuery
+	for skip := 0; ; skip += pageSize {
+		response, err := client.ListSavedQueriesWithResponse(ctx, &sdk.ListSavedQueriesParams{
+			Skip:   ptr(skip),
+			Limit:  ptr(pageSize),
+			SortBy: ptr("name"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+			return nil, statusError("ListSavedQueries", response.StatusCode(), response.Body)
+		}
+		if response.JSON200.Data == nil || len(*response.JSON200.Data) == 0 {
+			return queries, nil
+		}
+		queries = append(queries, *response.JSON200.Data...)
+		if len(*response.JSON200.Data) < pageSize || (response.JSON200.Count != nil && len(queries) >= *response.JSON200.Count) {
+			return queries, nil
+		}
+	}
+}
+
+func queriesListCommand() *command {
+	return &command{
+		name:    "list",
+		summary: "List saved queries",
+		run: func(ctx context.Context, a *app, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			queries, err := listSavedQueries(ctx, client)
+			if err != nil {
+				return err
+			}
+			t := newTable("ID", "NAME", "DESCRIPTION", "UPDATED")
+			for _, query := range queries {
+				t.add(integer(query.Id), str(query.Name), str(query.Description), timestamp(query.UpdatedAt))
+			}
+			return a.render(queries, t)
+		},
+	}
+}
+
+func queriesPullCommand() *command {
+	var file string
+	return &command{
+		name:    "pull",
+		summary: "Write the saved queries to a JSON file that 'queries push' accepts",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&file, "file", "", "file to write instead of stdout")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			queries, err := listSavedQueries(ctx, client)
+			if err != nil {
+				return err
+			}
+			pulled := make([]savedQuery, 0, len(queries))
+			for _, query := range queries {
+				pulled = append(pulled, savedQuery{
+					Name:        str(query.Name),
+					Description: str(query.Description),
+					Query:       str(query.Query),
+				})
+			}
+
+			if file == "" {
+				return writeJSON(a.stdout, pulled)
+			}
+			out, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(out, pulled); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.stderr, "Wrote %d queries to %s\n", len(pulled), file)
+			return nil
+		},
+	}
+}
+
+func queriesPushCommand() *command {
+	var dryRun bool
+	return &command{
+		name:    "push",
+		args:    "FILE",
+		summary: "Create or update saved queries from a JSON file, matching existing queries by name",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&dryRun, "dry-run", false, "only print what would change")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "a file, - for stdin"); err != nil {
+				return err
+			}
+			pushed, err := readSavedQueries(a, args[0])
+			if err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			existing, err := listSavedQueries(ctx, client)
+			if err != nil {
+				return err
+			}
+			byName := make(map[string]sdk.ModelSavedQuery, len(existing))
+			for _, query := range existing {
+				byName[str(query.Name)] = query
+			}
+
+			for _, query := range pushed {
+				body := sdk.ModelSavedQuery{Name: ptr(query.Name), Description: ptr(query.Description), Query: ptr(query.Query)}
+				current, ok := byName[query.Name]
+				switch {
+				case !ok:
+					fmt.Fprintf(a.stderr, "create %s\n", query.Name)
+					if !dryRun {
+						if err := createSavedQuery(ctx, client, body); err != nil {
+							return err
+						}
+					}
+				case str(current.Query) != query.Query || str(current.Description) != query.Description:
+					if current.Id == nil {
+						return fmt.Errorf("saved query %q has no id", query.Name)
+					}
+					fmt.Fprintf(a.stderr, "update %s\n", query.Name)
+					if !dryRun {
+						if err := updateSavedQuery(ctx, client, int32(*current.Id), body); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func readSavedQueries(a *app, path string) ([]savedQuery, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(a.stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var queries []savedQuery
+	if err := json.Unmarshal(content, &queries); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	seen := make(map[string]bool, len(queries))
+	for _, query := range queries {
+		if query.Name == "" || query.Query == "" {
+			return nil, errors.New("every saved query needs a name and a query")
+		}
+		if seen[query.Name] {
+			return nil, fmt.Errorf("saved query %q appears twice", query.Name)
+		}
+		seen[query.Name] = true
+	}
+	return queries, nil
+}
+
+func createSavedQuery(ctx context.Context, client *sdk.ClientWithResponses, query sdk.ModelSavedQuery) error {
+	response, err := client.CreateSavedQueryWithResponse(ctx, nil, query)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != http.StatusCreated {
+		return statusError("CreateSavedQuery", response.StatusCode(), response.Body)
+	}
+	return nil
+}
+
+func updateSavedQuery(ctx context.Context, client *sdk.ClientWithResponses, id int32, query sdk.ModelSavedQuery) error {
+	response, err := client.UpdateSavedQueryWithResponse(ctx, id, nil, query)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != http.StatusOK {
+		return statusError("UpdateSavedQuery", response.StatusCode(), response.Body)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+)
+
+func schedulesCommand() *command {
+	return &command{
+		name:    "schedules",
+		summary: "Manage collection schedules of collector clients",
+		subcommands: []*command{
+			schedulesPreviewCommand(),
+			schedulesListCommand(),
+			schedulesApplyCommand(),
+		},
+	}
+}
+
+func schedulesPreviewCommand() *command {
+	var count int
+	return &command{
+		name:    "preview",
+		args:    "SCHEDULE",
+		summary: `Print the RRULE and next occurrences of a schedule such as "weekdays at 02:00 UTC" or an RRULE`,
+		flags: func(fs *flag.FlagSet) {
+			fs.IntVar(&count, "count", 5, "number of occurrences to print")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, -1, "a schedule"); err != nil {
+				return err
+			}
+			recurrence, err := sdk.ParseRecurrence(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			occurrences := recurrence.Next(time.Now(), count)
+			if a.format() == formatJSON {
+				return writeJSON(a.stdout, struct {
+					Rrule       string      `json:"rrule"`
+					Occurrences []time.Time `json:"occurrences"`
+				}{recurrence.String(), occurrences})
+			}
+			fmt.Fprintln(a.stdout, recurrence.String())
+			fmt.Fprintln(a.stdout)
+			for _, occurrence := range occurrences {
+				fmt.Fprintln(a.stdout, occurrence.Format(time.RFC1123))
+			}
+			return nil
+		},
+	}
+}
+
+func schedulesListCommand() *command {
+	return &command{
+		name:    "list",
+		args:    "CLIENT_ID",
+		summary: "List the collection schedules of a client",
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "a client id"); err != nil {
+				return err
+			}
+			clientId, err := parseUUID(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			schedules, err := client.ClientSchedules(ctx, clientId)
+			if err != nil {
+				return err
+			}
+
+			t := newTable("ID", "RRULE", "NEXT", "COLLECTIONS", "DOMAINS", "OUS")
+			for _, schedule := range schedules {
+				next := ""
+				if recurrence, err := sdk.ParseRRule(str(schedule.Rrule)); err == nil {
+					if occurrences := recurrence.Next(time.Now(), 1); len(occurrences) > 0 {
+						next = timestamp(&occurrences[0])
+					}
+				}
+				domains, ous := 0, 0
+				if schedule.Domains != nil {
+					domains = len(*schedule.Domains)
+				}
+				if schedule.Ous != nil {
+					ous = len(*schedule.Ous)
+				}
+				t.add(integer(schedule.Id), strings.ReplaceAll(str(schedule.Rrule), "\n", " "), next, scheduleCollections(schedule), strconv.Itoa(domains), strconv.Itoa(ous))
+			}
+			return a.render(schedules, t)
+		},
+	}
+}
+
+func scheduleCollections(schedule sdk.ModelClientScheduleDisplay) string {
+	var names []string
+	for _, collection := range []struct {
+		name    string
+		enabled *bool
+	}{
+		{"sessions", schedule.SessionCollection},
+		{"local-groups", schedule.LocalGroupCollection},
+		{"ad-structure", schedule.AdStructureCollection},
+		{"cert-services", schedule.CertServicesCollection},
+		{"ca-registry", schedule.CaRegistryCollection},
+		{"dc-registry", schedule.DcRegistryCollection},
+		{"all-trusted-domains", schedule.AllTrustedDomains},
+	} {
+		if collection.enabled != nil && *collection.enabled {
+			names = append(names, collection.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func schedulesApplyCommand() *command {
+	var dryRun bool
+	return &command{
+		name:    "apply",
+		args:    "CLIENT_ID FILE",
+		summary: "Make the schedules of a client match a JSON list of declared schedules, creating, updating and deleting as needed",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&dryRun, "dry-run", false, "only print what would change")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 2, 2, "a client id and a file, - for stdin"); err != nil {
+				return err
+			}
+			clientId, err := parseUUID(args[0])
+			if err != nil {
+				return err
+			}
+			desired, err := readDesiredSchedules(a, args[1])
+			if err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			plan, err := client.PlanClientSchedules(ctx, clientId, desired)
+			if err != nil {
+				return err
+			}
+
+			for _, schedule := range plan.Create {
+				fmt.Fprintf(a.stderr, "create %s\n", strings.ReplaceAll(str(schedule.Rrule), "\n", " "))
+			}
+			for _, update := range plan.Update {
+				fmt.Fprintf(a.stderr, "update %d %s\n", update.Id, strings.ReplaceAll(str(update.Desired.Rrule), "\n", " "))
+			}
+			for _, schedule := range plan.Delete {
+				fmt.Fprintf(a.stderr, "delete %s %s\n", integer(schedule.Id), strings.ReplaceAll(str(schedule.Rrule), "\n", " "))
+			}
+			if plan.Empty() {
+				fmt.Fprintln(a.stderr, "Schedules are up to date")
+			}
+			if dryRun || plan.Empty() {
+				return nil
+			}
+			return client.ApplySchedulePlan(ctx, plan)
+		},
+	}
+}
+
+func readDesiredSchedules(a *app, path string) ([]sdk.DesiredSchedule, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(a.stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedules []sdk.DesiredSchedule
+	if err := json.Unmarshal(content, &schedules); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return schedules, nil
+}
@@ -0,0 +1,279 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// Completions that query the server give up after this long
+const completionTimeout = 5 * time.Second
+
+func tokensCommand() *command {
+	return &command{
+		name:    "tokens",
+		summary: "Manage API tokens",
+		subcommands: []*command{
+			tokensListCommand(),
+			tokensCreateCommand(),
+			tokensDeleteCommand(),
+			tokensRotateCommand(),
+		},
+	}
+}
+
+func parseUUID(value string) (openapi_types.UUID, error) {
+	var id openapi_types.UUID
+	if err := id.UnmarshalText([]byte(value)); err != nil {
+		return id, fmt.Errorf("invalid id %q: %w", value, err)
+	}
+	return id, nil
+}
+
+// userIdFlag resolves the -user flag, defaulting to the authenticated user
+func userIdFlag(ctx context.Context, client *sdk.ClientWithResponses, value string) (openapi_types.UUID, error) {
+	if value != "" {
+		return parseUUID(value)
+	}
+	user, err := self(ctx, client)
+	if err != nil {
+		return openapi_types.UUID{}, err
+	}
+	if user.Id == nil {
+		return openapi_types.UUID{}, errors.New("the authenticated user has no id")
+	}
+	return *user.Id, nil
+}
+
+func listAuthTokens(ctx context.Context, client *sdk.ClientWithResponses, params *sdk.ListAuthTokensParams) ([]sdk.ModelAuthToken, error) {
+	response, err := client.ListAuthTokensWithResponse(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, statusError("ListAuthTokens", response.StatusCode(), response.Body)
+	}
+	if response.JSON200.Data == nil || response.JSON200.Data.Tokens == nil {
+		return nil, nil
+	}
+	return *response.JSON200.Data.Tokens, nil
+}
+
+func completeTokenIds(a *app, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	client, err := a.client()
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	tokens, err := listAuthTokens(ctx, client, nil)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, token := range tokens {
+		if token.Id != nil {
+			ids = append(ids, token.Id.String())
+		}
+	}
+	return ids
+}
+
+func tokensListCommand() *command {
+	var userId string
+	return &command{
+		name:    "list",
+		summary: "List API tokens",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&userId, "user", "", "only list the tokens of this user id (administrators only)")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			params := &sdk.ListAuthTokensParams{SortBy: ptr("name")}
+			if userId != "" {
+				id, err := parseUUID(userId)
+				if err != nil {
+					return err
+				}
+				params.UserId = &id
+			}
+			tokens, err := listAuthTokens(ctx, client, params)
+			if err != nil {
+				return err
+			}
+
+			t := newTable("ID", "NAME", "USER", "CREATED", "LAST ACCESS")
+			for i, token := range tokens {
+				// Keys are only ever shown at creation
+				tokens[i].Key = nil
+				id, user := "", ""
+				if token.Id != nil {
+					id = token.Id.String()
+				}
+				if token.UserId != nil && token.UserId.Uuid != nil {
+					user = token.UserId.Uuid.String()
+				}
+				t.add(id, nullString(token.Name), user, timestamp(token.CreatedAt), timestamp(token.LastAccess))
+			}
+			return a.render(tokens, t)
+		},
+	}
+}
+
+func tokensCreateCommand() *command {
+	var name, userId string
+	return &command{
+		name:    "create",
+		summary: "Create an API token and print its key, which cannot be retrieved later",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&name, "name", "", "token name (required)")
+			fs.StringVar(&userId, "user", "", "user id owning the token, the authenticated user by default")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if name == "" {
+				return errors.New("-name is required")
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			owner, err := userIdFlag(ctx, client, userId)
+			if err != nil {
+				return err
+			}
+			response, err := client.CreateAuthTokenWithResponse(ctx, nil, sdk.CreateAuthTokenJSONRequestBody{
+				TokenName: &name,
+				UserId:    &owner,
+			})
+			if err != nil {
+				return err
+			}
+			if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil || response.JSON200.Data.Id == nil || response.JSON200.Data.Key == nil {
+				return statusError("CreateAuthToken", response.StatusCode(), response.Body)
+			}
+			token := response.JSON200.Data
+
+			t := newTable("KEY", "VALUE")
+			t.add("token_id", token.Id.String())
+			t.add("token_key", *token.Key)
+			return a.render(sdk.TokenSecret{
+				TokenID:   token.Id.String(),
+				TokenKey:  *token.Key,
+				Name:      name,
+				UserId:    owner.String(),
+				CreatedAt: time.Now().UTC(),
+			}, t)
+		},
+	}
+}
+
+func tokensDeleteCommand() *command {
+	return &command{
+		name:     "delete",
+		args:     "TOKEN_ID",
+		summary:  "Delete an API token",
+		complete: completeTokenIds,
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "a token id"); err != nil {
+				return err
+			}
+			id, err := parseUUID(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			response, err := client.DeleteAuthTokenWithResponse(ctx, id, nil)
+			if err != nil {
+				return err
+			}
+			if response.StatusCode() < 200 || response.StatusCode() > 299 {
+				return statusError("DeleteAuthToken", response.StatusCode(), response.Body)
+			}
+			return nil
+		},
+	}
+}
+
+func tokensRotateCommand() *command {
+	var name, userId, out string
+	return &command{
+		name:     "rotate",
+		args:     "TOKEN_ID",
+		summary:  "Replace an API token with a new one written to a file, then delete the old token",
+		complete: completeTokenIds,
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&name, "name", "", "name of the new token (required)")
+			fs.StringVar(&userId, "user", "", "user id owning the token, the authenticated user by default")
+			fs.StringVar(&out, "out", "", "JSON file receiving the new token, usable as a profile token_file (required)")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "a token id"); err != nil {
+				return err
+			}
+			if name == "" || out == "" {
+				return errors.New("-name and -out are required")
+			}
+			oldTokenId, err := parseUUID(args[0])
+			if err != nil {
+				return err
+			}
+			profile, err := a.profile()
+			if err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			owner, err := userIdFlag(ctx, client, userId)
+			if err != nil {
+				return err
+			}
+
+			rotator, err := sdk.NewTokenRotator(client, profile.Server, &sdk.FileTokenSecretSink{Path: out})
+			if err != nil {
+				return err
+			}
+			if rotator.HTTPClient, err = newHTTPClient(profile); err != nil {
+				return err
+			}
+			rotation, err := rotator.Rotate(ctx, owner, oldTokenId, name)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(a.stderr, "Rotated token %s to %s, written to %s\n", rotation.OldTokenId, rotation.NewTokenId, out)
+			return nil
+		},
+	}
+}
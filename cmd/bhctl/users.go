@@ -0,0 +1,352 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/SpecterOps/bloodhound-go-sdk/sdk"
+)
+
+func usersCommand() *command {
+	return &command{
+		name:    "users",
+		summary: "Manage users",
+		subcommands: []*command{
+			usersListCommand(),
+			usersProvisionCommand(),
+			usersAuditCommand(),
+			usersMfaCommand(),
+		},
+	}
+}
+
+func listUsers(ctx context.Context, client *sdk.ClientWithResponses) ([]sdk.ModelUser, error) {
+	response, err := client.ListUsersWithResponse(ctx, &sdk.ListUsersParams{SortBy: ptr("principal_name")})
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, statusError("ListUsers", response.StatusCode(), response.Body)
+	}
+	if response.JSON200.Data == nil || response.JSON200.Data.Users == nil {
+		return nil, nil
+	}
+	return *response.JSON200.Data.Users, nil
+}
+
+func usersListCommand() *command {
+	return &command{
+		name:    "list",
+		summary: "List users",
+		run: func(ctx context.Context, a *app, args []string) error {
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			users, err := listUsers(ctx, client)
+			if err != nil {
+				return err
+			}
+			t := newTable("ID", "PRINCIPAL NAME", "EMAIL", "ROLES", "DISABLED", "LAST LOGIN")
+			for _, user := range users {
+				id := ""
+				if user.Id != nil {
+					id = user.Id.String()
+				}
+				t.add(id, str(user.PrincipalName), nullString(user.EmailAddress), strings.Join(roleNames(user.Roles), ", "), boolean(user.IsDisabled), timestamp(user.LastLogin))
+			}
+			return a.render(users, t)
+		},
+	}
+}
+
+func usersProvisionCommand() *command {
+	var (
+		dryRun         bool
+		disableMissing bool
+		defaultRoles   string
+		keep           string
+		samlProviderId string
+		secretEnv      string
+	)
+	return &command{
+		name:    "provision",
+		args:    "FILE",
+		summary: "Create and update users to match a CSV file or a SCIM 2.0 JSON payload, and with -disable-missing disable the others",
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&dryRun, "dry-run", false, "only print what would change")
+			fs.BoolVar(&disableMissing, "disable-missing", false, "disable enabled users missing from the file")
+			fs.StringVar(&defaultRoles, "default-roles", "", "comma separated roles of users the file gives none")
+			fs.StringVar(&keep, "keep", "", "comma separated principal names never to disable")
+			fs.StringVar(&samlProviderId, "saml-provider", "", "SAML provider id of created users the file gives none")
+			fs.StringVar(&secretEnv, "initial-secret-env", "", "environment variable holding the initial secret of created users")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "a file, - for stdin"); err != nil {
+				return err
+			}
+			users, err := readProvisionedUsers(a, args[0])
+			if err != nil {
+				return err
+			}
+			options := sdk.UserProvisioningOptions{
+				DefaultRoles:   splitList(defaultRoles),
+				SamlProviderId: samlProviderId,
+				DisableMissing: disableMissing,
+				KeepPrincipals: splitList(keep),
+			}
+			if secretEnv != "" {
+				if options.InitialSecret = os.Getenv(secretEnv); options.InitialSecret == "" {
+					return fmt.Errorf("%s is not set", secretEnv)
+				}
+			}
+
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			plan, err := client.PlanUserProvisioning(ctx, users, options)
+			if err != nil {
+				return err
+			}
+			if a.format() == formatJSON {
+				if err := writeJSON(a.stdout, plan); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprint(a.stderr, plan.Diff())
+				if len(plan.Changes) == 0 {
+					fmt.Fprintln(a.stderr, "Users are up to date")
+				}
+			}
+			for _, group := range plan.IgnoredGroups {
+				fmt.Fprintf(a.stderr, "Group %s is not named after a role and was ignored\n", group)
+			}
+			if dryRun || len(plan.Changes) == 0 {
+				return nil
+			}
+			return client.ApplyUserProvisioning(ctx, plan)
+		},
+	}
+}
+
+func usersAuditCommand() *command {
+	return &command{
+		name:    "audit",
+		args:    "POLICY",
+		summary: "List enabled users whose roles grant permissions beyond a JSON policy of permissions and operation ids",
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 1, 1, "a policy file, - for stdin"); err != nil {
+				return err
+			}
+			var content []byte
+			var err error
+			if args[0] == "-" {
+				content, err = io.ReadAll(a.stdin)
+			} else {
+				content, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			var policy sdk.PrivilegePolicy
+			if err := json.Unmarshal(content, &policy); err != nil {
+				return fmt.Errorf("error reading %s: %w", args[0], err)
+			}
+
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			report, err := client.BuildPrivilegeReport(ctx, policy)
+			if err != nil {
+				return err
+			}
+			for _, principal := range report.UnknownUsers {
+				fmt.Fprintf(a.stderr, "warning: the policy names %s, who is not a user\n", principal)
+			}
+
+			t := newTable("ID", "PRINCIPAL NAME", "ROLES", "EXCESS PERMISSIONS")
+			for _, user := range report.OverPrivileged {
+				var names []string
+				for _, permission := range user.Excess {
+					names = append(names, permission.String())
+				}
+				t.add(user.UserId.String(), user.PrincipalName, strings.Join(user.Roles, ", "), strings.Join(names, ", "))
+			}
+			if err := a.render(report, t); err != nil {
+				return err
+			}
+			if a.format() != formatJSON {
+				fmt.Fprintf(a.stderr, "%d of %d enabled users over-privileged\n", len(report.OverPrivileged), report.UsersChecked)
+			}
+			return nil
+		},
+	}
+}
+
+func usersMfaCommand() *command {
+	return &command{
+		name:    "mfa",
+		summary: "Enroll users in multi-factor authentication without an authenticator app",
+		subcommands: []*command{
+			usersMfaStatusCommand(),
+			usersMfaEnrollCommand(),
+			usersMfaRemoveCommand(),
+		},
+	}
+}
+
+func usersMfaStatusCommand() *command {
+	var userId string
+	return &command{
+		name:    "status",
+		summary: "Show whether MFA of a user is activated, pending or deactivated",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&userId, "user", "", "user id, the authenticated user by default")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 0, 0, "no arguments"); err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			user, err := userIdFlag(ctx, client, userId)
+			if err != nil {
+				return err
+			}
+			status, err := client.MFAStatus(ctx, user)
+			if err != nil {
+				return err
+			}
+			t := newTable("USER ID", "STATUS")
+			t.add(user.String(), string(status))
+			return a.render(struct {
+				UserId string `json:"user_id"`
+				Status string `json:"status"`
+			}{user.String(), string(status)}, t)
+		},
+	}
+}
+
+func usersMfaEnrollCommand() *command {
+	var userId string
+	return &command{
+		name:    "enroll",
+		summary: "Add and activate MFA for a user and print the otpauth URI of their TOTP secret; the user's secret is read from $BHCTL_PASSWORD or stdin",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&userId, "user", "", "user id, the authenticated user by default")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 0, 0, "no arguments"); err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			user, err := userIdFlag(ctx, client, userId)
+			if err != nil {
+				return err
+			}
+			secret, err := a.readSecret("BHCTL_PASSWORD")
+			if err != nil {
+				return err
+			}
+			totp, err := client.EnrollMFA(ctx, user, secret)
+			if err != nil {
+				return err
+			}
+			totp.Issuer = "BloodHound"
+			if response, err := client.GetUserWithResponse(ctx, user, nil); err == nil && response.JSON200 != nil && response.JSON200.Data != nil {
+				totp.Account = str(response.JSON200.Data.PrincipalName)
+			}
+			fmt.Fprintln(a.stdout, totp.URI())
+			fmt.Fprintln(a.stderr, "MFA activated; store the URI securely, auth login -totp-secret-env needs it")
+			return nil
+		},
+	}
+}
+
+func usersMfaRemoveCommand() *command {
+	var userId string
+	return &command{
+		name:    "remove",
+		summary: "Remove MFA from a user; the user's secret is read from $BHCTL_PASSWORD or stdin",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&userId, "user", "", "user id, the authenticated user by default")
+		},
+		run: func(ctx context.Context, a *app, args []string) error {
+			if err := requireArgs(args, 0, 0, "no arguments"); err != nil {
+				return err
+			}
+			client, err := a.client()
+			if err != nil {
+				return err
+			}
+			user, err := userIdFlag(ctx, client, userId)
+			if err != nil {
+				return err
+			}
+			secret, err := a.readSecret("BHCTL_PASSWORD")
+			if err != nil {
+				return err
+			}
+			return client.RemoveMFA(ctx, user, secret)
+		},
+	}
+}
+
+// readProvisionedUsers reads CSV, or SCIM JSON when the file ends in .json or starts like JSON
+func readProvisionedUsers(a *app, path string) ([]sdk.ProvisionedUser, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(a.stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(content)
+	if strings.HasSuffix(strings.ToLower(path), ".json") || bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")) {
+		return sdk.ParseSCIMUsers(content)
+	}
+	return sdk.ParseProvisioningCSV(bytes.NewReader(content))
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,222 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DatapipeStatus is the state of the ingest and analysis pipeline
+type DatapipeStatus struct {
+	Status                 EnumDatapipeStatus
+	LastCompleteAnalysisAt time.Time
+	UpdatedAt              time.Time
+}
+
+// DatapipeStatus fetches the current state of the pipeline
+func (c *ClientWithResponses) DatapipeStatus(ctx context.Context) (DatapipeStatus, error) {
+	response, err := c.GetDatapipeStatusWithResponse(ctx, nil)
+	if err != nil {
+		return DatapipeStatus{}, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil {
+		return DatapipeStatus{}, newStatusError("GetDatapipeStatus", response.StatusCode(), response.Body)
+	}
+
+	var status DatapipeStatus
+	data := response.JSON200.Data
+	if data.Status != nil {
+		status.Status = *data.Status
+	}
+	if data.LastCompleteAnalysisAt != nil {
+		status.LastCompleteAnalysisAt = *data.LastCompleteAnalysisAt
+	}
+	if data.UpdatedAt != nil {
+		status.UpdatedAt = *data.UpdatedAt
+	}
+	return status, nil
+}
+
+// AnalysisRun is the outcome of RunAnalysis
+type AnalysisRun struct {
+	// Skipped is set when OnlyIfNewData found nothing ingested since the last analysis
+	Skipped bool
+
+	Before DatapipeStatus
+	After  DatapipeStatus
+
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+// AnalysisOption configures RunAnalysis
+type AnalysisOption func(*analysisOptions)
+
+type analysisOptions struct {
+	onlyIfNewData  bool
+	enterprise     bool
+	pollInterval   time.Duration
+	onStatusChange func(previous, current DatapipeStatus)
+}
+
+func (o *analysisOptions) interval() time.Duration {
+	if o.pollInterval <= 0 {
+		return 5 * time.Second
+	}
+	return o.pollInterval
+}
+
+// OnlyIfNewData skips the run when no file upload job ingested data after the last complete analysis
+func OnlyIfNewData() AnalysisOption {
+	return func(o *analysisOptions) {
+		o.onlyIfNewData = true
+	}
+}
+
+// WithEnterpriseAnalysis starts analysis through StartAnalysisBhe, which also regenerates attack paths
+func WithEnterpriseAnalysis() AnalysisOption {
+	return func(o *analysisOptions) {
+		o.enterprise = true
+	}
+}
+
+// WithAnalysisPollInterval sets how often the pipeline status is polled. The default of 5 seconds is used for
+// intervals that are not positive.
+func WithAnalysisPollInterval(interval time.Duration) AnalysisOption {
+	return func(o *analysisOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// OnAnalysisStatusChange registers a callback invoked each time the pipeline status changes
+func OnAnalysisStatusChange(fn func(previous, current DatapipeStatus)) AnalysisOption {
+	return func(o *analysisOptions) {
+		o.onStatusChange = fn
+	}
+}
+
+// RunAnalysis starts analysis and waits until it has completed or the context is done. Completion is the pipeline
+// returning to idle with a newer last complete analysis time than before the run, so a request the pipeline has
+// not yet picked up is not mistaken for a finished one.
+func (c *ClientWithResponses) RunAnalysis(ctx context.Context, options ...AnalysisOption) (*AnalysisRun, error) {
+	var config analysisOptions
+	for _, option := range options {
+		option(&config)
+	}
+
+	before, err := c.DatapipeStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run := &AnalysisRun{Before: before}
+
+	if config.onlyIfNewData {
+		ingested, err := c.ingestedSince(ctx, before.LastCompleteAnalysisAt)
+		if err != nil {
+			return nil, err
+		}
+		if !ingested && before.Status != Ingesting {
+			run.Skipped = true
+			run.After = before
+			return run, nil
+		}
+	}
+
+	if err := c.startAnalysis(ctx, config.enterprise); err != nil {
+		return nil, err
+	}
+	run.StartedAt = time.Now()
+
+	ticker := time.NewTicker(config.interval())
+	defer ticker.Stop()
+
+	previous := before
+	for {
+		select {
+		case <-ctx.Done():
+			run.After = previous
+			return run, ctx.Err()
+		case <-ticker.C:
+		}
+
+		current, err := c.DatapipeStatus(ctx)
+		if err != nil {
+			run.After = previous
+			return run, err
+		}
+		if current.Status != previous.Status && config.onStatusChange != nil {
+			config.onStatusChange(previous, current)
+		}
+		previous = current
+
+		if current.Status == Idle && current.LastCompleteAnalysisAt.After(before.LastCompleteAnalysisAt) {
+			run.After = current
+			run.CompletedAt = time.Now()
+			return run, nil
+		}
+	}
+}
+
+func (c *ClientWithResponses) startAnalysis(ctx context.Context, enterprise bool) error {
+	if enterprise {
+		response, err := c.StartAnalysisBheWithResponse(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if response.StatusCode() < 200 || response.StatusCode() > 299 {
+			return newStatusError("StartAnalysisBhe", response.StatusCode(), response.Body)
+		}
+		return nil
+	}
+
+	response, err := c.StartAnalysisWithResponse(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() < 200 || response.StatusCode() > 299 {
+		return newStatusError("StartAnalysis", response.StatusCode(), response.Body)
+	}
+	return nil
+}
+
+// Whether the most recently ingesting file upload job ingested data after the given time
+func (c *ClientWithResponses) ingestedSince(ctx context.Context, since time.Time) (bool, error) {
+	if since.IsZero() {
+		return true, nil
+	}
+	response, err := c.ListFileUploadJobsWithResponse(ctx, &ListFileUploadJobsParams{
+		SortBy: ptr("-last_ingest"),
+		Limit:  ptr(1),
+	})
+	if err != nil {
+		return false, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return false, newStatusError("ListFileUploadJobs", response.StatusCode(), response.Body)
+	}
+	if response.JSON200.Data == nil {
+		return false, nil
+	}
+	for _, job := range *response.JSON200.Data {
+		if job.LastIngest != nil && job.LastIngest.After(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,509 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditLogFormatter turns an audit log event into a single record for a SIEM
+type AuditLogFormatter interface {
+	Format(log ModelAuditLog) ([]byte, error)
+}
+
+// AuditLogSink receives formatted records
+type AuditLogSink interface {
+	Write(ctx context.Context, record []byte) error
+	Close() error
+}
+
+// auditLogFields is the flattened view of ModelAuditLog shared by the formatters
+type auditLogFields struct {
+	id         int64
+	createdAt  time.Time
+	action     string
+	status     string
+	actorId    string
+	actorName  string
+	actorEmail string
+	sourceIP   string
+	requestId  string
+	commitId   string
+	fields     map[string]interface{}
+}
+
+func flattenAuditLog(log ModelAuditLog) auditLogFields {
+	var flat auditLogFields
+	if log.Id != nil {
+		flat.id = *log.Id
+	}
+	if log.CreatedAt != nil {
+		flat.createdAt = log.CreatedAt.UTC()
+	}
+	if log.Action != nil {
+		flat.action = *log.Action
+	}
+	if log.Status != nil {
+		flat.status = string(*log.Status)
+	}
+	if log.ActorId != nil {
+		flat.actorId = log.ActorId.String()
+	}
+	if log.ActorName != nil {
+		flat.actorName = *log.ActorName
+	}
+	if log.ActorEmail != nil {
+		flat.actorEmail = string(*log.ActorEmail)
+	}
+	if log.SourceIpAddress != nil {
+		flat.sourceIP = *log.SourceIpAddress
+	}
+	if log.RequestId != nil {
+		flat.requestId = log.RequestId.String()
+	}
+	if log.CommitId != nil {
+		flat.commitId = log.CommitId.String()
+	}
+	if log.Fields != nil {
+		flat.fields = *log.Fields
+	}
+	return flat
+}
+
+// Map the audit log status onto the ECS event.outcome vocabulary
+func (f auditLogFields) outcome() string {
+	switch EnumAuditLogStatus(f.status) {
+	case Success:
+		return "success"
+	case Failure:
+		return "failure"
+	default:
+		return "unknown"
+	}
+}
+
+// ECSFormatter renders events as Elastic Common Schema JSON documents
+type ECSFormatter struct{}
+
+func (ECSFormatter) Format(log ModelAuditLog) ([]byte, error) {
+	flat := flattenAuditLog(log)
+
+	document := map[string]interface{}{
+		"@timestamp": flat.createdAt.Format(time.RFC3339Nano),
+		"ecs":        map[string]interface{}{"version": "8.11.0"},
+		"event": map[string]interface{}{
+			"id":       fmt.Sprint(flat.id),
+			"kind":     "event",
+			"category": []string{"configuration"},
+			"action":   flat.action,
+			"outcome":  flat.outcome(),
+			"provider": "bloodhound",
+		},
+		"user": map[string]interface{}{
+			"id":    flat.actorId,
+			"name":  flat.actorName,
+			"email": flat.actorEmail,
+		},
+		"bloodhound": map[string]interface{}{
+			"status":    flat.status,
+			"commit_id": flat.commitId,
+			"fields":    flat.fields,
+		},
+	}
+	if flat.sourceIP != "" {
+		document["source"] = map[string]interface{}{"ip": flat.sourceIP}
+	}
+	if flat.requestId != "" {
+		document["http"] = map[string]interface{}{"request": map[string]interface{}{"id": flat.requestId}}
+	}
+	return json.Marshal(document)
+}
+
+// CEFFormatter renders events as ArcSight Common Event Format lines
+type CEFFormatter struct {
+	Vendor  string
+	Product string
+	Version string
+}
+
+func (f CEFFormatter) Format(log ModelAuditLog) ([]byte, error) {
+	flat := flattenAuditLog(log)
+
+	vendor, product, version := f.Vendor, f.Product, f.Version
+	if vendor == "" {
+		vendor = "SpecterOps"
+	}
+	if product == "" {
+		product = "BloodHound"
+	}
+	if version == "" {
+		version = Version
+	}
+
+	// Failures rank higher than successes, intents are informational
+	severity := 3
+	switch EnumAuditLogStatus(flat.status) {
+	case Failure:
+		severity = 7
+	case Intent:
+		severity = 1
+	}
+
+	extensions := []string{
+		"rt=" + fmt.Sprint(flat.createdAt.UnixMilli()),
+		"externalId=" + fmt.Sprint(flat.id),
+		"act=" + escapeCEFExtension(flat.action),
+		"outcome=" + escapeCEFExtension(flat.status),
+		"suid=" + escapeCEFExtension(flat.actorId),
+		"suser=" + escapeCEFExtension(flat.actorName),
+	}
+	if flat.actorEmail != "" {
+		extensions = append(extensions, "cs1Label=actorEmail", "cs1="+escapeCEFExtension(flat.actorEmail))
+	}
+	if flat.sourceIP != "" {
+		extensions = append(extensions, "src="+escapeCEFExtension(flat.sourceIP))
+	}
+	if flat.requestId != "" {
+		extensions = append(extensions, "cs2Label=requestId", "cs2="+escapeCEFExtension(flat.requestId))
+	}
+	if flat.commitId != "" {
+		extensions = append(extensions, "cs3Label=commitId", "cs3="+escapeCEFExtension(flat.commitId))
+	}
+	if len(flat.fields) > 0 {
+		fields, err := json.Marshal(flat.fields)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, "msg="+escapeCEFExtension(string(fields)))
+	}
+
+	return []byte(fmt.Sprintf("CEF:0|%s|%s|%s|%s|%s|%d|%s",
+		escapeCEFHeader(vendor), escapeCEFHeader(product), escapeCEFHeader(version),
+		escapeCEFHeader(flat.action), escapeCEFHeader(flat.action), severity, strings.Join(extensions, " "))), nil
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+func escapeCEFHeader(value string) string {
+	return cefHeaderEscaper.Replace(value)
+}
+
+func escapeCEFExtension(value string) string {
+	return cefExtensionEscaper.Replace(value)
+}
+
+// SyslogFormatter renders events as RFC 5424 syslog messages with the audit details as structured data
+type SyslogFormatter struct {
+	// Facility defaults to 13, log audit
+	Facility int
+	Hostname string
+	AppName  string
+
+	// SDID of the structured data element. Defaults to an id under the documentation enterprise number.
+	SDID string
+}
+
+func (f SyslogFormatter) Format(log ModelAuditLog) ([]byte, error) {
+	flat := flattenAuditLog(log)
+
+	facility := f.Facility
+	if facility == 0 {
+		facility = 13
+	}
+	severity := 6 // informational
+	if EnumAuditLogStatus(flat.status) == Failure {
+		severity = 4 // warning
+	}
+	hostname, appName, sdid := f.Hostname, f.AppName, f.SDID
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	if appName == "" {
+		appName = "bloodhound"
+	}
+	if sdid == "" {
+		sdid = "bloodhound@32473"
+	}
+
+	params := []string{
+		syslogParam("id", fmt.Sprint(flat.id)),
+		syslogParam("action", flat.action),
+		syslogParam("status", flat.status),
+		syslogParam("actorId", flat.actorId),
+		syslogParam("actorName", flat.actorName),
+	}
+	if flat.actorEmail != "" {
+		params = append(params, syslogParam("actorEmail", flat.actorEmail))
+	}
+	if flat.sourceIP != "" {
+		params = append(params, syslogParam("sourceIp", flat.sourceIP))
+	}
+	if flat.requestId != "" {
+		params = append(params, syslogParam("requestId", flat.requestId))
+	}
+	if flat.commitId != "" {
+		params = append(params, syslogParam("commitId", flat.commitId))
+	}
+
+	message := fmt.Sprintf("%s %s %s", flat.actorName, flat.action, flat.status)
+	if len(flat.fields) > 0 {
+		fields, err := json.Marshal(flat.fields)
+		if err != nil {
+			return nil, err
+		}
+		message += " " + string(fields)
+	}
+
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s - %s [%s %s] %s",
+		facility*8+severity, flat.createdAt.Format(time.RFC3339Nano), syslogHeaderField(hostname, 255),
+		syslogHeaderField(appName, 48), syslogHeaderField(flat.action, 32), sdid, strings.Join(params, " "), message)), nil
+}
+
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func syslogParam(name, value string) string {
+	return name + `="` + syslogParamEscaper.Replace(value) + `"`
+}
+
+// Header fields are printable US-ASCII without spaces, with "-" standing in for empty values. Values longer than
+// the RFC 5424 limit of the field are truncated: 255 for HOSTNAME, 48 for APP-NAME, 128 for PROCID and 32 for
+// MSGID.
+func syslogHeaderField(value string, max int) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, value)
+	if value == "" {
+		return "-"
+	}
+	if len(value) > max {
+		value = value[:max]
+	}
+	return value
+}
+
+// SplunkHECFormatter wraps events in the Splunk HTTP Event Collector envelope
+type SplunkHECFormatter struct {
+	Host       string
+	Source     string
+	SourceType string
+	Index      string
+}
+
+func (f SplunkHECFormatter) Format(log ModelAuditLog) ([]byte, error) {
+	flat := flattenAuditLog(log)
+
+	source, sourceType := f.Source, f.SourceType
+	if source == "" {
+		source = "bloodhound"
+	}
+	if sourceType == "" {
+		sourceType = "bloodhound:audit"
+	}
+
+	envelope := map[string]interface{}{
+		"time":       float64(flat.createdAt.UnixMilli()) / 1000,
+		"source":     source,
+		"sourcetype": sourceType,
+		"event":      log,
+	}
+	if f.Host != "" {
+		envelope["host"] = f.Host
+	}
+	if f.Index != "" {
+		envelope["index"] = f.Index
+	}
+	return json.Marshal(envelope)
+}
+
+// WriterSink writes one record per line to an io.Writer, such as os.Stdout
+type WriterSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{
+		writer: writer,
+	}
+}
+
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+func (s *WriterSink) Write(ctx context.Context, record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.writer.Write(append(record[:len(record):len(record)], '\n'))
+	return err
+}
+
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// FileSink appends one record per line to a file
+type FileSink struct {
+	WriterSink
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{
+		WriterSink: WriterSink{writer: file},
+		file:       file,
+	}, nil
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// SyslogSink sends records to a syslog collector over UDP, one record per datagram, or TCP using RFC 6587
+// octet counting framing. The connection is re-established on the next write after a failure.
+type SyslogSink struct {
+	Network string
+	Address string
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func NewSyslogSink(network, address string) (*SyslogSink, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	return &SyslogSink{
+		Network: network,
+		Address: address,
+		Timeout: 5 * time.Second,
+	}, nil
+}
+
+func (s *SyslogSink) Write(ctx context.Context, record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: s.Timeout}
+		conn, err := dialer.DialContext(ctx, s.Network, s.Address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	frame := record
+	if strings.HasPrefix(s.Network, "tcp") {
+		frame = append([]byte(fmt.Sprintf("%d ", len(record))), record...)
+	}
+	if s.Timeout > 0 {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.Timeout))
+	}
+	if _, err := s.conn.Write(frame); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// AuditLogForwarder formats audit log events and writes them to a sink. It works with any reader that produces
+// ModelAuditLog values, such as a ListAuditLogs response or an AuditLogStream.
+type AuditLogForwarder struct {
+	Formatter AuditLogFormatter
+	Sink      AuditLogSink
+}
+
+func NewAuditLogForwarder(formatter AuditLogFormatter, sink AuditLogSink) (*AuditLogForwarder, error) {
+	if formatter == nil || sink == nil {
+		return nil, errors.New("formatter and sink must not be nil")
+	}
+	return &AuditLogForwarder{
+		Formatter: formatter,
+		Sink:      sink,
+	}, nil
+}
+
+// Forward the events in creation order
+func (f *AuditLogForwarder) Forward(ctx context.Context, logs ...ModelAuditLog) error {
+	sorted := append([]ModelAuditLog(nil), logs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].CreatedAt == nil || sorted[j].CreatedAt == nil {
+			return false
+		}
+		return sorted[i].CreatedAt.Before(*sorted[j].CreatedAt)
+	})
+	for _, log := range sorted {
+		if err := f.forward(ctx, log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Forward every event produced by seq, for example AuditLogStream.Seq, until it ends or a write fails
+func (f *AuditLogForwarder) ForwardSeq(ctx context.Context, seq func(yield func(ModelAuditLog) bool)) error {
+	var err error
+	seq(func(log ModelAuditLog) bool {
+		err = f.forward(ctx, log)
+		return err == nil
+	})
+	return err
+}
+
+func (f *AuditLogForwarder) forward(ctx context.Context, log ModelAuditLog) error {
+	record, err := f.Formatter.Format(log)
+	if err != nil {
+		return err
+	}
+	return f.Sink.Write(ctx, record)
+}
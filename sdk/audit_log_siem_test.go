@@ -0,0 +1,142 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+func goldenAuditLog() ModelAuditLog {
+	return ModelAuditLog{
+		Id:              ptr(int64(42)),
+		CreatedAt:       ptr(time.Date(2024, 5, 1, 10, 0, 0, 123000000, time.UTC)),
+		Action:          ptr("CreateUser"),
+		Status:          ptr(Failure),
+		ActorId:         ptr(openapi_types.UUID{1}),
+		ActorName:       ptr("alice"),
+		ActorEmail:      ptr(openapi_types.Email("alice@example.com")),
+		SourceIpAddress: ptr("192.0.2.10"),
+		RequestId:       ptr(openapi_types.UUID{2}),
+		CommitId:        ptr(openapi_types.UUID{3}),
+		Fields:          ptr(map[string]interface{}{"principal": "bob|ops=1"}),
+	}
+}
+
+func TestAuditLogFormattersGoldenOutput(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		formatter AuditLogFormatter
+		want      string
+	}{
+		{"ECS", ECSFormatter{}, `{"@timestamp":"2024-05-01T10:00:00.123Z","bloodhound":{"commit_id":"03000000-0000-0000-0000-000000000000","fields":{"principal":"bob|ops=1"},"status":"failure"},"ecs":{"version":"8.11.0"},"event":{"action":"CreateUser","category":["configuration"],"id":"42","kind":"event","outcome":"failure","provider":"bloodhound"},"http":{"request":{"id":"02000000-0000-0000-0000-000000000000"}},"source":{"ip":"192.0.2.10"},"user":{"email":"alice@example.com","id":"01000000-0000-0000-0000-000000000000","name":"alice"}}`},
+		{"CEF", CEFFormatter{Version: "5.0"}, `CEF:0|SpecterOps|BloodHound|5.0|CreateUser|CreateUser|7|rt=1714557600123 externalId=42 act=CreateUser outcome=failure suid=01000000-0000-0000-0000-000000000000 suser=alice cs1Label=actorEmail cs1=alice@example.com src=192.0.2.10 cs2Label=requestId cs2=02000000-0000-0000-0000-000000000000 cs3Label=commitId cs3=03000000-0000-0000-0000-000000000000 msg={"principal":"bob|ops\=1"}`},
+		{"syslog", SyslogFormatter{Hostname: "bh01"}, `<108>1 2024-05-01T10:00:00.123Z bh01 bloodhound - CreateUser [bloodhound@32473 id="42" action="CreateUser" status="failure" actorId="01000000-0000-0000-0000-000000000000" actorName="alice" actorEmail="alice@example.com" sourceIp="192.0.2.10" requestId="02000000-0000-0000-0000-000000000000" commitId="03000000-0000-0000-0000-000000000000"] alice CreateUser failure {"principal":"bob|ops=1"}`},
+		{"HEC", SplunkHECFormatter{Host: "bh01", Index: "audit"}, `{"event":{"action":"CreateUser","actor_email":"alice@example.com","actor_id":"01000000-0000-0000-0000-000000000000","actor_name":"alice","commit_id":"03000000-0000-0000-0000-000000000000","created_at":"2024-05-01T10:00:00.123Z","fields":{"principal":"bob|ops=1"},"id":42,"request_id":"02000000-0000-0000-0000-000000000000","source_ip_address":"192.0.2.10","status":"failure"},"host":"bh01","index":"audit","source":"bloodhound","sourcetype":"bloodhound:audit","time":1714557600.123}`},
+	} {
+		record, err := test.formatter.Format(goldenAuditLog())
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if string(record) != test.want {
+			t.Errorf("%s record is\n%s\nwant\n%s", test.name, record, test.want)
+		}
+	}
+}
+
+func TestSyslogFormatterTruncatesHeaderFields(t *testing.T) {
+	log := goldenAuditLog()
+	log.Action = ptr(strings.Repeat("a", 40))
+	record, err := SyslogFormatter{Hostname: "bh01", AppName: strings.Repeat("b", 60) + " app"}.Format(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := strings.Fields(string(record))
+	if appName := header[3]; appName != strings.Repeat("b", 48) {
+		t.Errorf("APP-NAME is %q, want it truncated to 48 characters", appName)
+	}
+	if msgId := header[5]; msgId != strings.Repeat("a", 32) {
+		t.Errorf("MSGID is %q, want it truncated to 32 characters", msgId)
+	}
+}
+
+func TestAuditLogForwarderRetriesFailedWrite(t *testing.T) {
+	now := time.Now().UTC()
+	logs := []ModelAuditLog{
+		{Id: ptr(int64(1)), CreatedAt: ptr(now.Add(-2 * time.Second))},
+		{Id: ptr(int64(2)), CreatedAt: ptr(now.Add(-time.Second))},
+	}
+	stream, err := NewAuditLogStream(auditLogServer(t, logs), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Start = now.Add(-time.Minute)
+	stream.PollInterval = time.Hour
+
+	sink := &failingAuditLogSink{failId: 2}
+	forwarder, err := NewAuditLogForwarder(ECSFormatter{}, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := forwarder.ForwardSeq(context.Background(), stream.Seq(context.Background())); err == nil {
+		t.Fatal("ForwardSeq did not report the failed write")
+	}
+	if sink.written != 1 {
+		t.Fatalf("wrote %d events before the failure, want 1", sink.written)
+	}
+
+	again, err := stream.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 1 || *again[0].Id != 2 {
+		t.Fatalf("the event that failed to write was not polled again")
+	}
+}
+
+// Fails to write the event with id failId
+type failingAuditLogSink struct {
+	failId  int64
+	written int
+}
+
+func (s *failingAuditLogSink) Write(ctx context.Context, record []byte) error {
+	var event struct {
+		Event struct {
+			Id string `json:"id"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(record, &event); err != nil {
+		return err
+	}
+	if event.Event.Id == strconv.FormatInt(s.failId, 10) {
+		return errors.New("sink unavailable")
+	}
+	s.written++
+	return nil
+}
+
+func (s *failingAuditLogSink) Close() error {
+	return nil
+}
@@ -0,0 +1,313 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAuditLogPollInterval = 10 * time.Second
+	defaultAuditLogPageSize     = 100
+
+	// The cursor is rewound by this much on every poll so events committed slightly out of order are not
+	// missed. Events seen inside the overlap are dropped by id.
+	auditLogCursorOverlap = 5 * time.Second
+)
+
+// AuditLogCheckpoint is the position of an AuditLogStream. SeenIds holds the ids already delivered inside the
+// cursor overlap so a resumed stream does not deliver them twice.
+type AuditLogCheckpoint struct {
+	After   time.Time `json:"after"`
+	SeenIds []int64   `json:"seen_ids,omitempty"`
+}
+
+// AuditLogCheckpointStore persists the position of an AuditLogStream between restarts
+type AuditLogCheckpointStore interface {
+	Load(ctx context.Context) (AuditLogCheckpoint, bool, error)
+	Save(ctx context.Context, checkpoint AuditLogCheckpoint) error
+}
+
+// MemoryAuditLogCheckpointStore keeps the checkpoint in memory only
+type MemoryAuditLogCheckpointStore struct {
+	mu         sync.Mutex
+	checkpoint *AuditLogCheckpoint
+}
+
+func (s *MemoryAuditLogCheckpointStore) Load(ctx context.Context) (AuditLogCheckpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checkpoint == nil {
+		return AuditLogCheckpoint{}, false, nil
+	}
+	return *s.checkpoint, true, nil
+}
+
+func (s *MemoryAuditLogCheckpointStore) Save(ctx context.Context, checkpoint AuditLogCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint = &checkpoint
+	return nil
+}
+
+// FileAuditLogCheckpointStore keeps the checkpoint in a JSON file
+type FileAuditLogCheckpointStore struct {
+	Path string
+}
+
+func (s *FileAuditLogCheckpointStore) Load(ctx context.Context) (AuditLogCheckpoint, bool, error) {
+	var checkpoint AuditLogCheckpoint
+	content, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return checkpoint, false, nil
+	} else if err != nil {
+		return checkpoint, false, err
+	}
+	if err := json.Unmarshal(content, &checkpoint); err != nil {
+		return checkpoint, false, err
+	}
+	return checkpoint, true, nil
+}
+
+func (s *FileAuditLogCheckpointStore) Save(ctx context.Context, checkpoint AuditLogCheckpoint) error {
+	content, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Path, content)
+}
+
+// AuditLogStream follows the audit log by polling ListAuditLogs with a moving After cursor. Events are
+// delivered in creation order, at least once, and deduplicated by id.
+type AuditLogStream struct {
+	Client ClientWithResponsesInterface
+
+	// Filters applied to every poll. Skip, Limit, SortBy, After and Before are managed by the stream.
+	Params ListAuditLogsParams
+
+	// Store the checkpoint is loaded from on the first poll and saved to on Commit
+	Store AuditLogCheckpointStore
+
+	// Start is where the stream begins when the store holds no checkpoint. Defaults to the time of the first
+	// poll, so only new events are delivered.
+	Start time.Time
+
+	PollInterval time.Duration
+	PageSize     int
+
+	mu      sync.Mutex
+	loaded  bool
+	cursor  time.Time
+	seen    map[int64]time.Time
+	lastErr error
+}
+
+func NewAuditLogStream(client ClientWithResponsesInterface, store AuditLogCheckpointStore) (*AuditLogStream, error) {
+	if client == nil {
+		return nil, errors.New("client must not be nil")
+	}
+	if store == nil {
+		store = &MemoryAuditLogCheckpointStore{}
+	}
+	return &AuditLogStream{
+		Client:       client,
+		Store:        store,
+		PollInterval: defaultAuditLogPollInterval,
+		PageSize:     defaultAuditLogPageSize,
+	}, nil
+}
+
+// Fetch every event created since the last poll and advance the cursor. Call Commit once the events are
+// handled to persist the new position.
+func (s *AuditLogStream) Poll(ctx context.Context) ([]ModelAuditLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	pageSize := s.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultAuditLogPageSize
+	}
+
+	params := s.Params
+	params.After = ptr(s.cursor.Add(-auditLogCursorOverlap))
+	params.Before = ptr(time.Now().UTC())
+	params.SortBy = ptr("created_at")
+	params.Limit = ptr(pageSize)
+
+	var events []ModelAuditLog
+	for skip := 0; ; skip += pageSize {
+		params.Skip = ptr(skip)
+		response, err := s.Client.ListAuditLogsWithResponse(ctx, &params)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode() != 200 || response.JSON200 == nil {
+			return nil, newStatusError("ListAuditLogs", response.StatusCode(), response.Body)
+		}
+
+		var logs []ModelAuditLog
+		if data := response.JSON200.Data; data != nil && data.Logs != nil {
+			logs = *data.Logs
+		}
+		for _, log := range logs {
+			if log.Id == nil || log.CreatedAt == nil {
+				continue
+			}
+			if _, ok := s.seen[*log.Id]; ok {
+				continue
+			}
+			s.seen[*log.Id] = *log.CreatedAt
+			events = append(events, log)
+		}
+		if len(logs) < pageSize {
+			break
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(*events[j].CreatedAt)
+	})
+	if len(events) > 0 && events[len(events)-1].CreatedAt.After(s.cursor) {
+		s.cursor = *events[len(events)-1].CreatedAt
+	}
+
+	// Forget ids that can no longer fall inside the overlap
+	for id, createdAt := range s.seen {
+		if createdAt.Before(s.cursor.Add(-auditLogCursorOverlap)) {
+			delete(s.seen, id)
+		}
+	}
+
+	return events, nil
+}
+
+// Save the current position to the checkpoint store
+func (s *AuditLogStream) Commit(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loaded {
+		return nil
+	}
+	return s.Store.Save(ctx, s.checkpoint())
+}
+
+// Deliver events on a channel until the context is cancelled or a poll fails. The error channel receives at
+// most one error and both channels are closed when the stream stops.
+func (s *AuditLogStream) Events(ctx context.Context) (<-chan ModelAuditLog, <-chan error) {
+	return followChannel(ctx, s.follow)
+}
+
+// Seq returns an iterator, compatible with iter.Seq, over the events of the stream. Iteration stops when the
+// loop breaks, the context is cancelled or a poll fails; check Err afterwards. The event the loop breaks on is
+// not committed and is delivered again by the next poll.
+func (s *AuditLogStream) Seq(ctx context.Context) func(yield func(ModelAuditLog) bool) {
+	return func(yield func(ModelAuditLog) bool) {
+		_ = s.follow(ctx, yield)
+	}
+}
+
+// Err returns the error that stopped the last Events or Seq, if any. Context cancellation is not an error.
+func (s *AuditLogStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+func (s *AuditLogStream) follow(ctx context.Context, deliver func(ModelAuditLog) bool) error {
+	s.setErr(nil)
+
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = defaultAuditLogPollInterval
+	}
+	loop := pollLoop[ModelAuditLog]{
+		interval: interval,
+		poll: func(ctx context.Context) ([]ModelAuditLog, bool, error) {
+			events, err := s.Poll(ctx)
+			return events, false, err
+		},
+		rewind: s.rewind,
+		commit: s.Commit,
+	}
+	err := loop.follow(ctx, deliver)
+	s.setErr(err)
+	return err
+}
+
+// Move the cursor back so events polled but never delivered are fetched again
+func (s *AuditLogStream) rewind(undelivered []ModelAuditLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(undelivered) == 0 {
+		return
+	}
+	for _, event := range undelivered {
+		delete(s.seen, *event.Id)
+	}
+	s.cursor = *undelivered[0].CreatedAt
+}
+
+func (s *AuditLogStream) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+}
+
+func (s *AuditLogStream) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	s.seen = map[int64]time.Time{}
+	checkpoint, ok, err := s.Store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if ok {
+		s.cursor = checkpoint.After
+		for _, id := range checkpoint.SeenIds {
+			s.seen[id] = checkpoint.After
+		}
+	} else if !s.Start.IsZero() {
+		s.cursor = s.Start.UTC()
+	} else {
+		s.cursor = time.Now().UTC()
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *AuditLogStream) checkpoint() AuditLogCheckpoint {
+	checkpoint := AuditLogCheckpoint{After: s.cursor}
+	for id := range s.seen {
+		checkpoint.SeenIds = append(checkpoint.SeenIds, id)
+	}
+	sort.Slice(checkpoint.SeenIds, func(i, j int) bool {
+		return checkpoint.SeenIds[i] < checkpoint.SeenIds[j]
+	})
+	return checkpoint
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func auditLogServer(t *testing.T, logs []ModelAuditLog) *ClientWithResponses {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"logs": logs}})
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestAuditLogStreamRedeliversEventsNotSentOnCancel(t *testing.T) {
+	now := time.Now().UTC()
+	var logs []ModelAuditLog
+	for i := int64(1); i <= 3; i++ {
+		logs = append(logs, ModelAuditLog{Id: ptr(i), CreatedAt: ptr(now.Add(time.Duration(i-4) * time.Second))})
+	}
+	store := &MemoryAuditLogCheckpointStore{}
+	stream, err := NewAuditLogStream(auditLogServer(t, logs), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Start = now.Add(-time.Minute)
+	stream.PollInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errs := stream.Events(ctx)
+	if first := <-events; *first.Id != 1 {
+		t.Fatalf("first event is %d, want 1", *first.Id)
+	}
+	// Event 2 is waiting to be sent when the context is cancelled
+	cancel()
+	for err := range errs {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkpoint, _, _ := store.Load(context.Background())
+	for _, id := range checkpoint.SeenIds {
+		if id != 1 {
+			t.Errorf("checkpoint marks undelivered event %d as seen", id)
+		}
+	}
+
+	again, err := stream.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 2 || *again[0].Id != 2 || *again[1].Id != 3 {
+		var ids []int64
+		for _, event := range again {
+			ids = append(ids, *event.Id)
+		}
+		t.Fatalf("next poll returned %v, want [2 3]", ids)
+	}
+}
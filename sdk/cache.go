@@ -0,0 +1,404 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response stored by a ResponseCache
+type CachedResponse struct {
+	// Key is the cache key, made of the identity hash, the operation id and the request URL
+	Key        string      `json:"key"`
+	Operation  string      `json:"operation"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Expires    time.Time   `json:"expires"`
+}
+
+// ResponseCache is a storage backend of CachingDoer. Implementations must be safe for concurrent use.
+type ResponseCache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(response *CachedResponse) error
+	Delete(key string) error
+
+	// Purge deletes every response the match function returns true for
+	Purge(match func(response *CachedResponse) bool) error
+}
+
+// LRUResponseCache is an in-memory ResponseCache holding at most MaxEntries responses
+type LRUResponseCache struct {
+	maxEntries int
+	mutex      sync.Mutex
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+// NewLRUResponseCache creates an in-memory cache evicting the least recently used response beyond maxEntries
+func NewLRUResponseCache(maxEntries int) (*LRUResponseCache, error) {
+	if maxEntries <= 0 {
+		return nil, errors.New("the cache must hold at least one entry")
+	}
+	return &LRUResponseCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}, nil
+}
+
+func (c *LRUResponseCache) Get(key string) (*CachedResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*CachedResponse), true
+}
+
+func (c *LRUResponseCache) Set(response *CachedResponse) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[response.Key]; ok {
+		element.Value = response
+		c.order.MoveToFront(element)
+		return nil
+	}
+	c.entries[response.Key] = c.order.PushFront(response)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*CachedResponse).Key)
+	}
+	return nil
+}
+
+func (c *LRUResponseCache) Delete(key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+func (c *LRUResponseCache) Purge(match func(response *CachedResponse) bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, element := range c.entries {
+		if match(element.Value.(*CachedResponse)) {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+// DiskResponseCache is a ResponseCache keeping one JSON file per response in a directory, so the cache survives
+// restarts and can be shared by processes running as the same user
+type DiskResponseCache struct {
+	Dir string
+
+	mutex sync.Mutex
+}
+
+// NewDiskResponseCache creates a cache in dir, creating the directory if needed
+func NewDiskResponseCache(dir string) (*DiskResponseCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+	return &DiskResponseCache{Dir: dir}, nil
+}
+
+func (c *DiskResponseCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *DiskResponseCache) read(path string) (*CachedResponse, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var response CachedResponse
+	if err := json.Unmarshal(content, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *DiskResponseCache) Get(key string) (*CachedResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	response, err := c.read(c.path(key))
+	if err != nil || response.Key != key {
+		return nil, false
+	}
+	return response, true
+}
+
+func (c *DiskResponseCache) Set(response *CachedResponse) error {
+	content, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return writeFileAtomic(c.path(response.Key), content)
+}
+
+func (c *DiskResponseCache) Delete(key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := os.Remove(c.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (c *DiskResponseCache) Purge(match func(response *CachedResponse) bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(c.Dir, entry.Name())
+		// Unreadable files are removed too, they can never be served
+		if response, err := c.read(path); err == nil && !match(response) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DefaultCacheTTLs are the TTLs of NewCachingDoer. Operations without a TTL are never cached.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"GetAvailableDomains": 5 * time.Minute,
+	"GetDomainEntity":     5 * time.Minute,
+	"GetEntity":           time.Minute,
+	"GetUserEntity":       time.Minute,
+	"GetComputerEntity":   time.Minute,
+	"GetGroupEntity":      time.Minute,
+	"GetOuEntity":         time.Minute,
+	"GetGpoEntity":        time.Minute,
+	"GetContainerEntity":  time.Minute,
+	"GetAzureEntity":      time.Minute,
+	"Search":              time.Minute,
+	"GetSearchResult":     time.Minute,
+}
+
+// DefaultCacheInvalidations are the invalidation rules of NewCachingDoer. New data lands in the graph when a
+// file upload job ends or an analysis runs, so those drop every cached response.
+var DefaultCacheInvalidations = map[string][]string{
+	"EndFileUploadJob":         nil,
+	"StartAnalysis":            nil,
+	"StartAnalysisBhe":         nil,
+	"DeleteBloodHoundDatabase": nil,
+	"UpdateDomainEntity":       {"GetDomainEntity", "GetAvailableDomains"},
+}
+
+// CachingDoer is an HttpRequestDoer serving repeated reads from a ResponseCache. Only successful GET requests of
+// operations with a TTL are cached. Responses are keyed by the Authorization header so identities never see each
+// other's responses; sign requests through a RequestEditorFn so the header is set before the doer runs.
+//
+//	cache, _ := NewLRUResponseCache(1000)
+//	client, _ := NewClientWithResponses(server, WithHTTPClient(NewCachingDoer(http.DefaultClient, cache)))
+type CachingDoer struct {
+	Next  HttpRequestDoer
+	Cache ResponseCache
+
+	// TTLs per operation id
+	TTLs map[string]time.Duration
+
+	// Invalidations maps a mutating operation id to the operation ids whose responses are dropped after it
+	// succeeds. A nil list drops every response.
+	Invalidations map[string][]string
+
+	// OnInvalidate, when set, is called after responses are dropped by a mutating call or Invalidate, with the
+	// invalidated operations or nil for all of them
+	OnInvalidate func(trigger string, operations []string)
+
+	// OnInvalidateError, when set, is called when the cached responses of a successful mutating call could not be
+	// dropped, not even by dropping the whole cache. The mutation's response is returned all the same.
+	OnInvalidateError func(trigger string, err error)
+
+	Now func() time.Time
+}
+
+// NewCachingDoer wraps next with the default TTLs and invalidation rules
+func NewCachingDoer(next HttpRequestDoer, cache ResponseCache) *CachingDoer {
+	ttls := make(map[string]time.Duration, len(DefaultCacheTTLs))
+	for operation, ttl := range DefaultCacheTTLs {
+		ttls[operation] = ttl
+	}
+	invalidations := make(map[string][]string, len(DefaultCacheInvalidations))
+	for operation, invalidated := range DefaultCacheInvalidations {
+		invalidations[operation] = invalidated
+	}
+	return &CachingDoer{
+		Next:          next,
+		Cache:         cache,
+		TTLs:          ttls,
+		Invalidations: invalidations,
+	}
+}
+
+func (d *CachingDoer) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+func (d *CachingDoer) next() HttpRequestDoer {
+	if d.Next != nil {
+		return d.Next
+	}
+	return http.DefaultClient
+}
+
+// Key of a request: the hashed identity, the operation id and the request URL
+func cacheKey(req *http.Request, operation string) string {
+	identity := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	return hex.EncodeToString(identity[:8]) + " " + operation + " " + req.URL.String()
+}
+
+func (d *CachingDoer) Do(req *http.Request) (*http.Response, error) {
+	operation, _ := OperationForRequest(req)
+
+	if req.Method != http.MethodGet {
+		response, err := d.next().Do(req)
+		if err == nil && response.StatusCode >= 200 && response.StatusCode < 300 {
+			if invalidated, ok := d.Invalidations[operation]; ok {
+				// The server made the change, so the response is returned whatever happens to the cache
+				if err := d.invalidate(operation, invalidated); err != nil && invalidated != nil {
+					err = d.invalidate(operation, nil)
+				}
+				if err != nil && d.OnInvalidateError != nil {
+					d.OnInvalidateError(operation, fmt.Errorf("error invalidating cached responses after %s: %w", operation, err))
+				}
+			}
+		}
+		return response, err
+	}
+
+	ttl, ok := d.TTLs[operation]
+	if !ok || ttl <= 0 {
+		return d.next().Do(req)
+	}
+
+	key := cacheKey(req, operation)
+	if !strings.Contains(req.Header.Get("Cache-Control"), "no-cache") {
+		if cached, ok := d.Cache.Get(key); ok {
+			if d.now().Before(cached.Expires) {
+				return cachedHTTPResponse(req, cached), nil
+			}
+			_ = d.Cache.Delete(key)
+		}
+	}
+
+	response, err := d.next().Do(req)
+	if err != nil || response.StatusCode != http.StatusOK {
+		return response, err
+	}
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	// A failing backend only costs the cache hit, the response is still good
+	_ = d.Cache.Set(&CachedResponse{
+		Key:        key,
+		Operation:  operation,
+		StatusCode: response.StatusCode,
+		Header:     response.Header.Clone(),
+		Body:       body,
+		Expires:    d.now().Add(ttl),
+	})
+	return response, nil
+}
+
+func cachedHTTPResponse(req *http.Request, cached *CachedResponse) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cached.StatusCode, http.StatusText(cached.StatusCode)),
+		StatusCode:    cached.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cached.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+}
+
+// Invalidate drops the cached responses of the given operation ids, or every response when none are given. Use
+// it after changes the doer cannot see, such as data uploaded by another client.
+func (d *CachingDoer) Invalidate(operations ...string) error {
+	if len(operations) == 0 {
+		operations = nil
+	}
+	return d.invalidate("", operations)
+}
+
+func (d *CachingDoer) invalidate(trigger string, operations []string) error {
+	var err error
+	if operations == nil {
+		err = d.Cache.Purge(func(*CachedResponse) bool { return true })
+	} else {
+		set := make(map[string]struct{}, len(operations))
+		for _, operation := range operations {
+			set[operation] = struct{}{}
+		}
+		err = d.Cache.Purge(func(response *CachedResponse) bool {
+			_, ok := set[response.Operation]
+			return ok
+		})
+	}
+	if err == nil && d.OnInvalidate != nil {
+		d.OnInvalidate(trigger, operations)
+	}
+	return err
+}
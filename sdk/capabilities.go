@@ -0,0 +1,156 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrOperationNotSupported is matched by the error returned when the server does not serve an operation
+var ErrOperationNotSupported = errors.New("operation not supported on this server")
+
+// UnsupportedOperationError names the operations a server does not serve
+type UnsupportedOperationError struct {
+	Operations []string
+	Edition    Editions
+}
+
+func (e *UnsupportedOperationError) Error() string {
+	return fmt.Sprintf("%s not supported on this server (%s edition)", strings.Join(e.Operations, ", "), e.Edition)
+}
+
+func (e *UnsupportedOperationError) Unwrap() error {
+	return ErrOperationNotSupported
+}
+
+// Capabilities describes what a server speaks
+type Capabilities struct {
+	ServerVersion        string
+	APIVersion           string
+	DeprecatedAPIVersion string
+
+	// Edition is EditionEnterprise or EditionCommunity
+	Edition Editions
+
+	// FeatureFlags maps feature flag keys to whether they are enabled
+	FeatureFlags map[string]bool
+}
+
+// Capabilities asks the server for its version and feature flags and works out its edition by probing a
+// read-only Enterprise endpoint, which Community servers answer with 404 Not Found
+func (c *ClientWithResponses) Capabilities(ctx context.Context) (*Capabilities, error) {
+	version, err := c.GetApiVersionWithResponse(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if version.StatusCode() != 200 || version.JSON200 == nil {
+		return nil, newStatusError("GetApiVersion", version.StatusCode(), version.Body)
+	}
+
+	capabilities := &Capabilities{
+		Edition:      EditionCommunity,
+		FeatureFlags: map[string]bool{},
+	}
+	if data := version.JSON200.Data; data != nil {
+		if data.ServerVersion != nil {
+			capabilities.ServerVersion = *data.ServerVersion
+		}
+		if data.API != nil && data.API.CurrentVersion != nil {
+			capabilities.APIVersion = *data.API.CurrentVersion
+		}
+		if data.API != nil && data.API.DeprecatedVersion != nil {
+			capabilities.DeprecatedAPIVersion = *data.API.DeprecatedVersion
+		}
+	}
+
+	flags, err := c.ListFeatureFlagsWithResponse(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if flags.StatusCode() != 200 || flags.JSON200 == nil {
+		return nil, newStatusError("ListFeatureFlags", flags.StatusCode(), flags.Body)
+	}
+	if flags.JSON200.Data != nil {
+		for _, flag := range *flags.JSON200.Data {
+			if flag.Key != nil {
+				capabilities.FeatureFlags[*flag.Key] = flag.Enabled != nil && *flag.Enabled
+			}
+		}
+	}
+
+	// The route exists when it answers, including with Forbidden for tokens without client permissions. Only
+	// Not Found tells a Community server; any other status is an error rather than a guess.
+	clients, err := c.ListClients(ctx, &ListClientsParams{Limit: ptr(1)})
+	if err != nil {
+		return nil, err
+	}
+	defer clients.Body.Close()
+	switch {
+	case clients.StatusCode >= 200 && clients.StatusCode < 300, clients.StatusCode == http.StatusForbidden:
+		capabilities.Edition = EditionEnterprise
+	case clients.StatusCode == http.StatusNotFound:
+		capabilities.Edition = EditionCommunity
+	default:
+		body, _ := io.ReadAll(clients.Body)
+		return nil, newStatusError("ListClients", clients.StatusCode, body)
+	}
+
+	return capabilities, nil
+}
+
+// Supports reports whether the server serves the operation, by its operation id such as "StartAnalysisBhe"
+func (c *Capabilities) Supports(operation string) bool {
+	info, ok := Operations[operation]
+	return ok && info.Editions.Has(c.Edition)
+}
+
+// SupportedOperations lists the operation ids served by the server
+func (c *Capabilities) SupportedOperations() []string {
+	var operations []string
+	for operation, info := range Operations {
+		if info.Editions.Has(c.Edition) {
+			operations = append(operations, operation)
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+// Require returns an *UnsupportedOperationError naming every operation the server does not serve
+func (c *Capabilities) Require(operations ...string) error {
+	var unsupported []string
+	for _, operation := range operations {
+		if !c.Supports(operation) {
+			unsupported = append(unsupported, operation)
+		}
+	}
+	if len(unsupported) > 0 {
+		return &UnsupportedOperationError{Operations: unsupported, Edition: c.Edition}
+	}
+	return nil
+}
+
+// FeatureEnabled reports whether the feature flag with the given key is enabled
+func (c *Capabilities) FeatureEnabled(key string) bool {
+	return c.FeatureFlags[key]
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCapabilitiesEditionFromClientsStatus(t *testing.T) {
+	for status, want := range map[int]Editions{
+		http.StatusOK:                  EditionEnterprise,
+		http.StatusForbidden:           EditionEnterprise,
+		http.StatusNotFound:            EditionCommunity,
+		http.StatusUnauthorized:        0,
+		http.StatusInternalServerError: 0,
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/api/version":
+				w.Write([]byte(`{"data":{"server_version":"v5.0.0"}}`))
+			case "/api/v2/features":
+				w.Write([]byte(`{"data":[]}`))
+			default:
+				w.WriteHeader(status)
+			}
+		}))
+		client, err := NewClientWithResponses(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		capabilities, err := client.Capabilities(context.Background())
+		server.Close()
+
+		var statusError *StatusError
+		if want == 0 {
+			if !errors.As(err, &statusError) || statusError.StatusCode != status {
+				t.Errorf("clients status %d returned %v, want a StatusError", status, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("clients status %d: %v", status, err)
+		} else if capabilities.Edition != want {
+			t.Errorf("clients status %d detected %s, want %s", status, capabilities.Edition, want)
+		}
+	}
+}
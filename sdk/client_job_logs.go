@@ -0,0 +1,205 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultJobLogPollInterval = 5 * time.Second
+
+// JobLogStream follows the log of a client job by polling GetClientJobLog, which returns the whole log, and
+// delivering only the lines added since the previous poll. A line is held back until its newline arrives or
+// the job finishes. Following stops once the job is finished and its final log delivered.
+type JobLogStream struct {
+	Client ClientWithResponsesInterface
+	JobId  int64
+
+	PollInterval time.Duration
+
+	mu        sync.Mutex
+	delivered string
+	status    EnumJobStatus
+	finished  bool
+	lastErr   error
+}
+
+func NewJobLogStream(client ClientWithResponsesInterface, jobId int64) (*JobLogStream, error) {
+	if client == nil {
+		return nil, errors.New("client must not be nil")
+	}
+	return &JobLogStream{
+		Client:       client,
+		JobId:        jobId,
+		PollInterval: defaultJobLogPollInterval,
+	}, nil
+}
+
+// Poll fetches the log and returns the complete lines not returned before. When the server rewrote the log
+// rather than appending to it, every line of the new log is returned again. Once the job is finished the last
+// partial line is returned too and Finished reports true.
+func (s *JobLogStream) Poll(ctx context.Context) ([]string, error) {
+	fresh, _, err := s.poll(ctx)
+	lines := make([]string, len(fresh))
+	for i, line := range fresh {
+		lines[i] = line.text
+	}
+	return lines, err
+}
+
+// A log line and the length of the log delivered before it, which rewinding truncates the delivered log to
+type jobLogLine struct {
+	text   string
+	offset int
+}
+
+// Poll, keeping the position of each line so delivery can be rewound, and reporting whether the job finished
+func (s *JobLogStream) poll(ctx context.Context) ([]jobLogLine, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The status is read before the log so a finished job is known to have its final log
+	job, err := s.Client.GetClientJobWithResponse(ctx, s.JobId, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if job.StatusCode() != http.StatusOK || job.JSON200 == nil || job.JSON200.Data == nil {
+		return nil, false, newStatusError("GetClientJob", job.StatusCode(), job.Body)
+	}
+	if status := job.JSON200.Data.Status; status != nil {
+		s.status = *status
+	}
+	finished := s.status.Finished()
+
+	response, err := s.Client.GetClientJobLogWithResponse(ctx, s.JobId, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	var log string
+	switch {
+	case response.StatusCode() == http.StatusNotFound && !finished:
+		// No log was written yet
+	case response.StatusCode() != http.StatusOK || response.JSON200 == nil:
+		return nil, false, newStatusError("GetClientJobLog", response.StatusCode(), response.Body)
+	case response.JSON200.Data != nil && response.JSON200.Data.Log != nil:
+		log = *response.JSON200.Data.Log
+	}
+
+	if !strings.HasPrefix(log, s.delivered) {
+		s.delivered = ""
+	}
+	fresh := log[len(s.delivered):]
+	if !finished {
+		// Hold back the line still being written
+		fresh = fresh[:strings.LastIndex(fresh, "\n")+1]
+	}
+	offset := len(s.delivered)
+	s.delivered += fresh
+	s.finished = finished
+
+	var lines []jobLogLine
+	for fresh != "" {
+		line, rest, _ := strings.Cut(fresh, "\n")
+		lines = append(lines, jobLogLine{text: strings.TrimSuffix(line, "\r"), offset: offset})
+		offset += len(fresh) - len(rest)
+		fresh = rest
+	}
+	return lines, finished, nil
+}
+
+// Forget the undelivered lines so the next poll returns them again
+func (s *JobLogStream) rewind(undelivered []jobLogLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(undelivered) > 0 && undelivered[0].offset < len(s.delivered) {
+		s.delivered = s.delivered[:undelivered[0].offset]
+		s.finished = false
+	}
+}
+
+// Finished reports whether the last poll saw the job finished
+func (s *JobLogStream) Finished() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.finished
+}
+
+// Status returns the job status seen by the last poll
+func (s *JobLogStream) Status() EnumJobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Deliver log lines on a channel until the job finishes, the context is cancelled or a poll fails. The error
+// channel receives at most one error and both channels are closed when the stream stops.
+func (s *JobLogStream) Lines(ctx context.Context) (<-chan string, <-chan error) {
+	return followChannel(ctx, func(ctx context.Context, deliver func(string) bool) error {
+		return s.follow(ctx, deliver, false)
+	})
+}
+
+// Seq returns an iterator, compatible with iter.Seq, over the log lines. Iteration stops when the loop breaks,
+// the job finishes, the context is cancelled or a poll fails; check Err afterwards. The lines after the one the
+// loop breaks on are returned by the next poll.
+func (s *JobLogStream) Seq(ctx context.Context) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		_ = s.follow(ctx, yield, true)
+	}
+}
+
+// Err returns the error that stopped the last Lines or Seq, if any. Context cancellation is not an error.
+func (s *JobLogStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+// Follow the log, as an iterator when iterating so the line the loop breaks on is not returned again
+func (s *JobLogStream) follow(ctx context.Context, deliver func(string) bool, iterating bool) error {
+	s.setErr(nil)
+
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = defaultJobLogPollInterval
+	}
+	loop := pollLoop[jobLogLine]{
+		interval: interval,
+		poll:     s.poll,
+		rewind:   s.rewind,
+	}
+	run := loop.follow
+	if iterating {
+		run = loop.iterate
+	}
+	err := run(ctx, func(line jobLogLine) bool {
+		return deliver(line.text)
+	})
+	s.setErr(err)
+	return err
+}
+
+func (s *JobLogStream) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+}
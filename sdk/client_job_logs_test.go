@@ -0,0 +1,95 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func jobLogServer(t *testing.T, status EnumJobStatus, log string) *ClientWithResponses {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/jobs/7":
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"id": 7, "status": status}})
+		case "/api/v2/jobs/7/log":
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"log": log}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestJobLogStreamResumesAfterLineTheLoopBreaksOn(t *testing.T) {
+	stream, err := NewJobLogStream(jobLogServer(t, JobStatusRunning, "first\nsecond\nthird\npartial"), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.PollInterval = time.Hour
+
+	// Breaking out of a range over the iterator is the yield returning false once the loop body ran
+	var seen []string
+	stream.Seq(context.Background())(func(line string) bool {
+		seen = append(seen, line)
+		return line != "second"
+	})
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(seen, ",") != "first,second" {
+		t.Fatalf("iterated %v, want [first second]", seen)
+	}
+
+	again, err := stream.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(again, ",") != "third" {
+		t.Fatalf("next poll returned %v, want [third]", again)
+	}
+}
+
+func TestJobLogStreamDeliversPartialLineOnceFinished(t *testing.T) {
+	stream, err := NewJobLogStream(jobLogServer(t, JobStatusComplete, "first\nlast"), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, errs := stream.Lines(context.Background())
+	var got []string
+	for line := range lines {
+		got = append(got, line)
+	}
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "first,last" || !stream.Finished() {
+		t.Fatalf("got %v, finished %t; want [first last] and finished", got, stream.Finished())
+	}
+}
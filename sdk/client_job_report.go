@@ -0,0 +1,414 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+const collectionJobPageSize = 100
+
+// CollectionJobReportFormat selects how a collection job report is rendered
+type CollectionJobReportFormat string
+
+const (
+	// CollectionJobReportTable is plain text for terminals
+	CollectionJobReportTable CollectionJobReportFormat = "table"
+	CollectionJobReportHTML  CollectionJobReportFormat = "html"
+	CollectionJobReportJSON  CollectionJobReportFormat = "json"
+)
+
+// CollectionJobReportOptions selects the jobs of a collection job report
+type CollectionJobReportOptions struct {
+	// ClientIds to report on, every client when empty
+	ClientIds []openapi_types.UUID
+
+	// From and To bound the start time of the reported jobs. To defaults to now and From to 7 days before To.
+	From time.Time
+	To   time.Time
+}
+
+// CollectionJobSummary is one completed job of a collection job report
+type CollectionJobSummary struct {
+	Id            int64      `json:"id"`
+	ClientId      string     `json:"client_id,omitempty"`
+	ClientName    string     `json:"client_name,omitempty"`
+	Status        string     `json:"status"`
+	StatusMessage string     `json:"status_message,omitempty"`
+	StartTime     time.Time  `json:"start_time"`
+	EndTime       *time.Time `json:"end_time,omitempty"`
+
+	// Duration is zero when the job has no end time
+	Duration    time.Duration `json:"duration"`
+	Collections []string      `json:"collections"`
+
+	DomainsSucceeded int `json:"domains_succeeded"`
+	DomainsFailed    int `json:"domains_failed"`
+}
+
+// DomainCollectionSummary aggregates the collection results of one domain across the reported jobs
+type DomainCollectionSummary struct {
+	Domain    string `json:"domain"`
+	Attempts  int    `json:"attempts"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+
+	LastSuccess        *time.Time `json:"last_success,omitempty"`
+	LastFailure        *time.Time `json:"last_failure,omitempty"`
+	LastFailureMessage string     `json:"last_failure_message,omitempty"`
+}
+
+// CollectionJobReport summarises the completed collection jobs of a time range and renders to a table, HTML
+// or JSON
+type CollectionJobReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+
+	Jobs    []CollectionJobSummary    `json:"jobs"`
+	Domains []DomainCollectionSummary `json:"domains"`
+
+	// StatusCounts and CollectionCounts count the jobs per status and per enabled collection method
+	StatusCounts     map[string]int `json:"status_counts"`
+	CollectionCounts map[string]int `json:"collection_counts"`
+
+	TotalDuration   time.Duration `json:"total_duration"`
+	AverageDuration time.Duration `json:"average_duration"`
+}
+
+// BuildCollectionJobReport fetches the completed jobs of the clients that started inside the window and
+// summarises them per job and per domain
+func BuildCollectionJobReport(ctx context.Context, client ClientWithResponsesInterface, options CollectionJobReportOptions) (*CollectionJobReport, error) {
+	if options.To.IsZero() {
+		options.To = time.Now()
+	}
+	if options.From.IsZero() {
+		options.From = options.To.Add(-7 * 24 * time.Hour)
+	}
+	if !options.From.Before(options.To) {
+		return nil, fmt.Errorf("report window start %s is not before its end %s", options.From.Format(time.RFC3339), options.To.Format(time.RFC3339))
+	}
+
+	clientIds := options.ClientIds
+	if len(clientIds) == 0 {
+		var err error
+		if clientIds, err = listClientIds(ctx, client); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &CollectionJobReport{
+		GeneratedAt:      time.Now().UTC(),
+		From:             options.From.UTC(),
+		To:               options.To.UTC(),
+		StatusCounts:     map[string]int{},
+		CollectionCounts: map[string]int{},
+	}
+	domains := map[string]*DomainCollectionSummary{}
+	for _, clientId := range clientIds {
+		jobs, err := listCompletedJobs(ctx, client, clientId, report.From)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range jobs {
+			if job.StartTime == nil || job.StartTime.Before(report.From) || !job.StartTime.Before(report.To) {
+				continue
+			}
+			report.add(job, domains)
+		}
+	}
+
+	sort.SliceStable(report.Jobs, func(i, j int) bool {
+		return report.Jobs[i].StartTime.Before(report.Jobs[j].StartTime)
+	})
+	for _, name := range sortedKeys(domains) {
+		report.Domains = append(report.Domains, *domains[name])
+	}
+	var timed int
+	for _, job := range report.Jobs {
+		if job.EndTime != nil {
+			report.TotalDuration += job.Duration
+			timed++
+		}
+	}
+	if timed > 0 {
+		report.AverageDuration = report.TotalDuration / time.Duration(timed)
+	}
+	return report, nil
+}
+
+func (r *CollectionJobReport) add(job ModelClientScheduledJobDisplay, domains map[string]*DomainCollectionSummary) {
+	summary := CollectionJobSummary{
+		StartTime:   job.StartTime.UTC(),
+		Collections: collectionMethods(job),
+	}
+	if job.Id != nil {
+		summary.Id = *job.Id
+	}
+	if job.ClientId != nil {
+		summary.ClientId = job.ClientId.String()
+	}
+	if job.ClientName != nil {
+		summary.ClientName = *job.ClientName
+	}
+	if job.Status != nil {
+		summary.Status = job.Status.String()
+	}
+	if job.StatusMessage != nil {
+		summary.StatusMessage = *job.StatusMessage
+	}
+	if job.EndTime != nil && !job.EndTime.IsZero() {
+		summary.EndTime = ptr(job.EndTime.UTC())
+		summary.Duration = job.EndTime.Sub(*job.StartTime)
+	}
+
+	if job.DomainResults != nil {
+		for _, result := range *job.DomainResults {
+			if result.DomainName == nil || *result.DomainName == "" {
+				continue
+			}
+			name := strings.ToUpper(*result.DomainName)
+			domain, ok := domains[name]
+			if !ok {
+				domain = &DomainCollectionSummary{Domain: name}
+				domains[name] = domain
+			}
+			domain.Attempts++
+
+			// Results are dated by the end of the job, or its start when it has none
+			at := summary.StartTime
+			if summary.EndTime != nil {
+				at = *summary.EndTime
+			}
+			if result.Success != nil && *result.Success {
+				summary.DomainsSucceeded++
+				domain.Succeeded++
+				if domain.LastSuccess == nil || at.After(*domain.LastSuccess) {
+					domain.LastSuccess = ptr(at)
+				}
+			} else {
+				summary.DomainsFailed++
+				domain.Failed++
+				if domain.LastFailure == nil || at.After(*domain.LastFailure) {
+					domain.LastFailure = ptr(at)
+					domain.LastFailureMessage = ""
+					if result.Message != nil {
+						domain.LastFailureMessage = *result.Message
+					}
+				}
+			}
+		}
+	}
+
+	r.StatusCounts[summary.Status]++
+	for _, collection := range summary.Collections {
+		r.CollectionCounts[collection]++
+	}
+	r.Jobs = append(r.Jobs, summary)
+}
+
+// The collection methods enabled for a job, named as SharpHound names them
+func collectionMethods(job ModelClientScheduledJobDisplay) []string {
+	methods := []string{}
+	for _, method := range []struct {
+		name    string
+		enabled *bool
+	}{
+		{"Session", job.SessionCollection},
+		{"LocalGroup", job.LocalGroupCollection},
+		{"ADStructure", job.AdStructureCollection},
+		{"CertServices", job.CertServicesCollection},
+		{"CARegistry", job.CaRegistryCollection},
+		{"DCRegistry", job.DcRegistryCollection},
+	} {
+		if method.enabled != nil && *method.enabled {
+			methods = append(methods, method.name)
+		}
+	}
+	return methods
+}
+
+func listClientIds(ctx context.Context, client ClientWithResponsesInterface) ([]openapi_types.UUID, error) {
+	var ids []openapi_types.UUID
+	for skip := 0; ; skip += collectionJobPageSize {
+		response, err := client.ListClientsWithResponse(ctx, &ListClientsParams{Skip: ptr(skip), Limit: ptr(collectionJobPageSize)})
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+			return nil, newStatusError("ListClients", response.StatusCode(), response.Body)
+		}
+		if response.JSON200.Data == nil {
+			return ids, nil
+		}
+		for _, display := range *response.JSON200.Data {
+			if display.Id != nil {
+				ids = append(ids, *display.Id)
+			}
+		}
+		if len(*response.JSON200.Data) < collectionJobPageSize {
+			return ids, nil
+		}
+	}
+}
+
+// Completed jobs of a client, newest first, stopping at the first page that reaches back before from
+func listCompletedJobs(ctx context.Context, client ClientWithResponsesInterface, clientId openapi_types.UUID, from time.Time) ([]ModelClientScheduledJobDisplay, error) {
+	var jobs []ModelClientScheduledJobDisplay
+	for skip := 0; ; skip += collectionJobPageSize {
+		response, err := client.ListClientCompletedJobsWithResponse(ctx, clientId, &ListClientCompletedJobsParams{
+			HydrateDomains: ptr(true),
+			SortBy:         ptr("-start_time"),
+			Skip:           ptr(skip),
+			Limit:          ptr(collectionJobPageSize),
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The server answers 404 for a client that never completed a job
+		if response.StatusCode() == http.StatusNotFound {
+			return jobs, nil
+		}
+		if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+			return nil, newStatusError("ListClientCompletedJobs", response.StatusCode(), response.Body)
+		}
+		if response.JSON200.Data == nil {
+			return jobs, nil
+		}
+		page := *response.JSON200.Data
+		jobs = append(jobs, page...)
+		if len(page) < collectionJobPageSize {
+			return jobs, nil
+		}
+		if last := page[len(page)-1]; last.StartTime != nil && last.StartTime.Before(from) {
+			return jobs, nil
+		}
+	}
+}
+
+// Render the report in the given format
+func (r *CollectionJobReport) Render(w io.Writer, format CollectionJobReportFormat) error {
+	switch format {
+	case CollectionJobReportTable:
+		return r.RenderTable(w)
+	case CollectionJobReportHTML:
+		return r.RenderHTML(w)
+	case CollectionJobReportJSON:
+		return r.RenderJSON(w)
+	default:
+		return fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+func (r *CollectionJobReport) RenderJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func (r *CollectionJobReport) RenderTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Collection jobs from %s to %s: %d jobs, %s in total, %s on average\n",
+		r.From.Format(time.RFC3339), r.To.Format(time.RFC3339), len(r.Jobs), formatJobDuration(r.TotalDuration), formatJobDuration(r.AverageDuration))
+	fmt.Fprintf(tw, "Statuses: %s\n", formatCounts(r.StatusCounts))
+	fmt.Fprintf(tw, "Collections: %s\n", formatCounts(r.CollectionCounts))
+
+	fmt.Fprintf(tw, "\nID\tCLIENT\tSTATUS\tSTARTED\tDURATION\tDOMAINS OK\tDOMAINS FAILED\tCOLLECTIONS\n")
+	for _, job := range r.Jobs {
+		client := job.ClientName
+		if client == "" {
+			client = job.ClientId
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n", job.Id, client, job.Status, job.StartTime.Format(time.RFC3339),
+			formatJobDuration(job.Duration), job.DomainsSucceeded, job.DomainsFailed, strings.Join(job.Collections, ","))
+	}
+
+	fmt.Fprintf(tw, "\nDOMAIN\tATTEMPTS\tSUCCEEDED\tFAILED\tLAST SUCCESS\tLAST FAILURE\tMESSAGE\n")
+	for _, domain := range r.Domains {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\t%s\n", domain.Domain, domain.Attempts, domain.Succeeded, domain.Failed,
+			formatReportTime(domain.LastSuccess), formatReportTime(domain.LastFailure), strings.ReplaceAll(domain.LastFailureMessage, "\n", " "))
+	}
+	return tw.Flush()
+}
+
+var collectionJobReportHTMLTemplate = template.Must(template.New("jobs").Funcs(template.FuncMap{
+	"duration": formatJobDuration,
+	"counts":   formatCounts,
+	"time":     formatReportTime,
+	"rfc3339":  func(t time.Time) string { return t.Format(time.RFC3339) },
+	"join":     strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Collection job report</title></head>
+<body>
+<h1>Collection job report</h1>
+<p>Window: {{rfc3339 .From}} to {{rfc3339 .To}}, {{len .Jobs}} jobs, {{duration .TotalDuration}} in total, {{duration .AverageDuration}} on average</p>
+<p>Statuses: {{counts .StatusCounts}}</p>
+<p>Collections: {{counts .CollectionCounts}}</p>
+<h2>Domains</h2>
+<table>
+<tr><th>Domain</th><th>Attempts</th><th>Succeeded</th><th>Failed</th><th>Last success</th><th>Last failure</th><th>Message</th></tr>
+{{range .Domains}}<tr><td>{{.Domain}}</td><td>{{.Attempts}}</td><td>{{.Succeeded}}</td><td>{{.Failed}}</td><td>{{time .LastSuccess}}</td><td>{{time .LastFailure}}</td><td>{{.LastFailureMessage}}</td></tr>
+{{end}}</table>
+<h2>Jobs</h2>
+<table>
+<tr><th>ID</th><th>Client</th><th>Status</th><th>Started</th><th>Duration</th><th>Domains succeeded</th><th>Domains failed</th><th>Collections</th></tr>
+{{range .Jobs}}<tr><td>{{.Id}}</td><td>{{if .ClientName}}{{.ClientName}}{{else}}{{.ClientId}}{{end}}</td><td>{{.Status}}</td><td>{{rfc3339 .StartTime}}</td><td>{{duration .Duration}}</td><td>{{.DomainsSucceeded}}</td><td>{{.DomainsFailed}}</td><td>{{join .Collections ", "}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func (r *CollectionJobReport) RenderHTML(w io.Writer) error {
+	return collectionJobReportHTMLTemplate.Execute(w, r)
+}
+
+func formatJobDuration(duration time.Duration) string {
+	if duration <= 0 {
+		return "n/a"
+	}
+	return duration.Round(time.Second).String()
+}
+
+func formatReportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	var parts []string
+	for _, name := range sortedKeys(counts) {
+		parts = append(parts, fmt.Sprintf("%s %d", name, counts[name]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+func TestBuildCollectionJobReportKeepsJobsStartedInsideWindow(t *testing.T) {
+	clientId := openapi_types.UUID{1}
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	// Newest first, as the report asks the server to sort them
+	starts := []time.Time{to.Add(time.Hour), to, to.Add(-time.Hour), from, from.Add(-time.Hour)}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/clients/"+clientId.String()+"/completed-jobs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var jobs []string
+		for i, start := range starts {
+			jobs = append(jobs, fmt.Sprintf(`{"id":%d,"client_id":%q,"status":2,"start_time":%q,"end_time":%q}`,
+				i+1, clientId, start.Format(time.RFC3339), start.Add(10*time.Minute).Format(time.RFC3339)))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(jobs, ","))
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := BuildCollectionJobReport(context.Background(), client, CollectionJobReportOptions{
+		ClientIds: []openapi_types.UUID{clientId},
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, job := range report.Jobs {
+		ids = append(ids, job.Id)
+	}
+	if fmt.Sprint(ids) != "[4 3]" {
+		t.Fatalf("report holds jobs %v, want [4 3]: the window includes its start and excludes its end", ids)
+	}
+	if report.TotalDuration != 20*time.Minute {
+		t.Errorf("total duration is %v, want 20m0s", report.TotalDuration)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ErrJobCanceled is the error of a job result when the server canceled the job while it was collecting
+var ErrJobCanceled = errors.New("job canceled by the server")
+
+// CollectFunc performs the collection a job asks for and returns the output files to upload, .zip or .json. The
+// context is canceled when the server cancels the job.
+type CollectFunc func(ctx context.Context, job ModelClientScheduledJob) ([]string, error)
+
+// ClientJobResult describes one job a JobRunner ran
+type ClientJobResult struct {
+	Job   ModelClientScheduledJob
+	Files []string
+
+	// FileUploadJobId is the ingest job the files were uploaded as, zero when nothing was uploaded
+	FileUploadJobId int64
+
+	StartedAt time.Time
+	EndedAt   time.Time
+
+	// Err is why the job failed, ErrJobCanceled when the server canceled it
+	Err error
+}
+
+// JobRunner is the job loop of a collector agent authenticated as a client: it reports heartbeats, claims the
+// jobs scheduled for the client, runs Collect, uploads its output and ends the job. Jobs run one at a time.
+type JobRunner struct {
+	Client  ClientWithResponsesInterface
+	Collect CollectFunc
+
+	// Info is sent with every heartbeat
+	Info UpdateClientInfoJSONRequestBody
+
+	// PollInterval is how often available jobs, and the cancellation of the running job, are checked. The
+	// default is 30 seconds.
+	PollInterval time.Duration
+
+	// HeartbeatInterval is how often UpdateClientInfo is called. The default is one minute.
+	HeartbeatInterval time.Duration
+
+	// Upload ingests the output of a job. The default uploads the files as one file upload job.
+	Upload func(ctx context.Context, job ModelClientScheduledJob, files []string) (int64, error)
+
+	// OnResult and OnError are called from Run for each finished job and for errors outside of a job
+	OnResult func(ClientJobResult)
+	OnError  func(error)
+}
+
+func NewJobRunner(client ClientWithResponsesInterface, collect CollectFunc) (*JobRunner, error) {
+	if client == nil {
+		return nil, errors.New("client must not be nil")
+	}
+	if collect == nil {
+		return nil, errors.New("collect function must not be nil")
+	}
+	return &JobRunner{
+		Client:            client,
+		Collect:           collect,
+		PollInterval:      30 * time.Second,
+		HeartbeatInterval: time.Minute,
+	}, nil
+}
+
+// Run claims and runs jobs until the context is done. Failures are reported to OnError and OnResult and do
+// not stop the loop, so a server outage only delays collection.
+func (r *JobRunner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.pollInterval())
+	defer ticker.Stop()
+
+	lastHeartbeat := time.Time{}
+	for {
+		if time.Since(lastHeartbeat) >= r.heartbeatInterval() {
+			if err := r.Heartbeat(ctx); err != nil {
+				r.reportError(err)
+			} else {
+				lastHeartbeat = time.Now()
+			}
+		}
+
+		result, err := r.RunOnce(ctx)
+		if result != nil && r.OnResult != nil {
+			r.OnResult(*result)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			r.reportError(err)
+		}
+		if result != nil {
+			lastHeartbeat = time.Now()
+			// Look for the next job straight away, schedules often queue several
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce claims the next available job and runs it to the end. It returns a nil result when no job is
+// waiting, and an error only when the job could not be claimed.
+func (r *JobRunner) RunOnce(ctx context.Context) (*ClientJobResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	next, err := r.nextJob(ctx)
+	if err != nil || next == nil {
+		return nil, err
+	}
+
+	started, err := r.Client.StartClientJobWithResponse(ctx, nil, StartClientJobJSONRequestBody{Id: next.Id})
+	if err != nil {
+		return nil, err
+	}
+	if started.StatusCode() != http.StatusOK || started.JSON200 == nil || started.JSON200.Data == nil {
+		return nil, newStatusError("StartClientJob", started.StatusCode(), started.Body)
+	}
+
+	result := &ClientJobResult{Job: *started.JSON200.Data, StartedAt: time.Now()}
+	r.runJob(ctx, result)
+	result.EndedAt = time.Now()
+	return result, nil
+}
+
+// Heartbeat reports the client as alive with its Info
+func (r *JobRunner) Heartbeat(ctx context.Context) error {
+	response, err := r.Client.UpdateClientInfoWithResponse(ctx, nil, r.Info)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != http.StatusOK {
+		return newStatusError("UpdateClientInfo", response.StatusCode(), response.Body)
+	}
+	return nil
+}
+
+// The ready job with the earliest execution time
+func (r *JobRunner) nextJob(ctx context.Context) (*ModelClientScheduledJobDisplay, error) {
+	response, err := r.Client.ListAvailableClientJobsWithResponse(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The server answers 404 when the client has nothing scheduled
+	if response.StatusCode() == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, newStatusError("ListAvailableClientJobs", response.StatusCode(), response.Body)
+	}
+	if response.JSON200.Data == nil {
+		return nil, nil
+	}
+
+	var ready []ModelClientScheduledJobDisplay
+	for _, job := range *response.JSON200.Data {
+		if job.Id != nil && (job.Status == nil || *job.Status == JobStatusReady) {
+			ready = append(ready, job)
+		}
+	}
+	if len(ready) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(ready, func(i, j int) bool {
+		return jobExecutionTime(ready[i]).Before(jobExecutionTime(ready[j]))
+	})
+	return &ready[0], nil
+}
+
+func jobExecutionTime(job ModelClientScheduledJobDisplay) time.Time {
+	if job.ExecutionTime == nil {
+		return time.Time{}
+	}
+	return *job.ExecutionTime
+}
+
+// Run the collect function while heartbeating and watching for cancellation, then upload and end the job
+func (r *JobRunner) runJob(ctx context.Context, result *ClientJobResult) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type collected struct {
+		files []string
+		err   error
+	}
+	done := make(chan collected, 1)
+	go func() {
+		files, err := r.Collect(jobCtx, result.Job)
+		done <- collected{files, err}
+	}()
+
+	poll := time.NewTicker(r.pollInterval())
+	defer poll.Stop()
+	heartbeat := time.NewTicker(r.heartbeatInterval())
+	defer heartbeat.Stop()
+
+	canceled := false
+	var outcome collected
+wait:
+	for {
+		select {
+		case outcome = <-done:
+			break wait
+		case <-heartbeat.C:
+			if err := r.Heartbeat(ctx); err != nil {
+				r.reportError(err)
+			}
+		case <-poll.C:
+			if r.canceledByServer(ctx, result.Job) {
+				canceled = true
+				cancel()
+			}
+		}
+	}
+	result.Files = outcome.files
+
+	switch {
+	case canceled:
+		// The server already closed the job
+		result.Err = ErrJobCanceled
+		return
+	case ctx.Err() != nil:
+		result.Err = ctx.Err()
+	case outcome.err != nil:
+		result.Err = fmt.Errorf("collection failed: %w", outcome.err)
+	case len(outcome.files) > 0:
+		upload := r.Upload
+		if upload == nil {
+			upload = func(ctx context.Context, job ModelClientScheduledJob, files []string) (int64, error) {
+				return uploadFiles(ctx, r.Client, files, nil)
+			}
+		}
+		result.FileUploadJobId, result.Err = upload(ctx, result.Job, outcome.files)
+	}
+
+	// End the job whatever happened so it does not stay running on the server. The parent context may be done,
+	// so ending gets a short context of its own.
+	endCtx, endCancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer endCancel()
+	if err := r.endJob(endCtx); err != nil {
+		result.Err = errors.Join(result.Err, err)
+	}
+}
+
+// Whether the server canceled the job, or no longer considers it the client's current job
+func (r *JobRunner) canceledByServer(ctx context.Context, job ModelClientScheduledJob) bool {
+	response, err := r.Client.GetClientCurrentJobWithResponse(ctx, nil)
+	if err != nil {
+		r.reportError(err)
+		return false
+	}
+	if response.StatusCode() == http.StatusNotFound {
+		return true
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil {
+		r.reportError(newStatusError("GetClientCurrentJob", response.StatusCode(), response.Body))
+		return false
+	}
+	current := response.JSON200.Data
+	if current.Id != nil && job.Id != nil && *current.Id != *job.Id {
+		return true
+	}
+	return current.Status != nil && *current.Status == JobStatusCanceled
+}
+
+func (r *JobRunner) endJob(ctx context.Context) error {
+	response, err := r.Client.EndClientJobWithResponse(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != http.StatusOK {
+		return newStatusError("EndClientJob", response.StatusCode(), response.Body)
+	}
+	return nil
+}
+
+func (r *JobRunner) reportError(err error) {
+	if r.OnError != nil {
+		r.OnError(err)
+	}
+}
+
+func (r *JobRunner) pollInterval() time.Duration {
+	if r.PollInterval <= 0 {
+		return 30 * time.Second
+	}
+	return r.PollInterval
+}
+
+func (r *JobRunner) heartbeatInterval() time.Duration {
+	if r.HeartbeatInterval <= 0 {
+		return time.Minute
+	}
+	return r.HeartbeatInterval
+}
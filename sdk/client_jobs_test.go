@@ -0,0 +1,79 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJobRunnerStopsJobCanceledByServer(t *testing.T) {
+	for name, current := range map[string]func(w http.ResponseWriter){
+		"canceled": func(w http.ResponseWriter) { w.Write([]byte(`{"data":{"id":7,"status":3}}`)) },
+		"replaced": func(w http.ResponseWriter) { w.Write([]byte(`{"data":{"id":8,"status":1}}`)) },
+		"gone": func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"http_status":404,"errors":[{"message":"no current job"}]}`))
+		},
+	} {
+		var ended atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/api/v2/jobs/available":
+				w.Write([]byte(`{"data":[{"id":7,"status":0}]}`))
+			case "/api/v2/jobs/start":
+				w.Write([]byte(`{"data":{"id":7,"status":1}}`))
+			case "/api/v2/jobs/current":
+				current(w)
+			case "/api/v2/jobs/end":
+				ended.Add(1)
+			}
+		}))
+		client, err := NewClientWithResponses(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner, err := NewJobRunner(client, func(ctx context.Context, job ModelClientScheduledJob) ([]string, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner.PollInterval = 10 * time.Millisecond
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		result, err := runner.RunOnce(ctx)
+		cancel()
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !errors.Is(result.Err, ErrJobCanceled) {
+			t.Errorf("%s: job ended with %v, want ErrJobCanceled", name, result.Err)
+		}
+		if ended.Load() != 0 {
+			t.Errorf("%s: a job the server already closed was ended", name)
+		}
+	}
+}
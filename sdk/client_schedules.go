@@ -0,0 +1,313 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// DesiredSchedule declares a collection schedule a client should have
+type DesiredSchedule struct {
+	// Recurrence is an RRULE or a friendly spec, see ParseRecurrence
+	Recurrence string `json:"recurrence"`
+
+	SessionCollection      bool `json:"session_collection,omitempty"`
+	LocalGroupCollection   bool `json:"local_group_collection,omitempty"`
+	AdStructureCollection  bool `json:"ad_structure_collection,omitempty"`
+	CertServicesCollection bool `json:"cert_services_collection,omitempty"`
+	CaRegistryCollection   bool `json:"ca_registry_collection,omitempty"`
+	DcRegistryCollection   bool `json:"dc_registry_collection,omitempty"`
+	AllTrustedDomains      bool `json:"all_trusted_domains,omitempty"`
+
+	// Domains and Ous are object ids
+	Domains []string `json:"domains,omitempty"`
+	Ous     []string `json:"ous,omitempty"`
+}
+
+// Schedule builds the request body for the client, with the recurrence formatted as an RRULE
+func (d DesiredSchedule) Schedule(clientId openapi_types.UUID) (ModelClientSchedule, error) {
+	recurrence, err := ParseRecurrence(d.Recurrence)
+	if err != nil {
+		return ModelClientSchedule{}, err
+	}
+	return ModelClientSchedule{
+		ClientId:               ptr(clientId),
+		Rrule:                  ptr(recurrence.String()),
+		SessionCollection:      ptr(d.SessionCollection),
+		LocalGroupCollection:   ptr(d.LocalGroupCollection),
+		AdStructureCollection:  ptr(d.AdStructureCollection),
+		CertServicesCollection: ptr(d.CertServicesCollection),
+		CaRegistryCollection:   ptr(d.CaRegistryCollection),
+		DcRegistryCollection:   ptr(d.DcRegistryCollection),
+		AllTrustedDomains:      ptr(d.AllTrustedDomains),
+		Domains:                ptr(append([]string{}, d.Domains...)),
+		Ous:                    ptr(append([]string{}, d.Ous...)),
+	}, nil
+}
+
+// ScheduleUpdate replaces the settings of an existing schedule
+type ScheduleUpdate struct {
+	Id      int32
+	Current ModelClientScheduleDisplay
+	Desired ModelClientSchedule
+}
+
+// SchedulePlan lists the changes that bring the schedules of a client to the declared set
+type SchedulePlan struct {
+	ClientId  openapi_types.UUID
+	Create    []ModelClientSchedule
+	Update    []ScheduleUpdate
+	Delete    []ModelClientScheduleDisplay
+	Unchanged []ModelClientScheduleDisplay
+}
+
+// Empty reports whether the schedules already match
+func (p *SchedulePlan) Empty() bool {
+	return len(p.Create) == 0 && len(p.Update) == 0 && len(p.Delete) == 0
+}
+
+// ClientSchedules lists the collection schedules of a client, ordered by id
+func (c *ClientWithResponses) ClientSchedules(ctx context.Context, clientId openapi_types.UUID) ([]ModelClientScheduleDisplay, error) {
+	const pageSize = 100
+	var schedules []ModelClientScheduleDisplay
+	for skip := 0; ; skip += pageSize {
+		response, err := c.ListClientSchedulesWithResponse(ctx, &ListClientSchedulesParams{
+			ClientId: ptr(clientId),
+			Skip:     ptr(skip),
+			Limit:    ptr(pageSize),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+			return nil, newStatusError("ListClientSchedules", response.StatusCode(), response.Body)
+		}
+		if response.JSON200.Data == nil {
+			break
+		}
+		for _, schedule := range *response.JSON200.Data {
+			// The filter is applied again in case the server ignores it
+			if schedule.ClientId == nil || *schedule.ClientId == clientId {
+				schedules = append(schedules, schedule)
+			}
+		}
+		if len(*response.JSON200.Data) < pageSize {
+			break
+		}
+	}
+	sort.SliceStable(schedules, func(i, j int) bool {
+		return scheduleId(schedules[i]) < scheduleId(schedules[j])
+	})
+	return schedules, nil
+}
+
+// PlanClientSchedules compares the schedules of a client with the declared set. Existing schedules with the
+// same recurrence are kept or updated in place, left over ones are reused for the remaining declarations
+// before anything is created or deleted.
+func (c *ClientWithResponses) PlanClientSchedules(ctx context.Context, clientId openapi_types.UUID, desired []DesiredSchedule) (*SchedulePlan, error) {
+	wanted := make([]ModelClientSchedule, len(desired))
+	for i, declared := range desired {
+		schedule, err := declared.Schedule(clientId)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %d: %w", i+1, err)
+		}
+		wanted[i] = schedule
+	}
+
+	existing, err := c.ClientSchedules(ctx, clientId)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &SchedulePlan{ClientId: clientId}
+	matched := make([]bool, len(existing))
+	pending := make([]bool, len(wanted))
+
+	// Unchanged first, so an identical schedule is never rewritten to make room for another declaration
+	for i, schedule := range wanted {
+		pending[i] = true
+		for j, current := range existing {
+			if !matched[j] && sameRecurrence(current.Rrule, schedule.Rrule) && sameScheduleSettings(current, schedule) {
+				matched[j], pending[i] = true, false
+				plan.Unchanged = append(plan.Unchanged, current)
+				break
+			}
+		}
+	}
+	for _, sameRule := range []bool{true, false} {
+		for i, schedule := range wanted {
+			if !pending[i] {
+				continue
+			}
+			for j, current := range existing {
+				if !matched[j] && (!sameRule || sameRecurrence(current.Rrule, schedule.Rrule)) {
+					matched[j], pending[i] = true, false
+					plan.Update = append(plan.Update, ScheduleUpdate{Id: scheduleId(current), Current: current, Desired: schedule})
+					break
+				}
+			}
+		}
+	}
+	for i, schedule := range wanted {
+		if pending[i] {
+			plan.Create = append(plan.Create, schedule)
+		}
+	}
+	for j, current := range existing {
+		if !matched[j] {
+			plan.Delete = append(plan.Delete, current)
+		}
+	}
+	return plan, nil
+}
+
+// ApplySchedulePlan makes the changes of a plan. Every change is attempted, the errors of those that failed
+// are joined.
+func (c *ClientWithResponses) ApplySchedulePlan(ctx context.Context, plan *SchedulePlan) error {
+	var errs []error
+	for _, update := range plan.Update {
+		response, err := c.UpdateClientEventWithResponse(ctx, update.Id, nil, update.Desired)
+		if err == nil && response.StatusCode() != http.StatusOK {
+			err = newStatusError("UpdateClientEvent", response.StatusCode(), response.Body)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error updating schedule %d: %w", update.Id, err))
+		}
+	}
+	for _, schedule := range plan.Create {
+		response, err := c.CreateClientScheduleWithResponse(ctx, nil, schedule)
+		if err == nil && response.StatusCode() != http.StatusOK && response.StatusCode() != http.StatusCreated {
+			err = newStatusError("CreateClientSchedule", response.StatusCode(), response.Body)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error creating schedule %s: %w", strings.ReplaceAll(*schedule.Rrule, "\n", " "), err))
+		}
+	}
+	for _, schedule := range plan.Delete {
+		id := scheduleId(schedule)
+		response, err := c.DeleteClientEventWithResponse(ctx, id, nil)
+		if err == nil && response.StatusCode() != http.StatusOK && response.StatusCode() != http.StatusNoContent {
+			err = newStatusError("DeleteClientEvent", response.StatusCode(), response.Body)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error deleting schedule %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReconcileClientSchedules plans and applies the changes that bring the schedules of a client to the declared
+// set, returning the plan that was applied
+func (c *ClientWithResponses) ReconcileClientSchedules(ctx context.Context, clientId openapi_types.UUID, desired []DesiredSchedule) (*SchedulePlan, error) {
+	plan, err := c.PlanClientSchedules(ctx, clientId, desired)
+	if err != nil {
+		return nil, err
+	}
+	return plan, c.ApplySchedulePlan(ctx, plan)
+}
+
+func scheduleId(schedule ModelClientScheduleDisplay) int32 {
+	if schedule.Id == nil {
+		return 0
+	}
+	return *schedule.Id
+}
+
+// Rules are compared in their canonical form. When either rule has no DTSTART, such as one stored by a server
+// that drops it, both are compared on their RRULE only. Rules that do not parse are compared on their text.
+func sameRecurrence(current, desired *string) bool {
+	if current == nil || desired == nil {
+		return current == desired
+	}
+	withStart := hasDTSTART(*current) && hasDTSTART(*desired)
+	return canonicalRRule(*current, withStart) == canonicalRRule(*desired, withStart)
+}
+
+func hasDTSTART(rule string) bool {
+	return strings.Contains(strings.ToUpper(rule), "DTSTART")
+}
+
+func canonicalRRule(rule string, withStart bool) string {
+	recurrence, err := ParseRRule(rule)
+	if err != nil {
+		return strings.TrimSpace(rule)
+	}
+	canonical := recurrence.String()
+	if !withStart {
+		_, canonical, _ = strings.Cut(canonical, "\n")
+	}
+	return canonical
+}
+
+func sameScheduleSettings(current ModelClientScheduleDisplay, desired ModelClientSchedule) bool {
+	flag := func(value *bool) bool { return value != nil && *value }
+	if flag(current.SessionCollection) != flag(desired.SessionCollection) ||
+		flag(current.LocalGroupCollection) != flag(desired.LocalGroupCollection) ||
+		flag(current.AdStructureCollection) != flag(desired.AdStructureCollection) ||
+		flag(current.CertServicesCollection) != flag(desired.CertServicesCollection) ||
+		flag(current.CaRegistryCollection) != flag(desired.CaRegistryCollection) ||
+		flag(current.DcRegistryCollection) != flag(desired.DcRegistryCollection) ||
+		flag(current.AllTrustedDomains) != flag(desired.AllTrustedDomains) {
+		return false
+	}
+
+	var domains, ous []string
+	if current.Domains != nil {
+		for _, domain := range *current.Domains {
+			if domain.Objectid != nil {
+				domains = append(domains, *domain.Objectid)
+			}
+		}
+	}
+	if current.Ous != nil {
+		for _, ou := range *current.Ous {
+			if ou.Objectid != nil {
+				ous = append(ous, *ou.Objectid)
+			}
+		}
+	}
+	return sameStringSet(domains, desired.Domains) && sameStringSet(ous, desired.Ous)
+}
+
+func sameStringSet(current []string, desired *[]string) bool {
+	set := map[string]bool{}
+	for _, item := range current {
+		set[strings.ToUpper(item)] = true
+	}
+	wanted := map[string]bool{}
+	if desired != nil {
+		for _, item := range *desired {
+			wanted[strings.ToUpper(item)] = true
+		}
+	}
+	if len(set) != len(wanted) {
+		return false
+	}
+	for item := range wanted {
+		if !set[item] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+func TestPlanClientSchedulesKeepsRuleStoredWithoutDTSTART(t *testing.T) {
+	clientId := openapi_types.UUID{1}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":[{"id":7,"client_id":%q,"rrule":"RRULE:FREQ=DAILY;INTERVAL=1","session_collection":true}]}`, clientId)
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := client.PlanClientSchedules(context.Background(), clientId, []DesiredSchedule{{
+		Recurrence:        "DTSTART:20240501T020000Z\nRRULE:FREQ=DAILY",
+		SessionCollection: true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() || len(plan.Unchanged) != 1 {
+		t.Fatalf("plan creates %d, updates %d and deletes %d schedules, want the stored schedule unchanged", len(plan.Create), len(plan.Update), len(plan.Delete))
+	}
+}
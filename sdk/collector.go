@@ -0,0 +1,314 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LatestCollectorVersion resolves to the version the collector manifest marks as latest
+const LatestCollectorVersion = "latest"
+
+var (
+	ErrCollectorVersionNotFound = errors.New("collector version not found in manifest")
+	ErrCollectorDeprecated      = errors.New("collector version is deprecated")
+	ErrCollectorChecksum        = errors.New("collector checksum mismatch")
+)
+
+// CollectorArtifact is a verified collector download
+type CollectorArtifact struct {
+	Type    EnumClientType
+	Version string
+	Sha256  string
+
+	// Path of the downloaded archive
+	Path string
+
+	// Cached is set when the archive was already in the cache and no download took place
+	Cached bool
+
+	// ExtractedDir and Files are set when extraction was requested; Files are relative to ExtractedDir
+	ExtractedDir string
+	Files        []string
+}
+
+// CollectorOption configures FetchCollector
+type CollectorOption func(*collectorOptions)
+
+type collectorOptions struct {
+	cacheDir        string
+	allowDeprecated bool
+	extractDir      string
+}
+
+// WithCollectorCacheDir sets where verified archives are kept. The default is a bloodhound/collectors directory
+// under the user cache directory.
+func WithCollectorCacheDir(dir string) CollectorOption {
+	return func(o *collectorOptions) {
+		o.cacheDir = dir
+	}
+}
+
+// AllowDeprecatedCollector lets FetchCollector download versions the manifest marks as deprecated
+func AllowDeprecatedCollector() CollectorOption {
+	return func(o *collectorOptions) {
+		o.allowDeprecated = true
+	}
+}
+
+// ExtractCollectorTo extracts the verified archive into dir
+func ExtractCollectorTo(dir string) CollectorOption {
+	return func(o *collectorOptions) {
+		o.extractDir = dir
+	}
+}
+
+// CollectorManifest fetches the manifest listing the available versions of a collector
+func (c *ClientWithResponses) CollectorManifest(ctx context.Context, collectorType EnumClientType) (*ModelCollectorManifest, error) {
+	response, err := c.GetCollectorManifestWithResponse(ctx, collectorType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil {
+		return nil, newStatusError("GetCollectorManifest", response.StatusCode(), response.Body)
+	}
+	return response.JSON200.Data, nil
+}
+
+// FetchCollector downloads a collector version, "latest" or a version listed in the manifest, and verifies its
+// SHA-256 against both the manifest and the checksum endpoint before it is kept. Verified archives are cached, a
+// cached archive is hashed again against the manifest before it is reused.
+func (c *ClientWithResponses) FetchCollector(ctx context.Context, collectorType EnumClientType, version string, options ...CollectorOption) (*CollectorArtifact, error) {
+	var config collectorOptions
+	for _, option := range options {
+		option(&config)
+	}
+	if config.cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		config.cacheDir = filepath.Join(userCacheDir, "bloodhound", "collectors")
+	}
+
+	manifest, err := c.CollectorManifest(ctx, collectorType)
+	if err != nil {
+		return nil, err
+	}
+	release, err := manifestVersion(manifest, version)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", collectorType, version, err)
+	}
+	if release.Deprecated != nil && *release.Deprecated && !config.allowDeprecated {
+		return nil, fmt.Errorf("%s %s: %w", collectorType, *release.Version, ErrCollectorDeprecated)
+	}
+	if release.Sha256sum == nil || *release.Sha256sum == "" {
+		return nil, fmt.Errorf("%s %s: manifest has no checksum", collectorType, *release.Version)
+	}
+
+	artifact := &CollectorArtifact{
+		Type:    collectorType,
+		Version: *release.Version,
+		Sha256:  strings.ToLower(*release.Sha256sum),
+	}
+	dir := filepath.Join(config.cacheDir, string(collectorType), artifact.Version)
+	artifact.Path = filepath.Join(dir, fmt.Sprintf("%s-%s.zip", collectorType, artifact.Version))
+
+	if sum, err := fileSha256(artifact.Path); err == nil && sum == artifact.Sha256 {
+		artifact.Cached = true
+	} else {
+		if err := c.downloadCollector(ctx, artifact); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.extractDir != "" {
+		files, err := extractZip(artifact.Path, config.extractDir)
+		if err != nil {
+			return nil, fmt.Errorf("error extracting %s: %w", artifact.Path, err)
+		}
+		artifact.ExtractedDir = config.extractDir
+		artifact.Files = files
+	}
+	return artifact, nil
+}
+
+// Find the manifest entry of a version, tolerating a "v" prefix on either side
+func manifestVersion(manifest *ModelCollectorManifest, version string) (ModelCollectorVersion, error) {
+	if version == "" || version == LatestCollectorVersion {
+		if manifest.Latest == nil || *manifest.Latest == "" {
+			return ModelCollectorVersion{}, errors.New("manifest names no latest version")
+		}
+		version = *manifest.Latest
+	}
+	if manifest.Versions != nil {
+		for _, release := range *manifest.Versions {
+			if release.Version != nil && strings.TrimPrefix(*release.Version, "v") == strings.TrimPrefix(version, "v") {
+				return release, nil
+			}
+		}
+	}
+	return ModelCollectorVersion{}, ErrCollectorVersionNotFound
+}
+
+// Stream the archive to a temporary file while hashing it and only move it into place once both checksums match
+func (c *ClientWithResponses) downloadCollector(ctx context.Context, artifact *CollectorArtifact) error {
+	published, err := c.collectorChecksum(ctx, artifact.Type, artifact.Version)
+	if err != nil {
+		return err
+	}
+	if published != artifact.Sha256 {
+		return fmt.Errorf("%s %s: %w: the manifest lists %s, the checksum endpoint %s", artifact.Type, artifact.Version, ErrCollectorChecksum, artifact.Sha256, published)
+	}
+
+	response, err := c.DownloadCollector(ctx, artifact.Type, artifact.Version, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+		return newStatusError("DownloadCollector", response.StatusCode, body)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(artifact.Path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(artifact.Path), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), response.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error downloading %s %s: %w", artifact.Type, artifact.Version, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != artifact.Sha256 {
+		return fmt.Errorf("%s %s: %w: expected %s, downloaded %s", artifact.Type, artifact.Version, ErrCollectorChecksum, artifact.Sha256, sum)
+	}
+	return os.Rename(tmp.Name(), artifact.Path)
+}
+
+// Fetch the published checksum, a sha256sum style line whose first field is the hex digest
+func (c *ClientWithResponses) collectorChecksum(ctx context.Context, collectorType EnumClientType, version string) (string, error) {
+	response, err := c.GetCollectorChecksumWithResponse(ctx, collectorType, version, nil)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode() != http.StatusOK {
+		return "", newStatusError("GetCollectorChecksum", response.StatusCode(), response.Body)
+	}
+	fields := strings.Fields(string(response.Body))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%s %s: empty checksum file", collectorType, version)
+	}
+	sum := strings.ToLower(fields[0])
+	if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("%s %s: checksum file does not hold a SHA-256 digest", collectorType, version)
+	}
+	return sum, nil
+}
+
+func fileSha256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Extract a zip archive into dir, refusing entries that would land outside of it
+func extractZip(archive, dir string) ([]string, error) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range reader.File {
+		target := filepath.Join(root, filepath.FromSlash(entry.Name))
+		if target != root && !strings.HasPrefix(target, root+string(filepath.Separator)) {
+			return nil, fmt.Errorf("archive entry %q escapes the extraction directory", entry.Name)
+		}
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !entry.Mode().IsRegular() {
+			return nil, fmt.Errorf("archive entry %q is not a regular file", entry.Name)
+		}
+		if err := extractZipEntry(entry, target); err != nil {
+			return nil, err
+		}
+		files = append(files, filepath.ToSlash(entry.Name))
+	}
+	return files, nil
+}
+
+func extractZipEntry(entry *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	source, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	// Keep the executable bit collectors ship with
+	mode := os.FileMode(0o644)
+	if entry.Mode()&0o111 != 0 {
+		mode = 0o755
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, source); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// collectorServer serves archive as sharphound v2.0.0, listing sum in both the manifest and the checksum file.
+// It counts the archive downloads.
+func collectorServer(t *testing.T, archive []byte, sum string) (*ClientWithResponses, *int) {
+	t.Helper()
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/collectors/sharphound":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"data":{"latest":"v2.0.0","versions":[{"version":"v2.0.0","sha256sum":%q}]}}`, sum)
+		case "/api/v2/collectors/sharphound/v2.0.0/checksum":
+			fmt.Fprintf(w, "%s  sharphound-v2.0.0.zip\n", sum)
+		case "/api/v2/collectors/sharphound/v2.0.0":
+			downloads++
+			w.Write(archive)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &downloads
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, name := range sortedKeys(files) {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write([]byte(files[name]))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFetchCollectorRejectsChecksumMismatch(t *testing.T) {
+	archive := zipArchive(t, map[string]string{"SharpHound.exe": "collector"})
+	client, _ := collectorServer(t, archive, sha256Hex([]byte("another archive")))
+	cacheDir := t.TempDir()
+
+	_, err := client.FetchCollector(context.Background(), Sharphound, LatestCollectorVersion, WithCollectorCacheDir(cacheDir))
+	if !errors.Is(err, ErrCollectorChecksum) {
+		t.Fatalf("FetchCollector returned %v, want ErrCollectorChecksum", err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "sharphound", "v2.0.0", "sharphound-v2.0.0.zip")); !os.IsNotExist(err) {
+		t.Errorf("an archive that failed verification was kept in the cache (%v)", err)
+	}
+}
+
+func TestFetchCollectorReusesCachedArchive(t *testing.T) {
+	archive := zipArchive(t, map[string]string{"SharpHound.exe": "collector"})
+	client, downloads := collectorServer(t, archive, sha256Hex(archive))
+	cacheDir := t.TempDir()
+
+	first, err := client.FetchCollector(context.Background(), Sharphound, "2.0.0", WithCollectorCacheDir(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.FetchCollector(context.Background(), Sharphound, "2.0.0", WithCollectorCacheDir(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Cached || !second.Cached || *downloads != 1 {
+		t.Fatalf("cached %v then %v with %d downloads, want one download reused from the cache", first.Cached, second.Cached, *downloads)
+	}
+
+	// A cached archive that no longer matches the manifest is downloaded again
+	if err := os.WriteFile(second.Path, []byte("tampered"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	third, err := client.FetchCollector(context.Background(), Sharphound, "2.0.0", WithCollectorCacheDir(cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Cached || *downloads != 2 {
+		t.Fatalf("a tampered cache entry was reused")
+	}
+}
+
+func TestFetchCollectorRefusesEntriesOutsideExtractionDir(t *testing.T) {
+	archive := zipArchive(t, map[string]string{"../escaped.txt": "outside", "SharpHound.exe": "collector"})
+	client, _ := collectorServer(t, archive, sha256Hex(archive))
+	root := t.TempDir()
+	extractDir := filepath.Join(root, "extract")
+
+	_, err := client.FetchCollector(context.Background(), Sharphound, "2.0.0", WithCollectorCacheDir(t.TempDir()), ExtractCollectorTo(extractDir))
+	if err == nil || !strings.Contains(err.Error(), "escapes the extraction directory") {
+		t.Fatalf("FetchCollector returned %v, want the escaping entry refused", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escaped.txt")); !os.IsNotExist(err) {
+		t.Errorf("the escaping entry was written (%v)", err)
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrWipeNotConfirmed is returned by WipeDatabase when the confirmation token does not match the current preview,
+// either because it is wrong or because the data changed since the preview was shown
+var ErrWipeNotConfirmed = errors.New("database wipe not confirmed")
+
+// DatabaseWipe selects what DeleteBloodHoundDatabase deletes
+type DatabaseWipe struct {
+	DeleteCollectedGraphData bool `json:"delete_collected_graph_data"`
+	DeleteFileIngestHistory  bool `json:"delete_file_ingest_history"`
+	DeleteDataQualityHistory bool `json:"delete_data_quality_history"`
+
+	// DeleteAssetGroupSelectors are the ids of the asset groups whose custom selectors are deleted
+	DeleteAssetGroupSelectors []int `json:"delete_asset_group_selectors,omitempty"`
+}
+
+func (w DatabaseWipe) body() DeleteBloodHoundDatabaseJSONRequestBody {
+	body := DeleteBloodHoundDatabaseJSONRequestBody{
+		DeleteCollectedGraphData: ptr(w.DeleteCollectedGraphData),
+		DeleteFileIngestHistory:  ptr(w.DeleteFileIngestHistory),
+		DeleteDataQualityHistory: ptr(w.DeleteDataQualityHistory),
+	}
+	if len(w.DeleteAssetGroupSelectors) > 0 {
+		body.DeleteAssetGroupSelectors = ptr(w.DeleteAssetGroupSelectors)
+	}
+	return body
+}
+
+// WipedSelector is an asset group selector the wipe would delete
+type WipedSelector struct {
+	AssetGroupID   int32  `json:"asset_group_id"`
+	AssetGroupName string `json:"asset_group_name"`
+	SelectorID     int32  `json:"selector_id"`
+	Name           string `json:"name"`
+	Selector       string `json:"selector"`
+}
+
+// DatabaseWipePreview describes what a wipe would delete. Only the parts selected by the wipe are filled in.
+type DatabaseWipePreview struct {
+	Wipe DatabaseWipe `json:"wipe"`
+
+	// Completeness is the graph completeness by statistic, in percent
+	Completeness map[string]float64 `json:"completeness,omitempty"`
+
+	// GraphCounts are the node and relationship counts of the latest data quality run, keyed by platform then
+	// by count name such as "users"
+	GraphCounts map[string]map[string]int64 `json:"graph_counts,omitempty"`
+
+	// DataQualityRecords is the number of data quality history records by platform
+	DataQualityRecords map[string]int `json:"data_quality_records,omitempty"`
+
+	FileUploadJobs int             `json:"file_upload_jobs"`
+	Selectors      []WipedSelector `json:"selectors,omitempty"`
+
+	// Token must be passed back to WipeDatabase to confirm the wipe. It is derived from everything above, so a
+	// preview of different data yields a different token.
+	Token string `json:"token"`
+}
+
+// Data quality platforms of GetPlatformDataQualityAggregate
+var dataQualityPlatforms = []string{"ad", "azure"}
+
+// PreviewDatabaseWipe computes what the wipe would delete, without deleting anything
+func (c *ClientWithResponses) PreviewDatabaseWipe(ctx context.Context, wipe DatabaseWipe) (*DatabaseWipePreview, error) {
+	preview := &DatabaseWipePreview{Wipe: wipe}
+
+	if wipe.DeleteCollectedGraphData {
+		completeness, err := c.GetCompletenessStatsWithResponse(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if completeness.StatusCode() != http.StatusOK || completeness.JSON200 == nil {
+			return nil, newStatusError("GetCompletenessStats", completeness.StatusCode(), completeness.Body)
+		}
+		if completeness.JSON200.Data != nil {
+			preview.Completeness = *completeness.JSON200.Data
+		}
+	}
+
+	if wipe.DeleteCollectedGraphData || wipe.DeleteDataQualityHistory {
+		preview.GraphCounts = map[string]map[string]int64{}
+		preview.DataQualityRecords = map[string]int{}
+		for _, platform := range dataQualityPlatforms {
+			counts, records, err := c.latestDataQualityCounts(ctx, platform)
+			if err != nil {
+				return nil, err
+			}
+			if wipe.DeleteCollectedGraphData && counts != nil {
+				preview.GraphCounts[platform] = counts
+			}
+			if wipe.DeleteDataQualityHistory {
+				preview.DataQualityRecords[platform] = records
+			}
+		}
+	}
+
+	if wipe.DeleteFileIngestHistory {
+		jobs, err := c.ListFileUploadJobsWithResponse(ctx, &ListFileUploadJobsParams{Limit: ptr(1)})
+		if err != nil {
+			return nil, err
+		}
+		if jobs.StatusCode() != http.StatusOK || jobs.JSON200 == nil {
+			return nil, newStatusError("ListFileUploadJobs", jobs.StatusCode(), jobs.Body)
+		}
+		if jobs.JSON200.Count != nil {
+			preview.FileUploadJobs = *jobs.JSON200.Count
+		}
+	}
+
+	if len(wipe.DeleteAssetGroupSelectors) > 0 {
+		selectors, err := c.wipedSelectors(ctx, wipe.DeleteAssetGroupSelectors)
+		if err != nil {
+			return nil, err
+		}
+		preview.Selectors = selectors
+	}
+
+	token, err := preview.token()
+	if err != nil {
+		return nil, err
+	}
+	preview.Token = token
+	return preview, nil
+}
+
+// Counts of the latest data quality run of a platform, and the number of runs on record
+func (c *ClientWithResponses) latestDataQualityCounts(ctx context.Context, platform string) (map[string]int64, int, error) {
+	response, err := c.GetPlatformDataQualityAggregateWithResponse(ctx, platform, &GetPlatformDataQualityAggregateParams{
+		SortBy: ptr("-created_at"),
+		Limit:  ptr(1),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, 0, newStatusError("GetPlatformDataQualityAggregate", response.StatusCode(), response.Body)
+	}
+
+	records := 0
+	if response.JSON200.Count != nil {
+		records = *response.JSON200.Count
+	}
+	if response.JSON200.Data == nil || len(*response.JSON200.Data) == 0 {
+		return nil, records, nil
+	}
+
+	// The item is a union of the AD and Azure aggregations, so the counts are read generically
+	raw, err := (*response.JSON200.Data)[0].MarshalJSON()
+	if err != nil {
+		return nil, 0, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, 0, fmt.Errorf("error decoding %s data quality: %w", platform, err)
+	}
+	counts := map[string]int64{}
+	for key, value := range fields {
+		if number, ok := value.(float64); ok && key != "id" && !strings.HasSuffix(key, "_completeness") {
+			counts[key] = int64(number)
+		}
+	}
+	return counts, records, nil
+}
+
+func (c *ClientWithResponses) wipedSelectors(ctx context.Context, assetGroupIds []int) ([]WipedSelector, error) {
+	response, err := c.ListAssetGroupsWithResponse(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, newStatusError("ListAssetGroups", response.StatusCode(), response.Body)
+	}
+
+	groups := map[int32]ModelAssetGroup{}
+	if data := response.JSON200.Data; data != nil && data.AssetGroups != nil {
+		for _, group := range *data.AssetGroups {
+			if group.Id != nil {
+				groups[*group.Id] = group
+			}
+		}
+	}
+
+	var selectors []WipedSelector
+	for _, id := range assetGroupIds {
+		group, ok := groups[int32(id)]
+		if !ok {
+			return nil, fmt.Errorf("asset group %d does not exist", id)
+		}
+		if group.Selectors == nil {
+			continue
+		}
+		for _, selector := range *group.Selectors {
+			// System selectors are kept by the server
+			if selector.SystemSelector != nil && *selector.SystemSelector {
+				continue
+			}
+			wiped := WipedSelector{AssetGroupID: int32(id)}
+			if group.Name != nil {
+				wiped.AssetGroupName = *group.Name
+			}
+			if selector.Id != nil {
+				wiped.SelectorID = *selector.Id
+			}
+			if selector.Name != nil {
+				wiped.Name = *selector.Name
+			}
+			if selector.Selector != nil {
+				wiped.Selector = *selector.Selector
+			}
+			selectors = append(selectors, wiped)
+		}
+	}
+	sort.Slice(selectors, func(i, j int) bool {
+		if selectors[i].AssetGroupID != selectors[j].AssetGroupID {
+			return selectors[i].AssetGroupID < selectors[j].AssetGroupID
+		}
+		return selectors[i].SelectorID < selectors[j].SelectorID
+	})
+	return selectors, nil
+}
+
+// The token is a short hash of the preview content, which encoding/json renders deterministically
+func (p *DatabaseWipePreview) token() (string, error) {
+	content := *p
+	content.Token = ""
+	encoded, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:6]), nil
+}
+
+// Summary renders the preview as human readable lines
+func (p *DatabaseWipePreview) Summary() string {
+	var builder strings.Builder
+	if p.Wipe.DeleteCollectedGraphData {
+		builder.WriteString("Collected graph data will be deleted:\n")
+		for _, platform := range sortedKeys(p.GraphCounts) {
+			counts := p.GraphCounts[platform]
+			for _, name := range sortedKeys(counts) {
+				fmt.Fprintf(&builder, "  %s %s: %d\n", platform, name, counts[name])
+			}
+		}
+		for _, name := range sortedKeys(p.Completeness) {
+			fmt.Fprintf(&builder, "  completeness %s: %.1f%%\n", name, p.Completeness[name])
+		}
+	}
+	if p.Wipe.DeleteDataQualityHistory {
+		builder.WriteString("Data quality history will be deleted:\n")
+		for _, platform := range sortedKeys(p.DataQualityRecords) {
+			fmt.Fprintf(&builder, "  %s: %d records\n", platform, p.DataQualityRecords[platform])
+		}
+	}
+	if p.Wipe.DeleteFileIngestHistory {
+		fmt.Fprintf(&builder, "File ingest history will be deleted: %d file upload jobs\n", p.FileUploadJobs)
+	}
+	if len(p.Wipe.DeleteAssetGroupSelectors) > 0 {
+		fmt.Fprintf(&builder, "Asset group selectors will be deleted: %d\n", len(p.Selectors))
+		for _, selector := range p.Selectors {
+			fmt.Fprintf(&builder, "  %s: %s (%s)\n", selector.AssetGroupName, selector.Name, selector.Selector)
+		}
+	}
+	fmt.Fprintf(&builder, "Confirmation token: %s\n", p.Token)
+	return builder.String()
+}
+
+// DatabaseSnapshot is the exportable state that survives a wipe only if it is saved beforehand
+type DatabaseSnapshot struct {
+	TakenAt      time.Time         `json:"taken_at"`
+	SavedQueries []ModelSavedQuery `json:"saved_queries"`
+	AssetGroups  []ModelAssetGroup `json:"asset_groups"`
+}
+
+// SnapshotDatabase exports the saved queries and the asset groups with their selectors
+func (c *ClientWithResponses) SnapshotDatabase(ctx context.Context) (*DatabaseSnapshot, error) {
+	snapshot := &DatabaseSnapshot{TakenAt: time.Now().UTC()}
+
+	const pageSize = 100
+	for skip := 0; ; skip += pageSize {
+		response, err := c.ListSavedQueriesWithResponse(ctx, &ListSavedQueriesParams{Skip: ptr(skip), Limit: ptr(pageSize)})
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+			return nil, newStatusError("ListSavedQueries", response.StatusCode(), response.Body)
+		}
+		if response.JSON200.Data == nil {
+			break
+		}
+		snapshot.SavedQueries = append(snapshot.SavedQueries, *response.JSON200.Data...)
+		if len(*response.JSON200.Data) < pageSize {
+			break
+		}
+	}
+
+	groups, err := c.ListAssetGroupsWithResponse(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if groups.StatusCode() != http.StatusOK || groups.JSON200 == nil {
+		return nil, newStatusError("ListAssetGroups", groups.StatusCode(), groups.Body)
+	}
+	if data := groups.JSON200.Data; data != nil && data.AssetGroups != nil {
+		snapshot.AssetGroups = *data.AssetGroups
+	}
+	return snapshot, nil
+}
+
+// WipeDatabase deletes what the wipe selects once confirmed. The preview is computed again and the wipe only
+// proceeds if its token equals confirmation. When snapshot is not nil the exportable state is written to it as
+// JSON first, and a failure to do so aborts the wipe.
+func (c *ClientWithResponses) WipeDatabase(ctx context.Context, wipe DatabaseWipe, confirmation string, snapshot io.Writer) (*DatabaseWipePreview, error) {
+	preview, err := c.PreviewDatabaseWipe(ctx, wipe)
+	if err != nil {
+		return nil, err
+	}
+	if confirmation == "" || confirmation != preview.Token {
+		return preview, fmt.Errorf("%w: expected token %s", ErrWipeNotConfirmed, preview.Token)
+	}
+
+	if snapshot != nil {
+		state, err := c.SnapshotDatabase(ctx)
+		if err != nil {
+			return preview, fmt.Errorf("error taking snapshot: %w", err)
+		}
+		encoder := json.NewEncoder(snapshot)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(state); err != nil {
+			return preview, fmt.Errorf("error writing snapshot: %w", err)
+		}
+	}
+
+	response, err := c.DeleteBloodHoundDatabaseWithResponse(ctx, nil, wipe.body())
+	if err != nil {
+		return preview, err
+	}
+	if response.StatusCode() < 200 || response.StatusCode() > 299 {
+		return preview, newStatusError("DeleteBloodHoundDatabase", response.StatusCode(), response.Body)
+	}
+	return preview, nil
+}
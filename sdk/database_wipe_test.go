@@ -0,0 +1,96 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// A server whose file upload job count and asset group selectors the test changes between preview and wipe
+type wipeServer struct {
+	jobs     int
+	selector string
+	wipes    int
+}
+
+func (s *wipeServer) client(t *testing.T) *ClientWithResponses {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/file-upload":
+			fmt.Fprintf(w, `{"count":%d,"data":[]}`, s.jobs)
+		case "/api/v2/asset-groups":
+			fmt.Fprintf(w, `{"data":{"asset_groups":[{"id":1,"name":"Owned","selectors":[{"id":7,"name":"custom","selector":%q}]}]}}`, s.selector)
+		case "/api/v2/clear-database":
+			s.wipes++
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestWipeDatabaseRejectsTokenOfChangedPreview(t *testing.T) {
+	wipe := DatabaseWipe{DeleteFileIngestHistory: true, DeleteAssetGroupSelectors: []int{1}}
+	for _, test := range []struct {
+		name   string
+		change func(*wipeServer)
+	}{
+		{name: "more file upload jobs", change: func(s *wipeServer) { s.jobs++ }},
+		{name: "a different selector", change: func(s *wipeServer) { s.selector = "S-1-5-21-2" }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := &wipeServer{jobs: 3, selector: "S-1-5-21-1"}
+			client := server.client(t)
+			preview, err := client.PreviewDatabaseWipe(context.Background(), wipe)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test.change(server)
+			current, err := client.WipeDatabase(context.Background(), wipe, preview.Token, nil)
+			if !errors.Is(err, ErrWipeNotConfirmed) {
+				t.Fatalf("got %v, want %v", err, ErrWipeNotConfirmed)
+			}
+			if server.wipes != 0 {
+				t.Fatal("the database was wiped with the token of an outdated preview")
+			}
+			if current.Token == preview.Token {
+				t.Fatal("the changed preview kept its token")
+			}
+
+			if _, err := client.WipeDatabase(context.Background(), wipe, current.Token, nil); err != nil {
+				t.Fatal(err)
+			}
+			if server.wipes != 1 {
+				t.Fatalf("wiped %d times with the current token, want once", server.wipes)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrEntityNotFound is returned when no node has the requested object id
+	ErrEntityNotFound = errors.New("entity not found")
+
+	// ErrUnknownRelation is returned when a related entity listing does not exist for the kind of an entity
+	ErrUnknownRelation = errors.New("unknown related entity listing")
+)
+
+// EntityModel is implemented by every typed node model, such as *ADUser or *AZVM
+type EntityModel interface {
+	Kind() string
+}
+
+// RelatedEntity is one entry of a related entity listing
+type RelatedEntity struct {
+	ObjectID string
+	Name     string
+	Label    string
+}
+
+// Entity is a node fetched without knowing its kind in advance
+type Entity interface {
+	Kind() string
+	ObjectID() string
+
+	// Model is the typed node model. Type switch on it, or assert the type matching Kind, such as *ADUser.
+	Model() EntityModel
+
+	// Counts are the related entity counts returned with the node
+	Counts() map[string]int64
+
+	// Relations names the related entity listings available for the kind, such as "Controllers" or "Members"
+	Relations() []string
+
+	// Related fetches every entry of a related entity listing. Names are matched ignoring case, hyphens and
+	// underscores, so "group-membership" finds "GroupMembership".
+	Related(ctx context.Context, relation string) ([]RelatedEntity, error)
+}
+
+// Page size used when walking related entity listings
+const relatedEntityPageSize = 100
+
+type adEntityGetter struct {
+	operation string
+	get       func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error)
+	convert   func(properties EntityProperties) EntityModel
+}
+
+func entityModel[T EntityModel](convert func(EntityProperties) T) func(EntityProperties) EntityModel {
+	return func(properties EntityProperties) EntityModel {
+		return convert(properties)
+	}
+}
+
+var adEntityGetters = map[string]adEntityGetter{
+	KindUser: {"GetUserEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetUserEntity(ctx, objectId, nil)
+	}, entityModel(NewADUser)},
+	KindComputer: {"GetComputerEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetComputerEntity(ctx, objectId, nil)
+	}, entityModel(NewADComputer)},
+	KindGroup: {"GetGroupEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetGroupEntity(ctx, objectId, nil)
+	}, entityModel(NewADGroup)},
+	KindDomain: {"GetDomainEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetDomainEntity(ctx, objectId, nil)
+	}, entityModel(NewADDomain)},
+	KindOU: {"GetOuEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetOuEntity(ctx, objectId, nil)
+	}, entityModel(NewADOU)},
+	KindGPO: {"GetGpoEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetGpoEntity(ctx, objectId, nil)
+	}, entityModel(NewADGPO)},
+	KindContainer: {"GetContainerEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetContainerEntity(ctx, objectId, nil)
+	}, entityModel(NewADContainer)},
+	KindCertTemplate: {"GetCertTemplateEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetCertTemplateEntity(ctx, objectId, nil)
+	}, entityModel(NewADCertTemplate)},
+	KindEnterpriseCA: {"GetEnterpriseCaEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetEnterpriseCaEntity(ctx, objectId, nil)
+	}, entityModel(NewADEnterpriseCA)},
+	KindRootCA: {"GetRootCaEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetRootCaEntity(ctx, objectId, nil)
+	}, entityModel(NewADRootCA)},
+	KindAIACA: {"GetAiaCaEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetAiaCaEntity(ctx, objectId, nil)
+	}, entityModel(NewADAIACA)},
+	KindNTAuthStore: {"GetNtAuthStoreEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+		return c.GetNtAuthStoreEntity(ctx, objectId, nil)
+	}, entityModel(NewADNTAuthStore)},
+}
+
+// Path segment of GetAzureEntity for each Azure kind
+var azureEntityTypes = map[string]string{
+	KindAZUser:             "users",
+	KindAZGroup:            "groups",
+	KindAZServicePrincipal: "service-principals",
+	KindAZApp:              "applications",
+	KindAZDevice:           "devices",
+	KindAZTenant:           "tenants",
+	KindAZSubscription:     "subscriptions",
+	KindAZResourceGroup:    "resource-groups",
+	KindAZVM:               "vms",
+	KindAZKeyVault:         "key-vaults",
+	KindAZRole:             "roles",
+	KindAZManagementGroup:  "management-groups",
+}
+
+// Related entity types accepted by GetAzureEntity. Not every listing applies to every kind; the server answers
+// 400 Bad Request for those that do not.
+var azureRelatedEntityTypes = []string{
+	"inbound-control",
+	"outbound-control",
+	"inbound-execution-privileges",
+	"outbound-execution-privileges",
+	"group-membership",
+	"group-members",
+	"roles",
+	"active-assignments",
+	"pim-assignments",
+	"descendent-users",
+	"descendent-groups",
+	"descendent-management-groups",
+	"descendent-subscriptions",
+	"descendent-resource-groups",
+	"descendent-vms",
+	"descendent-key-vaults",
+	"descendent-service-principals",
+	"descendent-applications",
+	"descendent-devices",
+}
+
+// Entity fetches a node by object id without knowing its kind. The kind is resolved through search, then the
+// node is fetched from the endpoint of that kind.
+func (c *ClientWithResponses) Entity(ctx context.Context, objectId string) (Entity, error) {
+	kind, err := c.ResolveEntityKind(ctx, objectId)
+	if err != nil {
+		return nil, err
+	}
+	return c.EntityOfKind(ctx, kind, objectId)
+}
+
+// ResolveEntityKind returns the kind of the node with the given object id, such as "User" or "AZVM"
+func (c *ClientWithResponses) ResolveEntityKind(ctx context.Context, objectId string) (string, error) {
+	search, err := c.SearchWithResponse(ctx, &SearchParams{Q: objectId, Limit: ptr(10)})
+	if err != nil {
+		return "", err
+	}
+	if search.StatusCode() != 200 {
+		return "", newStatusError("Search", search.StatusCode(), search.Body)
+	}
+	if search.JSON200 != nil && search.JSON200.Data != nil {
+		for _, result := range *search.JSON200.Data {
+			if result.Objectid != nil && result.Type != nil && strings.EqualFold(*result.Objectid, objectId) {
+				return *result.Type, nil
+			}
+		}
+	}
+
+	// Search ranks by name and may not return an object id match, so fall back to an exact graph search
+	result, err := c.GetSearchResultWithResponse(ctx, &GetSearchResultParams{SearchQuery: objectId, Type: ptr(Exact)})
+	if err != nil {
+		return "", err
+	}
+	if result.StatusCode() == 200 && result.JSON200 != nil && result.JSON200.Data != nil {
+		for _, node := range *result.JSON200.Data {
+			if node.Data == nil {
+				continue
+			}
+			data := newPropertyReader(EntityProperties(*node.Data))
+			if kind := data.string("nodetype"); kind != "" && strings.EqualFold(data.string("objectid"), objectId) {
+				return kind, nil
+			}
+		}
+	} else if result.StatusCode() != http.StatusNotFound {
+		return "", newStatusError("GetSearchResult", result.StatusCode(), result.Body)
+	}
+	return "", fmt.Errorf("%w: %s", ErrEntityNotFound, objectId)
+}
+
+// EntityOfKind fetches a node whose kind is already known. Active Directory kinds without a dedicated endpoint
+// are fetched through GetEntity.
+func (c *ClientWithResponses) EntityOfKind(ctx context.Context, kind, objectId string) (Entity, error) {
+	if strings.HasPrefix(kind, "AZ") {
+		return c.azureEntity(ctx, kind, objectId)
+	}
+
+	getter, ok := adEntityGetters[kind]
+	if !ok {
+		getter = adEntityGetter{"GetEntity", func(ctx context.Context, c *ClientWithResponses, objectId string) (*http.Response, error) {
+			return c.GetEntity(ctx, objectId, nil)
+		}, entityModel(func(properties EntityProperties) *ADBase {
+			r := newPropertyReader(properties)
+			base := readADBase(r)
+			base.Extras = r.extras()
+			return &base
+		})}
+	}
+
+	response, err := getter.get(ctx, c, objectId)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parseEntityHTTPResponse(getter.operation, objectId, response)
+	if err != nil {
+		return nil, err
+	}
+
+	// The generic entity listings apply to every kind, and the dedicated ones take precedence
+	listers := map[string]relatedEntityLister{
+		"Controllers":   entityListingLister("GetEntityControllers", entityListing(ClientInterface.GetEntityControllers)),
+		"Controllables": entityListingLister("GetEntityControllables", entityListing(ClientInterface.GetEntityControllables)),
+	}
+	for _, relation := range adEntityRelations {
+		if relation.kind == kind {
+			listers[relation.relation] = entityListingLister(getter.operation+relation.relation, relation.fetch)
+		}
+	}
+	return newEntity(c, kind, objectId, getter.convert(parsed.Properties), parsed.Counts, listers), nil
+}
+
+func (c *ClientWithResponses) azureEntity(ctx context.Context, kind, objectId string) (Entity, error) {
+	entityType, ok := azureEntityTypes[kind]
+	if !ok {
+		entityType = "az-base"
+	}
+	response, err := c.GetAzureEntity(ctx, entityType, &GetAzureEntityParams{ObjectId: objectId, Counts: ptr(true)})
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parseEntityHTTPResponse("GetAzureEntity", objectId, response)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Kind != "" {
+		kind = parsed.Kind
+	}
+
+	listers := map[string]relatedEntityLister{}
+	for _, relatedType := range azureRelatedEntityTypes {
+		listers[relatedType] = azureRelatedEntityLister(entityType, relatedType)
+	}
+	return newEntity(c, kind, objectId, NewAZEntity(kind, parsed.Properties), parsed.Counts, listers), nil
+}
+
+func parseEntityHTTPResponse(operation, objectId string, response *http.Response) (*EntityResponse, error) {
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch response.StatusCode {
+	case http.StatusOK:
+		return ParseEntityResponse(body)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrEntityNotFound, objectId)
+	default:
+		return nil, newStatusError(operation, response.StatusCode, body)
+	}
+}
+
+func azureRelatedEntityLister(entityType, relatedType string) relatedEntityLister {
+	return func(ctx context.Context, c *ClientWithResponses, objectId string, skip, limit int) (*RelatedEntityQueryResults, error) {
+		response, err := c.GetAzureEntityWithResponse(ctx, entityType, &GetAzureEntityParams{
+			ObjectId:          objectId,
+			RelatedEntityType: &relatedType,
+			Type:              ptr(GetAzureEntityParamsTypeList),
+			Skip:              &skip,
+			Limit:             &limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// The generated JSON200 type does not describe listings, so they are decoded from the body
+		var results RelatedEntityQueryResults
+		if response.StatusCode() == 200 {
+			if err := json.Unmarshal(response.Body, &results); err != nil {
+				return nil, fmt.Errorf("error decoding %s listing: %w", relatedType, err)
+			}
+		}
+		return relatedEntityResults("GetAzureEntity", response.StatusCode(), response.Body, &results)
+	}
+}
+
+func relatedEntityResults(operation string, statusCode int, body []byte, results *RelatedEntityQueryResults) (*RelatedEntityQueryResults, error) {
+	if statusCode != 200 || results == nil {
+		return nil, newStatusError(operation, statusCode, body)
+	}
+	return results, nil
+}
+
+type entity struct {
+	client   *ClientWithResponses
+	kind     string
+	objectId string
+	model    EntityModel
+	counts   map[string]int64
+	names    []string
+	listers  map[string]relatedEntityLister
+}
+
+func newEntity(client *ClientWithResponses, kind, objectId string, model EntityModel, counts map[string]int64, listers map[string]relatedEntityLister) *entity {
+	e := &entity{
+		client:   client,
+		kind:     kind,
+		objectId: objectId,
+		model:    model,
+		counts:   counts,
+		listers:  map[string]relatedEntityLister{},
+	}
+	for name, lister := range listers {
+		e.names = append(e.names, name)
+		e.listers[normalizeRelation(name)] = lister
+	}
+	sort.Strings(e.names)
+	return e
+}
+
+func normalizeRelation(name string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(name))
+}
+
+func (e *entity) Kind() string             { return e.kind }
+func (e *entity) ObjectID() string         { return e.objectId }
+func (e *entity) Model() EntityModel       { return e.model }
+func (e *entity) Counts() map[string]int64 { return e.counts }
+func (e *entity) Relations() []string      { return append([]string(nil), e.names...) }
+
+func (e *entity) Related(ctx context.Context, relation string) ([]RelatedEntity, error) {
+	lister, ok := e.listers[normalizeRelation(relation)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q for kind %s", ErrUnknownRelation, relation, e.kind)
+	}
+
+	var related []RelatedEntity
+	for skip := 0; ; skip += relatedEntityPageSize {
+		page, err := lister(ctx, e.client, e.objectId, skip, relatedEntityPageSize)
+		if err != nil {
+			return related, err
+		}
+		if page.Data == nil {
+			break
+		}
+		for _, item := range *page.Data {
+			entry := RelatedEntity{}
+			if item.ObjectID != nil {
+				entry.ObjectID = *item.ObjectID
+			}
+			if item.Name != nil {
+				entry.Name = *item.Name
+			}
+			if item.Label != nil {
+				entry.Label = *item.Label
+			}
+			related = append(related, entry)
+		}
+		if len(*page.Data) < relatedEntityPageSize || (page.Count != nil && len(related) >= *page.Count) {
+			break
+		}
+	}
+	return related, nil
+}
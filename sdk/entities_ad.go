@@ -0,0 +1,450 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+// Node kinds of the Active Directory graph
+const (
+	KindBase         = "Base"
+	KindUser         = "User"
+	KindComputer     = "Computer"
+	KindGroup        = "Group"
+	KindDomain       = "Domain"
+	KindOU           = "OU"
+	KindGPO          = "GPO"
+	KindContainer    = "Container"
+	KindCertTemplate = "CertTemplate"
+	KindEnterpriseCA = "EnterpriseCA"
+	KindRootCA       = "RootCA"
+	KindAIACA        = "AIACA"
+	KindNTAuthStore  = "NTAuthStore"
+)
+
+// ADBase holds the properties shared by every Active Directory node. Extras keeps the properties that have no
+// typed field.
+type ADBase struct {
+	ObjectID          string                 `json:"objectid"`
+	Name              string                 `json:"name"`
+	DisplayName       string                 `json:"displayname,omitempty"`
+	DistinguishedName string                 `json:"distinguishedname,omitempty"`
+	Domain            string                 `json:"domain,omitempty"`
+	DomainSID         string                 `json:"domainsid,omitempty"`
+	Description       string                 `json:"description,omitempty"`
+	WhenCreated       *EntityTime            `json:"whencreated,omitempty"`
+	LastSeen          *EntityTime            `json:"lastseen,omitempty"`
+	SystemTags        string                 `json:"system_tags,omitempty"`
+	IsTierZero        bool                   `json:"is_tier_zero"`
+	Extras            map[string]interface{} `json:"extras,omitempty"`
+}
+
+func (ADBase) Kind() string { return KindBase }
+
+func readADBase(r *propertyReader) ADBase {
+	base := ADBase{
+		ObjectID:          r.string("objectid"),
+		Name:              r.string("name"),
+		DisplayName:       r.string("displayname"),
+		DistinguishedName: r.string("distinguishedname"),
+		Domain:            r.string("domain"),
+		DomainSID:         r.string("domainsid"),
+		Description:       r.string("description"),
+		WhenCreated:       r.time("whencreated"),
+		LastSeen:          r.time("lastseen"),
+		SystemTags:        r.string("system_tags"),
+	}
+	base.IsTierZero = hasSystemTag(base.SystemTags, "admin_tier_0")
+	return base
+}
+
+type ADUser struct {
+	ADBase
+	SAMAccountName          string      `json:"samaccountname,omitempty"`
+	Email                   string      `json:"email,omitempty"`
+	Title                   string      `json:"title,omitempty"`
+	Enabled                 bool        `json:"enabled"`
+	AdminCount              bool        `json:"admincount"`
+	HasSPN                  bool        `json:"hasspn"`
+	DontReqPreauth          bool        `json:"dontreqpreauth"`
+	PasswordNeverExpires    bool        `json:"pwdneverexpires"`
+	PasswordNotRequired     bool        `json:"passwordnotreqd"`
+	Sensitive               bool        `json:"sensitive"`
+	UnconstrainedDelegation bool        `json:"unconstraineddelegation"`
+	TrustedToAuth           bool        `json:"trustedtoauth"`
+	LastLogon               *EntityTime `json:"lastlogon,omitempty"`
+	LastLogonTimestamp      *EntityTime `json:"lastlogontimestamp,omitempty"`
+	PasswordLastSet         *EntityTime `json:"pwdlastset,omitempty"`
+	ServicePrincipalNames   []string    `json:"serviceprincipalnames,omitempty"`
+	AllowedToDelegate       []string    `json:"allowedtodelegate,omitempty"`
+	SIDHistory              []string    `json:"sidhistory,omitempty"`
+}
+
+func (ADUser) Kind() string { return KindUser }
+
+func NewADUser(properties EntityProperties) *ADUser {
+	r := newPropertyReader(properties)
+	user := &ADUser{
+		ADBase:                  readADBase(r),
+		SAMAccountName:          r.string("samaccountname"),
+		Email:                   r.string("email"),
+		Title:                   r.string("title"),
+		Enabled:                 r.bool("enabled"),
+		AdminCount:              r.bool("admincount"),
+		HasSPN:                  r.bool("hasspn"),
+		DontReqPreauth:          r.bool("dontreqpreauth"),
+		PasswordNeverExpires:    r.bool("pwdneverexpires"),
+		PasswordNotRequired:     r.bool("passwordnotreqd"),
+		Sensitive:               r.bool("sensitive"),
+		UnconstrainedDelegation: r.bool("unconstraineddelegation"),
+		TrustedToAuth:           r.bool("trustedtoauth"),
+		LastLogon:               r.time("lastlogon"),
+		LastLogonTimestamp:      r.time("lastlogontimestamp"),
+		PasswordLastSet:         r.time("pwdlastset"),
+		ServicePrincipalNames:   r.strings("serviceprincipalnames"),
+		AllowedToDelegate:       r.strings("allowedtodelegate"),
+		SIDHistory:              r.strings("sidhistory"),
+	}
+	user.Extras = r.extras()
+	return user
+}
+
+type ADComputer struct {
+	ADBase
+	SAMAccountName          string      `json:"samaccountname,omitempty"`
+	OperatingSystem         string      `json:"operatingsystem,omitempty"`
+	Enabled                 bool        `json:"enabled"`
+	HasLAPS                 bool        `json:"haslaps"`
+	IsDC                    bool        `json:"isdc"`
+	UnconstrainedDelegation bool        `json:"unconstraineddelegation"`
+	TrustedToAuth           bool        `json:"trustedtoauth"`
+	LastLogon               *EntityTime `json:"lastlogon,omitempty"`
+	LastLogonTimestamp      *EntityTime `json:"lastlogontimestamp,omitempty"`
+	PasswordLastSet         *EntityTime `json:"pwdlastset,omitempty"`
+	ServicePrincipalNames   []string    `json:"serviceprincipalnames,omitempty"`
+	AllowedToDelegate       []string    `json:"allowedtodelegate,omitempty"`
+	SIDHistory              []string    `json:"sidhistory,omitempty"`
+}
+
+func (ADComputer) Kind() string { return KindComputer }
+
+func NewADComputer(properties EntityProperties) *ADComputer {
+	r := newPropertyReader(properties)
+	computer := &ADComputer{
+		ADBase:                  readADBase(r),
+		SAMAccountName:          r.string("samaccountname"),
+		OperatingSystem:         r.string("operatingsystem"),
+		Enabled:                 r.bool("enabled"),
+		HasLAPS:                 r.bool("haslaps"),
+		IsDC:                    r.bool("isdc"),
+		UnconstrainedDelegation: r.bool("unconstraineddelegation"),
+		TrustedToAuth:           r.bool("trustedtoauth"),
+		LastLogon:               r.time("lastlogon"),
+		LastLogonTimestamp:      r.time("lastlogontimestamp"),
+		PasswordLastSet:         r.time("pwdlastset"),
+		ServicePrincipalNames:   r.strings("serviceprincipalnames"),
+		AllowedToDelegate:       r.strings("allowedtodelegate"),
+		SIDHistory:              r.strings("sidhistory"),
+	}
+	computer.Extras = r.extras()
+	return computer
+}
+
+type ADGroup struct {
+	ADBase
+	SAMAccountName string `json:"samaccountname,omitempty"`
+	AdminCount     bool   `json:"admincount"`
+}
+
+func (ADGroup) Kind() string { return KindGroup }
+
+func NewADGroup(properties EntityProperties) *ADGroup {
+	r := newPropertyReader(properties)
+	group := &ADGroup{
+		ADBase:         readADBase(r),
+		SAMAccountName: r.string("samaccountname"),
+		AdminCount:     r.bool("admincount"),
+	}
+	group.Extras = r.extras()
+	return group
+}
+
+type ADDomain struct {
+	ADBase
+	FunctionalLevel string `json:"functionallevel,omitempty"`
+	Collected       bool   `json:"collected"`
+}
+
+func (ADDomain) Kind() string { return KindDomain }
+
+func NewADDomain(properties EntityProperties) *ADDomain {
+	r := newPropertyReader(properties)
+	domain := &ADDomain{
+		ADBase:          readADBase(r),
+		FunctionalLevel: r.string("functionallevel"),
+		Collected:       r.bool("collected"),
+	}
+	domain.Extras = r.extras()
+	return domain
+}
+
+type ADOU struct {
+	ADBase
+	BlocksInheritance bool `json:"blocksinheritance"`
+}
+
+func (ADOU) Kind() string { return KindOU }
+
+func NewADOU(properties EntityProperties) *ADOU {
+	r := newPropertyReader(properties)
+	ou := &ADOU{
+		ADBase:            readADBase(r),
+		BlocksInheritance: r.bool("blocksinheritance"),
+	}
+	ou.Extras = r.extras()
+	return ou
+}
+
+type ADGPO struct {
+	ADBase
+	GPCPath string `json:"gpcpath,omitempty"`
+}
+
+func (ADGPO) Kind() string { return KindGPO }
+
+func NewADGPO(properties EntityProperties) *ADGPO {
+	r := newPropertyReader(properties)
+	gpo := &ADGPO{
+		ADBase:  readADBase(r),
+		GPCPath: r.string("gpcpath"),
+	}
+	gpo.Extras = r.extras()
+	return gpo
+}
+
+type ADContainer struct {
+	ADBase
+}
+
+func (ADContainer) Kind() string { return KindContainer }
+
+func NewADContainer(properties EntityProperties) *ADContainer {
+	r := newPropertyReader(properties)
+	container := &ADContainer{
+		ADBase: readADBase(r),
+	}
+	container.Extras = r.extras()
+	return container
+}
+
+type ADCertTemplate struct {
+	ADBase
+	OID                          string   `json:"oid,omitempty"`
+	SchemaVersion                int64    `json:"schemaversion"`
+	ValidityPeriod               string   `json:"validityperiod,omitempty"`
+	RenewalPeriod                string   `json:"renewalperiod,omitempty"`
+	AuthorizedSignatures         int64    `json:"authorizedsignatures"`
+	RequiresManagerApproval      bool     `json:"requiresmanagerapproval"`
+	AuthenticationEnabled        bool     `json:"authenticationenabled"`
+	EnrolleeSuppliesSubject      bool     `json:"enrolleesuppliessubject"`
+	NoSecurityExtension          bool     `json:"nosecurityextension"`
+	EffectiveEKUs                []string `json:"effectiveekus,omitempty"`
+	EKUs                         []string `json:"ekus,omitempty"`
+	CertificateApplicationPolicy []string `json:"certificateapplicationpolicy,omitempty"`
+}
+
+func (ADCertTemplate) Kind() string { return KindCertTemplate }
+
+func NewADCertTemplate(properties EntityProperties) *ADCertTemplate {
+	r := newPropertyReader(properties)
+	template := &ADCertTemplate{
+		ADBase:                       readADBase(r),
+		OID:                          r.string("oid"),
+		SchemaVersion:                r.int64("schemaversion"),
+		ValidityPeriod:               r.string("validityperiod"),
+		RenewalPeriod:                r.string("renewalperiod"),
+		AuthorizedSignatures:         r.int64("authorizedsignatures"),
+		RequiresManagerApproval:      r.bool("requiresmanagerapproval"),
+		AuthenticationEnabled:        r.bool("authenticationenabled"),
+		EnrolleeSuppliesSubject:      r.bool("enrolleesuppliessubject"),
+		NoSecurityExtension:          r.bool("nosecurityextension"),
+		EffectiveEKUs:                r.strings("effectiveekus"),
+		EKUs:                         r.strings("ekus"),
+		CertificateApplicationPolicy: r.strings("certificateapplicationpolicy"),
+	}
+	template.Extras = r.extras()
+	return template
+}
+
+// ADCertificateAuthority holds the certificate properties shared by the CA kinds
+type ADCertificateAuthority struct {
+	CertThumbprint            string   `json:"certthumbprint,omitempty"`
+	CertName                  string   `json:"certname,omitempty"`
+	CertChain                 []string `json:"certchain,omitempty"`
+	HasBasicConstraints       bool     `json:"hasbasicconstraints"`
+	BasicConstraintPathLength int64    `json:"basicconstraintpathlength"`
+}
+
+func readADCertificateAuthority(r *propertyReader) ADCertificateAuthority {
+	return ADCertificateAuthority{
+		CertThumbprint:            r.string("certthumbprint"),
+		CertName:                  r.string("certname"),
+		CertChain:                 r.strings("certchain"),
+		HasBasicConstraints:       r.bool("hasbasicconstraints"),
+		BasicConstraintPathLength: r.int64("basicconstraintpathlength"),
+	}
+}
+
+type ADEnterpriseCA struct {
+	ADBase
+	ADCertificateAuthority
+	CAName                               string `json:"caname,omitempty"`
+	DNSHostname                          string `json:"dnshostname,omitempty"`
+	CASecurityCollected                  bool   `json:"casecuritycollected"`
+	EnrollmentAgentRestrictionsCollected bool   `json:"enrollmentagentrestrictionscollected"`
+	IsUserSpecifiesSanEnabled            bool   `json:"isuserspecifiessanenabled"`
+	HasEnrollmentAgentRestrictions       bool   `json:"hasenrollmentagentrestrictions"`
+}
+
+func (ADEnterpriseCA) Kind() string { return KindEnterpriseCA }
+
+func NewADEnterpriseCA(properties EntityProperties) *ADEnterpriseCA {
+	r := newPropertyReader(properties)
+	ca := &ADEnterpriseCA{
+		ADBase:                               readADBase(r),
+		ADCertificateAuthority:               readADCertificateAuthority(r),
+		CAName:                               r.string("caname"),
+		DNSHostname:                          r.string("dnshostname"),
+		CASecurityCollected:                  r.bool("casecuritycollected"),
+		EnrollmentAgentRestrictionsCollected: r.bool("enrollmentagentrestrictionscollected"),
+		IsUserSpecifiesSanEnabled:            r.bool("isuserspecifiessanenabled"),
+		HasEnrollmentAgentRestrictions:       r.bool("hasenrollmentagentrestrictions"),
+	}
+	ca.Extras = r.extras()
+	return ca
+}
+
+type ADRootCA struct {
+	ADBase
+	ADCertificateAuthority
+}
+
+func (ADRootCA) Kind() string { return KindRootCA }
+
+func NewADRootCA(properties EntityProperties) *ADRootCA {
+	r := newPropertyReader(properties)
+	ca := &ADRootCA{
+		ADBase:                 readADBase(r),
+		ADCertificateAuthority: readADCertificateAuthority(r),
+	}
+	ca.Extras = r.extras()
+	return ca
+}
+
+type ADAIACA struct {
+	ADBase
+	ADCertificateAuthority
+	HasCrossCertificatePair bool     `json:"hascrosscertificatepair"`
+	CrossCertificatePair    []string `json:"crosscertificatepair,omitempty"`
+}
+
+func (ADAIACA) Kind() string { return KindAIACA }
+
+func NewADAIACA(properties EntityProperties) *ADAIACA {
+	r := newPropertyReader(properties)
+	ca := &ADAIACA{
+		ADBase:                  readADBase(r),
+		ADCertificateAuthority:  readADCertificateAuthority(r),
+		HasCrossCertificatePair: r.bool("hascrosscertificatepair"),
+		CrossCertificatePair:    r.strings("crosscertificatepair"),
+	}
+	ca.Extras = r.extras()
+	return ca
+}
+
+type ADNTAuthStore struct {
+	ADBase
+	CertThumbprints []string `json:"certthumbprints,omitempty"`
+}
+
+func (ADNTAuthStore) Kind() string { return KindNTAuthStore }
+
+func NewADNTAuthStore(properties EntityProperties) *ADNTAuthStore {
+	r := newPropertyReader(properties)
+	store := &ADNTAuthStore{
+		ADBase:          readADBase(r),
+		CertThumbprints: r.strings("certthumbprints"),
+	}
+	store.Extras = r.extras()
+	return store
+}
+
+func typedEntityFromBody[T any](operation string, statusCode int, body []byte, convert func(EntityProperties) *T) (*T, error) {
+	if statusCode != 200 {
+		return nil, newStatusError(operation, statusCode, body)
+	}
+	entity, err := ParseEntityResponse(body)
+	if err != nil {
+		return nil, err
+	}
+	return convert(entity.Properties), nil
+}
+
+func ADUserFromResponse(response *GetUserEntityResponse) (*ADUser, error) {
+	return typedEntityFromBody("GetUserEntity", response.StatusCode(), response.Body, NewADUser)
+}
+
+func ADComputerFromResponse(response *GetComputerEntityResponse) (*ADComputer, error) {
+	return typedEntityFromBody("GetComputerEntity", response.StatusCode(), response.Body, NewADComputer)
+}
+
+func ADGroupFromResponse(response *GetGroupEntityResponse) (*ADGroup, error) {
+	return typedEntityFromBody("GetGroupEntity", response.StatusCode(), response.Body, NewADGroup)
+}
+
+func ADDomainFromResponse(response *GetDomainEntityResponse) (*ADDomain, error) {
+	return typedEntityFromBody("GetDomainEntity", response.StatusCode(), response.Body, NewADDomain)
+}
+
+func ADOUFromResponse(response *GetOuEntityResponse) (*ADOU, error) {
+	return typedEntityFromBody("GetOuEntity", response.StatusCode(), response.Body, NewADOU)
+}
+
+func ADGPOFromResponse(response *GetGpoEntityResponse) (*ADGPO, error) {
+	return typedEntityFromBody("GetGpoEntity", response.StatusCode(), response.Body, NewADGPO)
+}
+
+func ADContainerFromResponse(response *GetContainerEntityResponse) (*ADContainer, error) {
+	return typedEntityFromBody("GetContainerEntity", response.StatusCode(), response.Body, NewADContainer)
+}
+
+func ADCertTemplateFromResponse(response *GetCertTemplateEntityResponse) (*ADCertTemplate, error) {
+	return typedEntityFromBody("GetCertTemplateEntity", response.StatusCode(), response.Body, NewADCertTemplate)
+}
+
+func ADEnterpriseCAFromResponse(response *GetEnterpriseCaEntityResponse) (*ADEnterpriseCA, error) {
+	return typedEntityFromBody("GetEnterpriseCaEntity", response.StatusCode(), response.Body, NewADEnterpriseCA)
+}
+
+func ADRootCAFromResponse(response *GetRootCaEntityResponse) (*ADRootCA, error) {
+	return typedEntityFromBody("GetRootCaEntity", response.StatusCode(), response.Body, NewADRootCA)
+}
+
+func ADAIACAFromResponse(response *GetAiaCaEntityResponse) (*ADAIACA, error) {
+	return typedEntityFromBody("GetAiaCaEntity", response.StatusCode(), response.Body, NewADAIACA)
+}
+
+func ADNTAuthStoreFromResponse(response *GetNtAuthStoreEntityResponse) (*ADNTAuthStore, error) {
+	return typedEntityFromBody("GetNtAuthStoreEntity", response.StatusCode(), response.Body, NewADNTAuthStore)
+}
@@ -0,0 +1,357 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+// Node kinds of the Azure graph
+const (
+	KindAZBase             = "AZBase"
+	KindAZUser             = "AZUser"
+	KindAZGroup            = "AZGroup"
+	KindAZServicePrincipal = "AZServicePrincipal"
+	KindAZApp              = "AZApp"
+	KindAZDevice           = "AZDevice"
+	KindAZTenant           = "AZTenant"
+	KindAZSubscription     = "AZSubscription"
+	KindAZResourceGroup    = "AZResourceGroup"
+	KindAZVM               = "AZVM"
+	KindAZKeyVault         = "AZKeyVault"
+	KindAZRole             = "AZRole"
+	KindAZManagementGroup  = "AZManagementGroup"
+)
+
+// AZBase holds the properties shared by every Azure node. Extras keeps the properties that have no typed field.
+type AZBase struct {
+	ObjectID    string                 `json:"objectid"`
+	Name        string                 `json:"name"`
+	DisplayName string                 `json:"displayname,omitempty"`
+	TenantID    string                 `json:"tenantid,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	WhenCreated *EntityTime            `json:"whencreated,omitempty"`
+	LastSeen    *EntityTime            `json:"lastseen,omitempty"`
+	SystemTags  string                 `json:"system_tags,omitempty"`
+	IsTierZero  bool                   `json:"is_tier_zero"`
+	Extras      map[string]interface{} `json:"extras,omitempty"`
+}
+
+func (AZBase) Kind() string { return KindAZBase }
+
+func readAZBase(r *propertyReader) AZBase {
+	base := AZBase{
+		ObjectID:    r.string("objectid"),
+		Name:        r.string("name"),
+		DisplayName: r.string("displayname"),
+		TenantID:    r.string("tenantid"),
+		Description: r.string("description"),
+		WhenCreated: r.time("whencreated"),
+		LastSeen:    r.time("lastseen"),
+		SystemTags:  r.string("system_tags"),
+	}
+	base.IsTierZero = hasSystemTag(base.SystemTags, "admin_tier_0")
+	return base
+}
+
+type AZUser struct {
+	AZBase
+	UserPrincipalName string `json:"userprincipalname,omitempty"`
+	OnPremID          string `json:"onpremid,omitempty"`
+	OnPremSyncEnabled bool   `json:"onpremsyncenabled"`
+	UserType          string `json:"usertype,omitempty"`
+	Title             string `json:"title,omitempty"`
+	Email             string `json:"email,omitempty"`
+	Enabled           bool   `json:"enabled"`
+}
+
+func (AZUser) Kind() string { return KindAZUser }
+
+func NewAZUser(properties EntityProperties) *AZUser {
+	r := newPropertyReader(properties)
+	user := &AZUser{
+		AZBase:            readAZBase(r),
+		UserPrincipalName: r.string("userprincipalname"),
+		OnPremID:          r.string("onpremid"),
+		OnPremSyncEnabled: r.bool("onpremsyncenabled"),
+		UserType:          r.string("usertype"),
+		Title:             r.string("title"),
+		Email:             r.string("email"),
+		Enabled:           r.bool("enabled"),
+	}
+	user.Extras = r.extras()
+	return user
+}
+
+type AZGroup struct {
+	AZBase
+	IsAssignableToRole bool   `json:"isassignabletorole"`
+	SecurityEnabled    bool   `json:"securityenabled"`
+	SecurityIdentifier string `json:"securityidentifier,omitempty"`
+	OnPremID           string `json:"onpremid,omitempty"`
+	OnPremSyncEnabled  bool   `json:"onpremsyncenabled"`
+}
+
+func (AZGroup) Kind() string { return KindAZGroup }
+
+func NewAZGroup(properties EntityProperties) *AZGroup {
+	r := newPropertyReader(properties)
+	group := &AZGroup{
+		AZBase:             readAZBase(r),
+		IsAssignableToRole: r.bool("isassignabletorole"),
+		SecurityEnabled:    r.bool("securityenabled"),
+		SecurityIdentifier: r.string("securityidentifier"),
+		OnPremID:           r.string("onpremid"),
+		OnPremSyncEnabled:  r.bool("onpremsyncenabled"),
+	}
+	group.Extras = r.extras()
+	return group
+}
+
+type AZServicePrincipal struct {
+	AZBase
+	AppID                string   `json:"appid,omitempty"`
+	AppOwnerOrganization string   `json:"appownerorganizationid,omitempty"`
+	ServicePrincipalType string   `json:"serviceprincipaltype,omitempty"`
+	Enabled              bool     `json:"enabled"`
+	ServicePrincipalName []string `json:"serviceprincipalnames,omitempty"`
+}
+
+func (AZServicePrincipal) Kind() string { return KindAZServicePrincipal }
+
+func NewAZServicePrincipal(properties EntityProperties) *AZServicePrincipal {
+	r := newPropertyReader(properties)
+	principal := &AZServicePrincipal{
+		AZBase:               readAZBase(r),
+		AppID:                r.string("appid"),
+		AppOwnerOrganization: r.string("appownerorganizationid"),
+		ServicePrincipalType: r.string("serviceprincipaltype"),
+		Enabled:              r.bool("enabled"),
+		ServicePrincipalName: r.strings("serviceprincipalnames"),
+	}
+	principal.Extras = r.extras()
+	return principal
+}
+
+type AZApp struct {
+	AZBase
+	AppID           string `json:"appid,omitempty"`
+	PublisherDomain string `json:"publisherdomain,omitempty"`
+	SignInAudience  string `json:"signinaudience,omitempty"`
+}
+
+func (AZApp) Kind() string { return KindAZApp }
+
+func NewAZApp(properties EntityProperties) *AZApp {
+	r := newPropertyReader(properties)
+	app := &AZApp{
+		AZBase:          readAZBase(r),
+		AppID:           r.string("appid"),
+		PublisherDomain: r.string("publisherdomain"),
+		SignInAudience:  r.string("signinaudience"),
+	}
+	app.Extras = r.extras()
+	return app
+}
+
+type AZDevice struct {
+	AZBase
+	DeviceID               string `json:"deviceid,omitempty"`
+	OperatingSystem        string `json:"operatingsystem,omitempty"`
+	OperatingSystemVersion string `json:"operatingsystemversion,omitempty"`
+	TrustType              string `json:"trusttype,omitempty"`
+	MDMAppID               string `json:"mdmappid,omitempty"`
+}
+
+func (AZDevice) Kind() string { return KindAZDevice }
+
+func NewAZDevice(properties EntityProperties) *AZDevice {
+	r := newPropertyReader(properties)
+	device := &AZDevice{
+		AZBase:                 readAZBase(r),
+		DeviceID:               r.string("deviceid"),
+		OperatingSystem:        r.string("operatingsystem"),
+		OperatingSystemVersion: r.string("operatingsystemversion"),
+		TrustType:              r.string("trusttype"),
+		MDMAppID:               r.string("mdmappid"),
+	}
+	device.Extras = r.extras()
+	return device
+}
+
+type AZTenant struct {
+	AZBase
+}
+
+func (AZTenant) Kind() string { return KindAZTenant }
+
+func NewAZTenant(properties EntityProperties) *AZTenant {
+	r := newPropertyReader(properties)
+	tenant := &AZTenant{
+		AZBase: readAZBase(r),
+	}
+	tenant.Extras = r.extras()
+	return tenant
+}
+
+type AZSubscription struct {
+	AZBase
+}
+
+func (AZSubscription) Kind() string { return KindAZSubscription }
+
+func NewAZSubscription(properties EntityProperties) *AZSubscription {
+	r := newPropertyReader(properties)
+	subscription := &AZSubscription{
+		AZBase: readAZBase(r),
+	}
+	subscription.Extras = r.extras()
+	return subscription
+}
+
+type AZResourceGroup struct {
+	AZBase
+}
+
+func (AZResourceGroup) Kind() string { return KindAZResourceGroup }
+
+func NewAZResourceGroup(properties EntityProperties) *AZResourceGroup {
+	r := newPropertyReader(properties)
+	group := &AZResourceGroup{
+		AZBase: readAZBase(r),
+	}
+	group.Extras = r.extras()
+	return group
+}
+
+type AZVM struct {
+	AZBase
+	OperatingSystem string `json:"operatingsystem,omitempty"`
+}
+
+func (AZVM) Kind() string { return KindAZVM }
+
+func NewAZVM(properties EntityProperties) *AZVM {
+	r := newPropertyReader(properties)
+	vm := &AZVM{
+		AZBase:          readAZBase(r),
+		OperatingSystem: r.string("operatingsystem"),
+	}
+	vm.Extras = r.extras()
+	return vm
+}
+
+type AZKeyVault struct {
+	AZBase
+	EnableRBACAuthorization bool `json:"enablerbacauthorization"`
+}
+
+func (AZKeyVault) Kind() string { return KindAZKeyVault }
+
+func NewAZKeyVault(properties EntityProperties) *AZKeyVault {
+	r := newPropertyReader(properties)
+	vault := &AZKeyVault{
+		AZBase:                  readAZBase(r),
+		EnableRBACAuthorization: r.bool("enablerbacauthorization"),
+	}
+	vault.Extras = r.extras()
+	return vault
+}
+
+type AZRole struct {
+	AZBase
+	TemplateID     string `json:"templateid,omitempty"`
+	RoleTemplateID string `json:"roletemplateid,omitempty"`
+	IsBuiltIn      bool   `json:"isbuiltin"`
+}
+
+func (AZRole) Kind() string { return KindAZRole }
+
+func NewAZRole(properties EntityProperties) *AZRole {
+	r := newPropertyReader(properties)
+	role := &AZRole{
+		AZBase:         readAZBase(r),
+		TemplateID:     r.string("templateid"),
+		RoleTemplateID: r.string("roletemplateid"),
+		IsBuiltIn:      r.bool("isbuiltin"),
+	}
+	role.Extras = r.extras()
+	return role
+}
+
+type AZManagementGroup struct {
+	AZBase
+}
+
+func (AZManagementGroup) Kind() string { return KindAZManagementGroup }
+
+func NewAZManagementGroup(properties EntityProperties) *AZManagementGroup {
+	r := newPropertyReader(properties)
+	group := &AZManagementGroup{
+		AZBase: readAZBase(r),
+	}
+	group.Extras = r.extras()
+	return group
+}
+
+// AZEntity is any typed Azure node
+type AZEntity interface {
+	EntityModel
+}
+
+// NewAZEntity converts a property map to the typed node of the given kind. Kinds without a typed model are
+// returned as *AZBase.
+func NewAZEntity(kind string, properties EntityProperties) AZEntity {
+	switch kind {
+	case KindAZUser:
+		return NewAZUser(properties)
+	case KindAZGroup:
+		return NewAZGroup(properties)
+	case KindAZServicePrincipal:
+		return NewAZServicePrincipal(properties)
+	case KindAZApp:
+		return NewAZApp(properties)
+	case KindAZDevice:
+		return NewAZDevice(properties)
+	case KindAZTenant:
+		return NewAZTenant(properties)
+	case KindAZSubscription:
+		return NewAZSubscription(properties)
+	case KindAZResourceGroup:
+		return NewAZResourceGroup(properties)
+	case KindAZVM:
+		return NewAZVM(properties)
+	case KindAZKeyVault:
+		return NewAZKeyVault(properties)
+	case KindAZRole:
+		return NewAZRole(properties)
+	case KindAZManagementGroup:
+		return NewAZManagementGroup(properties)
+	}
+	r := newPropertyReader(properties)
+	base := readAZBase(r)
+	base.Extras = r.extras()
+	return &base
+}
+
+// AZEntityFromResponse converts a GetAzureEntity response to the typed node named by its kind
+func AZEntityFromResponse(response *GetAzureEntityResponse) (AZEntity, error) {
+	if response.StatusCode() != 200 {
+		return nil, newStatusError("GetAzureEntity", response.StatusCode(), response.Body)
+	}
+	entity, err := ParseEntityResponse(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	return NewAZEntity(entity.Kind, entity.Properties), nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEntityRelationsNameExistingOperations(t *testing.T) {
+	for _, relation := range adEntityRelations {
+		getter, ok := adEntityGetters[relation.kind]
+		if !ok {
+			t.Errorf("relation %s of kind %s has no entity getter", relation.relation, relation.kind)
+			continue
+		}
+		if _, ok := Operations[getter.operation+relation.relation]; !ok {
+			t.Errorf("relation %s of kind %s names no operation", relation.relation, relation.kind)
+		}
+	}
+}
+
+func TestEntityListingsSignPage(t *testing.T) {
+	client := signedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if query := r.URL.Query(); query.Get("skip") != "25" || query.Get("limit") != "50" {
+			t.Errorf("%s listed with query %q, want skip=25 and limit=50", r.URL.Path, r.URL.RawQuery)
+		}
+	}))
+	fetches := map[string]entityListingFetch{
+		"GetEntityControllers": entityListing(ClientInterface.GetEntityControllers),
+	}
+	for _, relation := range adEntityRelations {
+		fetches[adEntityGetters[relation.kind].operation+relation.relation] = relation.fetch
+	}
+	for operation, fetch := range fetches {
+		response, err := fetch(client, context.Background(), "S-1-5-21-1-1105", 25, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("%s answered %d, want a verified request", operation, response.StatusCode)
+		}
+	}
+}
+
+func TestEntityRelatedPagesThroughListing(t *testing.T) {
+	const objectId = "S-1-5-21-1-1105"
+	total := relatedEntityPageSize + 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/users/" + objectId:
+			fmt.Fprintf(w, `{"data":{"props":{"objectid":%q,"name":"ALICE@CORP.LOCAL"},"adminRights":%d}}`, objectId, total)
+		case "/api/v2/users/" + objectId + "/admin-rights":
+			skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			var items []string
+			for i := skip; i < total && i < skip+limit; i++ {
+				items = append(items, fmt.Sprintf(`{"objectID":"S-1-5-21-1-%d","name":"COMPUTER%d","label":"Computer"}`, 2000+i, i))
+			}
+			fmt.Fprintf(w, `{"count":%d,"data":[%s]}`, total, strings.Join(items, ","))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entity, err := client.EntityOfKind(context.Background(), KindUser, objectId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entity.Model().(*ADUser); !ok {
+		t.Fatalf("model is %T, want *ADUser", entity.Model())
+	}
+	related, err := entity.Related(context.Background(), "admin-rights")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(related) != total || related[total-1].Name != fmt.Sprintf("COMPUTER%d", total-1) {
+		t.Fatalf("got %d related entities, want %d ending with COMPUTER%d", len(related), total, total-1)
+	}
+
+	if _, err := entity.Related(context.Background(), "sessions"); err == nil || !strings.Contains(err.Error(), "GetUserEntitySessions") {
+		t.Errorf("a failed listing returned %v, want an error naming GetUserEntitySessions", err)
+	}
+}
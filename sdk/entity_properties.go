@@ -0,0 +1,254 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EntityProperties is the schema-less property map of a graph node
+type EntityProperties map[string]interface{}
+
+// EntityResponse is the decoded body of an entity endpoint. AD endpoints return the node properties under
+// "props" while the Azure endpoint returns them under "properties" next to the node kind. Every other key of
+// the data object is a related entity count.
+type EntityResponse struct {
+	Kind       string
+	Properties EntityProperties
+	Counts     map[string]int64
+}
+
+// ParseEntityResponse decodes the body of a Get*Entity or GetAzureEntity response. The generated JSON200 field
+// types the properties as objects, which does not hold for real nodes, so the raw body is used instead.
+func ParseEntityResponse(body []byte) (*EntityResponse, error) {
+	var envelope struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("error decoding entity response: %w", err)
+	}
+	if envelope.Data == nil {
+		return nil, errors.New("entity response has no data")
+	}
+
+	entity := &EntityResponse{
+		Properties: EntityProperties{},
+		Counts:     map[string]int64{},
+	}
+	for key, value := range envelope.Data {
+		var err error
+		switch key {
+		case "kind":
+			err = json.Unmarshal(value, &entity.Kind)
+		case "props", "properties":
+			err = json.Unmarshal(value, &entity.Properties)
+		default:
+			var count int64
+			if json.Unmarshal(value, &count) == nil {
+				entity.Counts[key] = count
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding entity %s: %w", key, err)
+		}
+	}
+	return entity, nil
+}
+
+// propertyReader reads typed values from a property map and remembers which keys were consumed so the rest
+// can be kept as extras
+type propertyReader struct {
+	properties EntityProperties
+	used       map[string]struct{}
+}
+
+func newPropertyReader(properties EntityProperties) *propertyReader {
+	return &propertyReader{
+		properties: properties,
+		used:       map[string]struct{}{},
+	}
+}
+
+func (r *propertyReader) get(key string) (interface{}, bool) {
+	r.used[key] = struct{}{}
+	value, ok := r.properties[key]
+	return value, ok && value != nil
+}
+
+func (r *propertyReader) string(key string) string {
+	value, ok := r.get(key)
+	if !ok {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+func (r *propertyReader) bool(key string) bool {
+	value, ok := r.get(key)
+	if !ok {
+		return false
+	}
+	switch typed := value.(type) {
+	case bool:
+		return typed
+	case string:
+		parsed, _ := strconv.ParseBool(typed)
+		return parsed
+	case float64:
+		return typed != 0
+	}
+	return false
+}
+
+func (r *propertyReader) int64(key string) int64 {
+	value, ok := r.get(key)
+	if !ok {
+		return 0
+	}
+	switch typed := value.(type) {
+	case float64:
+		return int64(typed)
+	case string:
+		parsed, _ := strconv.ParseInt(typed, 10, 64)
+		return parsed
+	}
+	return 0
+}
+
+func (r *propertyReader) strings(key string) []string {
+	value, ok := r.get(key)
+	if !ok {
+		return nil
+	}
+	switch typed := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(typed))
+		for _, item := range typed {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	case string:
+		if typed == "" {
+			return nil
+		}
+		return []string{typed}
+	}
+	return nil
+}
+
+// EntityTime is a timestamp property of a node. BloodHound stores 0 when the timestamp is unset, which is
+// read as a nil *EntityTime, and -1 when the event never happened, such as a user that never logged on, which
+// is read as Never with a zero Time.
+type EntityTime struct {
+	time.Time
+	Never bool
+}
+
+// String returns "never" or the time in RFC 3339
+func (t EntityTime) String() string {
+	if t.Never {
+		return "never"
+	}
+	return t.Time.Format(time.RFC3339Nano)
+}
+
+// MarshalJSON encodes never as the string "never" and other times as RFC 3339 strings
+func (t EntityTime) MarshalJSON() ([]byte, error) {
+	if t.Never {
+		return []byte(`"never"`), nil
+	}
+	return t.Time.MarshalJSON()
+}
+
+func (t *EntityTime) UnmarshalJSON(data []byte) error {
+	if string(data) == `"never"` {
+		*t = EntityTime{Never: true}
+		return nil
+	}
+	*t = EntityTime{}
+	return t.Time.UnmarshalJSON(data)
+}
+
+// Timestamps are epoch seconds, where BloodHound uses 0 for unset and -1 for never, or RFC 3339 strings
+func (r *propertyReader) time(key string) *EntityTime {
+	value, ok := r.get(key)
+	if !ok {
+		return nil
+	}
+	return parseEntityTime(value)
+}
+
+func parseEntityTime(value interface{}) *EntityTime {
+	switch typed := value.(type) {
+	case float64:
+		switch {
+		case typed == -1:
+			return &EntityTime{Never: true}
+		case typed <= 0:
+			return nil
+		}
+		seconds, fraction := math.Modf(typed)
+		return &EntityTime{Time: time.Unix(int64(seconds), int64(fraction*1e9)).UTC()}
+	case string:
+		if typed == "" {
+			return nil
+		}
+		if parsed, err := time.Parse(time.RFC3339Nano, typed); err == nil {
+			if parsed.IsZero() {
+				return nil
+			}
+			return &EntityTime{Time: parsed}
+		}
+		if seconds, err := strconv.ParseFloat(typed, 64); err == nil {
+			return parseEntityTime(seconds)
+		}
+	}
+	return nil
+}
+
+// Return the properties that were not read into a typed field
+func (r *propertyReader) extras() map[string]interface{} {
+	extras := map[string]interface{}{}
+	for key, value := range r.properties {
+		if _, ok := r.used[key]; !ok {
+			extras[key] = value
+		}
+	}
+	if len(extras) == 0 {
+		return nil
+	}
+	return extras
+}
+
+// Tier zero membership is carried in the space separated system_tags property
+func hasSystemTag(tags, tag string) bool {
+	for _, field := range strings.Fields(tags) {
+		if field == tag {
+			return true
+		}
+	}
+	return false
+}
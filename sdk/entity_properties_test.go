@@ -0,0 +1,64 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseEntityTimeDistinguishesNeverFromUnset(t *testing.T) {
+	for _, test := range []struct {
+		value interface{}
+		want  string
+	}{
+		{value: float64(0), want: "unset"},
+		{value: "", want: "unset"},
+		{value: "0001-01-01T00:00:00Z", want: "unset"},
+		{value: float64(-1), want: "never"},
+		{value: "-1", want: "never"},
+		{value: float64(1700000000), want: "2023-11-14T22:13:20Z"},
+		{value: "1700000000.5", want: "2023-11-14T22:13:20.5Z"},
+		{value: "2023-11-14T22:13:20Z", want: "2023-11-14T22:13:20Z"},
+	} {
+		got := "unset"
+		if parsed := parseEntityTime(test.value); parsed != nil {
+			got = parsed.String()
+		}
+		if got != test.want {
+			t.Errorf("%#v: got %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestEntityTimeJSONKeepsNever(t *testing.T) {
+	user := NewADUser(EntityProperties{"objectid": "S-1-5-21-1-1105", "lastlogon": float64(-1), "pwdlastset": float64(0)})
+	encoded, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ADUser
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.LastLogon == nil || !decoded.LastLogon.Never {
+		t.Errorf("lastlogon of never decoded from %s as %v", encoded, decoded.LastLogon)
+	}
+	if decoded.PasswordLastSet != nil {
+		t.Errorf("unset pwdlastset decoded from %s as %v", encoded, decoded.PasswordLastSet)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// relatedEntityLister fetches one page of a related entity listing
+type relatedEntityLister func(ctx context.Context, c *ClientWithResponses, objectId string, skip, limit int) (*RelatedEntityQueryResults, error)
+
+// entityListingFetch requests one page of a related entity listing of a node
+type entityListingFetch func(c *ClientWithResponses, ctx context.Context, objectId string, skip, limit int) (*http.Response, error)
+
+// Adapt a generated listing method, such as ClientInterface.GetUserEntityControllers. The listings only differ
+// by their parameter types, which all carry the page as skip and limit, so the page is decoded into whichever type
+// the listing takes and stays part of the query the client signs.
+func entityListing[P any](get func(ClientInterface, context.Context, PathObjectId, *P, ...RequestEditorFn) (*http.Response, error)) entityListingFetch {
+	return func(c *ClientWithResponses, ctx context.Context, objectId string, skip, limit int) (*http.Response, error) {
+		params := new(P)
+		if err := json.Unmarshal([]byte(fmt.Sprintf(`{"skip":%d,"limit":%d}`, skip, limit)), params); err != nil {
+			return nil, err
+		}
+		return get(c.ClientInterface, ctx, objectId, params)
+	}
+}
+
+// Turn a listing request into a lister, naming the operation in errors
+func entityListingLister(operation string, fetch entityListingFetch) relatedEntityLister {
+	return func(ctx context.Context, c *ClientWithResponses, objectId string, skip, limit int) (*RelatedEntityQueryResults, error) {
+		response, err := fetch(c, ctx, objectId, skip, limit)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		var results *RelatedEntityQueryResults
+		if response.StatusCode == http.StatusOK {
+			results = &RelatedEntityQueryResults{}
+			if err := json.Unmarshal(body, results); err != nil {
+				return nil, fmt.Errorf("error decoding %s: %w", operation, err)
+			}
+		}
+		return relatedEntityResults(operation, response.StatusCode, body, results)
+	}
+}
+
+// Related entity listings of the Active Directory kinds, named after the suffix of their operation id, such as
+// "Controllers" for GetUserEntityControllers
+var adEntityRelations = []struct {
+	kind     string
+	relation string
+	fetch    entityListingFetch
+}{
+	{KindUser, "AdminRights", entityListing(ClientInterface.GetUserEntityAdminRights)},
+	{KindUser, "ConstrainedDelegationRights", entityListing(ClientInterface.GetUserEntityConstrainedDelegationRights)},
+	{KindUser, "Controllables", entityListing(ClientInterface.GetUserEntityControllables)},
+	{KindUser, "Controllers", entityListing(ClientInterface.GetUserEntityControllers)},
+	{KindUser, "DcomRights", entityListing(ClientInterface.GetUserEntityDcomRights)},
+	{KindUser, "Membership", entityListing(ClientInterface.GetUserEntityMembership)},
+	{KindUser, "PsRemoteRights", entityListing(ClientInterface.GetUserEntityPsRemoteRights)},
+	{KindUser, "RdpRights", entityListing(ClientInterface.GetUserEntityRdpRights)},
+	{KindUser, "Sessions", entityListing(ClientInterface.GetUserEntitySessions)},
+	{KindUser, "SqlAdminRights", entityListing(ClientInterface.GetUserEntitySqlAdminRights)},
+	{KindComputer, "AdminRights", entityListing(ClientInterface.GetComputerEntityAdminRights)},
+	{KindComputer, "Admins", entityListing(ClientInterface.GetComputerEntityAdmins)},
+	{KindComputer, "ConstrainedDelegationRights", entityListing(ClientInterface.GetComputerEntityConstrainedDelegationRights)},
+	{KindComputer, "ConstrainedUsers", entityListing(ClientInterface.GetComputerEntityConstrainedUsers)},
+	{KindComputer, "Controllables", entityListing(ClientInterface.GetComputerEntityControllables)},
+	{KindComputer, "Controllers", entityListing(ClientInterface.GetComputerEntityControllers)},
+	{KindComputer, "DcomRights", entityListing(ClientInterface.GetComputerEntityDcomRights)},
+	{KindComputer, "DcomUsers", entityListing(ClientInterface.GetComputerEntityDcomUsers)},
+	{KindComputer, "GroupMembership", entityListing(ClientInterface.GetComputerEntityGroupMembership)},
+	{KindComputer, "PsRemoteRights", entityListing(ClientInterface.GetComputerEntityPsRemoteRights)},
+	{KindComputer, "PsRemoteUsers", entityListing(ClientInterface.GetComputerEntityPsRemoteUsers)},
+	{KindComputer, "RdpRights", entityListing(ClientInterface.GetComputerEntityRdpRights)},
+	{KindComputer, "RdpUsers", entityListing(ClientInterface.GetComputerEntityRdpUsers)},
+	{KindComputer, "Sessions", entityListing(ClientInterface.GetComputerEntitySessions)},
+	{KindComputer, "SqlAdmins", entityListing(ClientInterface.GetComputerEntitySqlAdmins)},
+	{KindGroup, "AdminRights", entityListing(ClientInterface.GetGroupEntityAdminRights)},
+	{KindGroup, "Controllables", entityListing(ClientInterface.GetGroupEntityControllables)},
+	{KindGroup, "Controllers", entityListing(ClientInterface.GetGroupEntityControllers)},
+	{KindGroup, "DcomRights", entityListing(ClientInterface.GetGroupEntityDcomRights)},
+	{KindGroup, "Members", entityListing(ClientInterface.GetGroupEntityMembers)},
+	{KindGroup, "Memberships", entityListing(ClientInterface.GetGroupEntityMemberships)},
+	{KindGroup, "PsRemoteRights", entityListing(ClientInterface.GetGroupEntityPsRemoteRights)},
+	{KindGroup, "RdpRights", entityListing(ClientInterface.GetGroupEntityRdpRights)},
+	{KindGroup, "Sessions", entityListing(ClientInterface.GetGroupEntitySessions)},
+	{KindDomain, "Computers", entityListing(ClientInterface.GetDomainEntityComputers)},
+	{KindDomain, "Controllers", entityListing(ClientInterface.GetDomainEntityControllers)},
+	{KindDomain, "DcSyncers", entityListing(ClientInterface.GetDomainEntityDcSyncers)},
+	{KindDomain, "ForeignAdmins", entityListing(ClientInterface.GetDomainEntityForeignAdmins)},
+	{KindDomain, "ForeignGpoControllers", entityListing(ClientInterface.GetDomainEntityForeignGpoControllers)},
+	{KindDomain, "ForeignGroups", entityListing(ClientInterface.GetDomainEntityForeignGroups)},
+	{KindDomain, "ForeignUsers", entityListing(ClientInterface.GetDomainEntityForeignUsers)},
+	{KindDomain, "Gpos", entityListing(ClientInterface.GetDomainEntityGpos)},
+	{KindDomain, "Groups", entityListing(ClientInterface.GetDomainEntityGroups)},
+	{KindDomain, "InboundTrusts", entityListing(ClientInterface.GetDomainEntityInboundTrusts)},
+	{KindDomain, "LinkedGpos", entityListing(ClientInterface.GetDomainEntityLinkedGpos)},
+	{KindDomain, "Ous", entityListing(ClientInterface.GetDomainEntityOus)},
+	{KindDomain, "OutboundTrusts", entityListing(ClientInterface.GetDomainEntityOutboundTrusts)},
+	{KindDomain, "Users", entityListing(ClientInterface.GetDomainEntityUsers)},
+	{KindOU, "Computers", entityListing(ClientInterface.GetOuEntityComputers)},
+	{KindOU, "Gpos", entityListing(ClientInterface.GetOuEntityGpos)},
+	{KindOU, "Groups", entityListing(ClientInterface.GetOuEntityGroups)},
+	{KindOU, "Users", entityListing(ClientInterface.GetOuEntityUsers)},
+	{KindGPO, "Computers", entityListing(ClientInterface.GetGpoEntityComputers)},
+	{KindGPO, "Controllers", entityListing(ClientInterface.GetGpoEntityControllers)},
+	{KindGPO, "Ous", entityListing(ClientInterface.GetGpoEntityOus)},
+	{KindGPO, "TierZero", entityListing(ClientInterface.GetGpoEntityTierZero)},
+	{KindGPO, "Users", entityListing(ClientInterface.GetGpoEntityUsers)},
+	{KindContainer, "Controllers", entityListing(ClientInterface.GetContainerEntityControllers)},
+	{KindCertTemplate, "Controllers", entityListing(ClientInterface.GetCertTemplateEntityControllers)},
+	{KindEnterpriseCA, "Controllers", entityListing(ClientInterface.GetEnterpriseCaEntityControllers)},
+	{KindRootCA, "Controllers", entityListing(ClientInterface.GetRootCaEntityControllers)},
+	{KindAIACA, "Controllers", entityListing(ClientInterface.GetAiaCaEntityControllers)},
+	{KindNTAuthStore, "Controllers", entityListing(ClientInterface.GetNtAuthStoreEntityControllers)},
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Job states, named after the values documented for EnumJobStatus
+const (
+	JobStatusInvalid           EnumJobStatus = -1
+	JobStatusReady             EnumJobStatus = 0
+	JobStatusRunning           EnumJobStatus = 1
+	JobStatusComplete          EnumJobStatus = 2
+	JobStatusCanceled          EnumJobStatus = 3
+	JobStatusTimedOut          EnumJobStatus = 4
+	JobStatusFailed            EnumJobStatus = 5
+	JobStatusIngesting         EnumJobStatus = 6
+	JobStatusAnalyzing         EnumJobStatus = 7
+	JobStatusPartiallyComplete EnumJobStatus = 8
+)
+
+var jobStatusNames = map[EnumJobStatus]string{
+	JobStatusInvalid:           "invalid",
+	JobStatusReady:             "ready",
+	JobStatusRunning:           "running",
+	JobStatusComplete:          "complete",
+	JobStatusCanceled:          "canceled",
+	JobStatusTimedOut:          "timed out",
+	JobStatusFailed:            "failed",
+	JobStatusIngesting:         "ingesting",
+	JobStatusAnalyzing:         "analyzing",
+	JobStatusPartiallyComplete: "partially complete",
+}
+
+func (s EnumJobStatus) String() string {
+	if name, ok := jobStatusNames[s]; ok {
+		return name
+	}
+	return strconv.Itoa(int(s))
+}
+
+// Finished reports whether a job in this state will not change any more
+func (s EnumJobStatus) Finished() bool {
+	switch s {
+	case JobStatusComplete, JobStatusCanceled, JobStatusTimedOut, JobStatusFailed, JobStatusPartiallyComplete:
+		return true
+	}
+	return false
+}
+
+// UploadContentType returns the content type of a collector output file, which must be a .zip or .json file
+func UploadContentType(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip":
+		return "application/zip", nil
+	case ".json":
+		return "application/json", nil
+	default:
+		return "", fmt.Errorf("%s: only .zip and .json files can be uploaded", path)
+	}
+}
+
+// UploadFiles ingests collector output files as one file upload job and returns the job id. When uploaded is not
+// nil it is called with the path of each file once the server accepted it.
+func (c *ClientWithResponses) UploadFiles(ctx context.Context, paths []string, uploaded func(path string)) (int64, error) {
+	return uploadFiles(ctx, c, paths, uploaded)
+}
+
+func uploadFiles(ctx context.Context, client ClientWithResponsesInterface, paths []string, uploaded func(path string)) (int64, error) {
+	if len(paths) == 0 {
+		return 0, fmt.Errorf("no files to upload")
+	}
+	for _, path := range paths {
+		if _, err := UploadContentType(path); err != nil {
+			return 0, err
+		}
+	}
+
+	created, err := client.CreateFileUploadJobWithResponse(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	if created.StatusCode() != http.StatusCreated || created.JSON201 == nil || created.JSON201.Data == nil || created.JSON201.Data.Id == nil {
+		return 0, newStatusError("CreateFileUploadJob", created.StatusCode(), created.Body)
+	}
+	jobId := *created.JSON201.Data.Id
+
+	for _, path := range paths {
+		if err := uploadFile(ctx, client, jobId, path); err != nil {
+			return jobId, fmt.Errorf("error uploading %s to file upload job %d: %w", path, jobId, err)
+		}
+		if uploaded != nil {
+			uploaded(path)
+		}
+	}
+
+	ended, err := client.EndFileUploadJobWithResponse(ctx, jobId, nil)
+	if err != nil {
+		return jobId, err
+	}
+	if ended.StatusCode() != http.StatusOK {
+		return jobId, newStatusError("EndFileUploadJob", ended.StatusCode(), ended.Body)
+	}
+	return jobId, nil
+}
+
+func uploadFile(ctx context.Context, client ClientWithResponsesInterface, jobId int64, path string) error {
+	contentType, err := UploadContentType(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	response, err := client.UploadFileToJobWithBodyWithResponse(ctx, jobId, &UploadFileToJobParams{ContentType: contentType}, contentType, file)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != http.StatusAccepted {
+		return newStatusError("UploadFileToJob", response.StatusCode(), response.Body)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploadFilesReportsEachAcceptedFile(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"users.json", "computers.json", "groups.json"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(`{"data":[]}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/file-upload/start":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"data":{"id":42}}`)
+		case "/api/v2/file-upload/42":
+			// The second file is rejected
+			if received++; received == 2 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		case "/api/v2/file-upload/42/end":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var uploaded []string
+	jobId, err := client.UploadFiles(context.Background(), paths, func(path string) {
+		uploaded = append(uploaded, filepath.Base(path))
+	})
+	if err == nil || !strings.Contains(err.Error(), "computers.json") {
+		t.Fatalf("got %v, want an error naming computers.json", err)
+	}
+	if jobId != 42 {
+		t.Errorf("got job %d, want 42", jobId)
+	}
+	if strings.Join(uploaded, ",") != "users.json" {
+		t.Errorf("reported %v as uploaded, want only users.json", uploaded)
+	}
+}
@@ -22,27 +22,111 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"io"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
+// HMACToken is an API token key and id pair
+type HMACToken struct {
+	Key string
+	ID  string
+}
+
+// HMACCredentialsProvider supplies the token used to sign a request. It is consulted once per request, so
+// implementations may swap the token at any time.
+type HMACCredentialsProvider interface {
+	Token(ctx context.Context) (HMACToken, error)
+}
+
 type HMACCredentials struct {
 	TokenKey string
 	TokenID  string
+
+	// Provider, when set, is used instead of TokenKey and TokenID
+	Provider HMACCredentialsProvider
+
+	// Now is the clock requests are signed with. Defaults to time.Now. The offset learnt by ClockSkewDoer is
+	// applied on top of it.
+	Now func() time.Time
+
+	// Offset between the server clock and Now, shared by copies of the credentials. Credentials built as a
+	// struct literal get it from ClockSkewDoer or SetClockOffset; call one of them before signing concurrently.
+	clockOffset *hmacClockOffset
+}
+
+type hmacClockOffset struct {
+	nanoseconds atomic.Int64
 }
 
 func NewSecurityProviderHMACCredentials(token string, token_id string) (*HMACCredentials, error) {
 	return &HMACCredentials{
-		TokenKey: token,
-		TokenID:  token_id,
+		TokenKey:    token,
+		TokenID:     token_id,
+		clockOffset: &hmacClockOffset{},
+	}, nil
+}
+
+func NewSecurityProviderHMACCredentialsFromProvider(provider HMACCredentialsProvider) (*HMACCredentials, error) {
+	if provider == nil {
+		return nil, errors.New("credentials provider must not be nil")
+	}
+	return &HMACCredentials{
+		Provider:    provider,
+		clockOffset: &hmacClockOffset{},
 	}, nil
 }
 
+// Token returns the token the next request will be signed with
+func (c *HMACCredentials) Token(ctx context.Context) (HMACToken, error) {
+	if c.Provider != nil {
+		return c.Provider.Token(ctx)
+	}
+	return HMACToken{Key: c.TokenKey, ID: c.TokenID}, nil
+}
+
 // Based on python example
 func (c *HMACCredentials) Intercept(ctx context.Context, req *http.Request) error {
+	// The token is read once so the whole signature chain uses the same key even if it is swapped meanwhile
+	token, err := c.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Format the current time as RFC3339
+	datetimeFormatted := c.signingTime().Format(time.RFC3339)
+
+	var bodyBytes []byte
+	if req.Body != nil {
+		bodyBytes, err = io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+
+		// Interceptors modify request in place (sigh)
+		req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(bodyBytes)), nil
+		}
+	}
+	signature := hmacSignature(token.Key, req.Method, req.URL.RequestURI(), datetimeFormatted, bodyBytes)
+
+	// Perform the request with the signed and expected headers
+	req.Header.Set("User-Agent", "bhe-go-sdk 0001")
+	req.Header.Set("Authorization", "bhesignature "+token.ID)
+	req.Header.Set("RequestDate", datetimeFormatted)
+	req.Header.Set("Signature", base64.StdEncoding.EncodeToString(signature))
+
+	return nil
+}
+
+// Compute the bhesignature chain for a request. Both the client side Intercept and the server side verifier
+// build the signature here.
+func hmacSignature(tokenKey, method, requestURI, datetimeFormatted string, body []byte) []byte {
 	// Digester is initialized with HMAC-SHA-256 using the token key as the HMAC digest key.
-	digester := hmac.New(sha256.New, []byte(c.TokenKey))
+	digester := hmac.New(sha256.New, []byte(tokenKey))
 
 	// OperationKey is the first HMAC digest link in the signature chain. This prevents replay attacks that seek to
 	// modify the request method or URI. It is composed of concatenating the request method and the request URI with
@@ -50,7 +134,7 @@ func (c *HMACCredentials) Intercept(ctx context.Context, req *http.Request) erro
 	//
 	// Example: GET /api/v2/test/resource HTTP/1.1
 	// Signature Component: GET/api/v2/test/resource
-	digester.Write([]byte(req.Method + req.URL.RequestURI()))
+	digester.Write([]byte(method + requestURI))
 
 	// Update the digester for further chaining
 	digester = hmac.New(sha256.New, digester.Sum(nil))
@@ -62,8 +146,6 @@ func (c *HMACCredentials) Intercept(ctx context.Context, req *http.Request) erro
 	//
 	// Example: 2020-12-01T23:59:60Z
 	// Signature Component: 2020-12-01T23
-	// Format the current time as RFC3339
-	datetimeFormatted := time.Now().UTC().Format(time.RFC3339)
 	digester.Write([]byte(datetimeFormatted[:13]))
 
 	// Update the digester for further chaining
@@ -73,23 +155,133 @@ func (c *HMACCredentials) Intercept(ctx context.Context, req *http.Request) erro
 	// the signature to prevent replay attacks that seek to modify the payload of a signed request. In the case
 	// where there is no body content the HMAC digest is computed anyway, simply with no values written to the
 	// digester.
-	if req.Body != nil {
-		bodyBytes, err := io.ReadAll(req.Body)
+	digester.Write(body)
+
+	return digester.Sum(nil)
+}
+
+// ClockOffset returns the learnt difference between the server clock and the local clock
+func (c *HMACCredentials) ClockOffset() time.Duration {
+	if c.clockOffset == nil {
+		return 0
+	}
+	return time.Duration(c.clockOffset.nanoseconds.Load())
+}
+
+// SetClockOffset overrides the difference between the server clock and the local clock
+func (c *HMACCredentials) SetClockOffset(offset time.Duration) {
+	if c.clockOffset == nil {
+		c.clockOffset = &hmacClockOffset{}
+	}
+	c.clockOffset.nanoseconds.Store(int64(offset))
+}
+
+// ObserveServerTime updates the clock offset from a server timestamp observed at the given local time
+func (c *HMACCredentials) ObserveServerTime(serverTime, observedAt time.Time) {
+	c.SetClockOffset(serverTime.Sub(observedAt))
+}
+
+func (c *HMACCredentials) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *HMACCredentials) signingTime() time.Time {
+	return c.now().Add(c.ClockOffset()).UTC()
+}
+
+// ClockSkewDoer wraps next so the credentials learn the server clock offset from the Date header, or a
+// server set RequestDate header, of every response. When a request is rejected as Unauthorized and the hour it was signed for does not
+// match the server clock, it is re-signed with the corrected time and sent once more. Use it together with
+// Intercept:
+//
+//	NewClientWithResponses(server, WithRequestEditorFn(credentials.Intercept), WithHTTPClient(credentials.ClockSkewDoer(httpClient)))
+func (c *HMACCredentials) ClockSkewDoer(next HttpRequestDoer) HttpRequestDoer {
+	if next == nil {
+		next = http.DefaultClient
+	}
+	if c.clockOffset == nil {
+		c.clockOffset = &hmacClockOffset{}
+	}
+	return &hmacClockSkewDoer{
+		credentials: c,
+		next:        next,
+	}
+}
+
+type hmacClockSkewDoer struct {
+	credentials *HMACCredentials
+	next        HttpRequestDoer
+}
+
+func (d *hmacClockSkewDoer) Do(req *http.Request) (*http.Response, error) {
+	response, err := d.next.Do(req)
+	if err != nil {
+		return response, err
+	}
+
+	serverTime, ok := responseServerTime(req, response)
+	if ok {
+		d.credentials.ObserveServerTime(serverTime, d.credentials.now())
+	} else {
+		serverTime = d.credentials.signingTime()
+	}
+
+	if response.StatusCode != http.StatusUnauthorized || !signedForOtherHour(req, serverTime) {
+		return response, nil
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return response, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
 		if err != nil {
-			return err
+			return response, nil
 		}
+		retry.Body = body
+	}
+	if err := d.credentials.Intercept(req.Context(), retry); err != nil {
+		return response, nil
+	}
 
-		// Interceptors modify request in place (sigh)
-		req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
 
-		digester.Write(bodyBytes)
+	response, err = d.next.Do(retry)
+	if err == nil {
+		if serverTime, ok := responseServerTime(req, response); ok {
+			d.credentials.ObserveServerTime(serverTime, d.credentials.now())
+		}
 	}
+	return response, err
+}
 
-	// Perform the request with the signed and expected headers
-	req.Header.Set("User-Agent", "bhe-go-sdk 0001")
-	req.Header.Set("Authorization", "bhesignature "+c.TokenID)
-	req.Header.Set("RequestDate", datetimeFormatted)
-	req.Header.Set("Signature", base64.StdEncoding.EncodeToString(digester.Sum(nil)))
+// Read the server clock from the Date header of the response, falling back to a RequestDate header when the
+// server sets one that is not merely the value the request was signed with
+func responseServerTime(req *http.Request, response *http.Response) (time.Time, bool) {
+	if value := response.Header.Get("Date"); value != "" {
+		if serverTime, err := http.ParseTime(value); err == nil {
+			return serverTime, true
+		}
+	}
+	if value := response.Header.Get("RequestDate"); value != "" && value != req.Header.Get("RequestDate") {
+		if serverTime, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return serverTime, true
+		}
+	}
+	return time.Time{}, false
+}
 
-	return nil
+// A signature is only valid for the hour in its RequestDate, so a mismatch with the server hour is the tell of
+// a skewed clock or a request that crossed the hour boundary
+func signedForOtherHour(req *http.Request, serverTime time.Time) bool {
+	requestDate := req.Header.Get("RequestDate")
+	if len(requestDate) < 13 {
+		return false
+	}
+	return requestDate[:13] != serverTime.UTC().Format(time.RFC3339)[:13]
 }
@@ -0,0 +1,192 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadableHMACCredentials is a credentials provider whose token can be replaced while requests are being
+// signed. Requests already signed keep the old token, requests signed after Update use the new one.
+type ReloadableHMACCredentials struct {
+	token atomic.Pointer[HMACToken]
+}
+
+func NewReloadableHMACCredentials(key, id string) (*ReloadableHMACCredentials, error) {
+	credentials := &ReloadableHMACCredentials{}
+	if err := credentials.Update(HMACToken{Key: key, ID: id}); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func (c *ReloadableHMACCredentials) Token(ctx context.Context) (HMACToken, error) {
+	token := c.token.Load()
+	if token == nil {
+		return HMACToken{}, errors.New("no HMAC token loaded")
+	}
+	return *token, nil
+}
+
+// Update swaps in a new token
+func (c *ReloadableHMACCredentials) Update(token HMACToken) error {
+	if token.Key == "" || token.ID == "" {
+		return errors.New("token key and id must not be empty")
+	}
+	c.token.Store(&token)
+	return nil
+}
+
+// HMACCredentialsFileWatcher reloads a ReloadableHMACCredentials whenever its file changes. The file is either
+// the JSON document written by FileTokenSecretSink or an env file as written by EnvFileTokenSecretSink. Changes
+// are detected by polling the modification time and size.
+type HMACCredentialsFileWatcher struct {
+	Path         string
+	PollInterval time.Duration
+
+	// KeyVar and IDVar name the variables of an env file, API_TOKEN_KEY and API_TOKEN_ID by default
+	KeyVar string
+	IDVar  string
+
+	// OnReload, when set, is called after every reload attempt with the error, if any. A failed reload keeps
+	// the previous token.
+	OnReload func(err error)
+
+	credentials *ReloadableHMACCredentials
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// Load the token from path and return a provider that follows the file once Watch is running
+func NewHMACCredentialsFileWatcher(path string) (*HMACCredentialsFileWatcher, error) {
+	return NewHMACCredentialsEnvFileWatcher(path, "", "")
+}
+
+// Like NewHMACCredentialsFileWatcher, for env files that name the token variables as an EnvFileTokenSecretSink
+// with the same KeyVar and IDVar writes them
+func NewHMACCredentialsEnvFileWatcher(path, keyVar, idVar string) (*HMACCredentialsFileWatcher, error) {
+	watcher := &HMACCredentialsFileWatcher{
+		Path:         path,
+		PollInterval: 5 * time.Second,
+		KeyVar:       keyVar,
+		IDVar:        idVar,
+		credentials:  &ReloadableHMACCredentials{},
+	}
+	if _, err := watcher.Reload(); err != nil {
+		return nil, err
+	}
+	return watcher, nil
+}
+
+func (w *HMACCredentialsFileWatcher) Token(ctx context.Context) (HMACToken, error) {
+	return w.credentials.Token(ctx)
+}
+
+// Reload the token if the file changed since the last load and report whether it did
+func (w *HMACCredentialsFileWatcher) Reload() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.Path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(w.Path)
+	if err != nil {
+		return false, err
+	}
+	token, err := parseHMACTokenFile(content, w.KeyVar, w.IDVar)
+	if err != nil {
+		return false, fmt.Errorf("error reading credentials file %s: %w", w.Path, err)
+	}
+	if err := w.credentials.Update(token); err != nil {
+		return false, err
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	return true, nil
+}
+
+// Poll the file until the context is cancelled
+func (w *HMACCredentialsFileWatcher) Watch(ctx context.Context) {
+	interval := w.PollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := w.Reload()
+			if w.OnReload != nil && (reloaded || err != nil) {
+				w.OnReload(err)
+			}
+		}
+	}
+}
+
+func parseHMACTokenFile(content []byte, keyVar, idVar string) (HMACToken, error) {
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		var secret TokenSecret
+		if err := json.Unmarshal(trimmed, &secret); err != nil {
+			return HMACToken{}, err
+		}
+		return HMACToken{Key: secret.TokenKey, ID: secret.TokenID}, nil
+	}
+
+	if keyVar == "" {
+		keyVar = "API_TOKEN_KEY"
+	}
+	if idVar == "" {
+		idVar = "API_TOKEN_ID"
+	}
+	var token HMACToken
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "export "), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(name) {
+		case keyVar:
+			token.Key = value
+		case idVar:
+			token.ID = value
+		}
+	}
+	return token, scanner.Err()
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestHMACCredentialsEnvFileWatcherReadsCustomVariables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bloodhound.env")
+	sink := &EnvFileTokenSecretSink{Path: path, KeyVar: "BHE_TOKEN_KEY", IDVar: "BHE_TOKEN_ID"}
+	if err := sink.Store(context.Background(), TokenSecret{TokenKey: "key", TokenID: "id"}); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher, err := NewHMACCredentialsEnvFileWatcher(path, sink.KeyVar, sink.IDVar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := watcher.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Key != "key" || token.ID != "id" {
+		t.Fatalf("loaded token %+v, want the key and id written by the sink", token)
+	}
+
+	if _, err := NewHMACCredentialsFileWatcher(path); err == nil {
+		t.Fatal("the default variable names loaded a file that does not use them")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// A server whose clock is ahead of the local one by skew, answering every request with status
+func skewedServer(local func() time.Time, skew time.Duration, status int, calls *[]*http.Request) doerFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		*calls = append(*calls, req)
+		header := http.Header{}
+		header.Set("Date", local().Add(skew).UTC().Format(http.TimeFormat))
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	}
+}
+
+func signedClockSkewRequest(t *testing.T, credentials *HMACCredentials, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "https://bloodhound.example.com/api/v2/ingest", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.Intercept(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestClockSkewDoerLearnsOffsetFromDate(t *testing.T) {
+	local := time.Date(2024, 5, 1, 10, 20, 0, 0, time.UTC)
+	credentials, _ := NewSecurityProviderHMACCredentials("key", "token")
+	credentials.Now = func() time.Time { return local }
+
+	var calls []*http.Request
+	doer := credentials.ClockSkewDoer(skewedServer(credentials.Now, 7*time.Minute, http.StatusOK, &calls))
+	if _, err := doer.Do(signedClockSkewRequest(t, credentials, "")); err != nil {
+		t.Fatal(err)
+	}
+	if offset := credentials.ClockOffset(); offset != 7*time.Minute {
+		t.Fatalf("learnt offset %s, want 7m", offset)
+	}
+	if got := signedClockSkewRequest(t, credentials, "").Header.Get("RequestDate"); got != "2024-05-01T10:27:00Z" {
+		t.Fatalf("next request signed at %s, want the server time", got)
+	}
+}
+
+func TestClockSkewDoerIgnoresEchoedRequestDate(t *testing.T) {
+	local := time.Date(2024, 5, 1, 10, 20, 0, 0, time.UTC)
+	credentials, _ := NewSecurityProviderHMACCredentials("key", "token")
+	credentials.Now = func() time.Time { return local }
+	credentials.SetClockOffset(time.Minute)
+
+	doer := credentials.ClockSkewDoer(doerFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("RequestDate", req.Header.Get("RequestDate"))
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}))
+	if _, err := doer.Do(signedClockSkewRequest(t, credentials, "")); err != nil {
+		t.Fatal(err)
+	}
+	if offset := credentials.ClockOffset(); offset != time.Minute {
+		t.Fatalf("offset changed to %s by an echoed RequestDate", offset)
+	}
+}
+
+func TestClockSkewDoerRetriesOnceForOtherHour(t *testing.T) {
+	local := time.Date(2024, 5, 1, 10, 59, 50, 0, time.UTC)
+	credentials, _ := NewSecurityProviderHMACCredentials("key", "token")
+	credentials.Now = func() time.Time { return local }
+
+	var calls []*http.Request
+	doer := credentials.ClockSkewDoer(skewedServer(credentials.Now, 30*time.Second, http.StatusUnauthorized, &calls))
+	response, err := doer.Do(signedClockSkewRequest(t, credentials, `{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusUnauthorized || len(calls) != 2 {
+		t.Fatalf("%d calls answered %d, want one retry", len(calls), response.StatusCode)
+	}
+	if hour := calls[1].Header.Get("RequestDate")[:13]; hour != "2024-05-01T11" {
+		t.Fatalf("retry signed for hour %s, want the server hour", hour)
+	}
+	if body, _ := io.ReadAll(calls[1].Body); string(body) != `{"a":1}` {
+		t.Fatalf("retry sent body %q", body)
+	}
+}
+
+func TestClockSkewDoerDoesNotRetryWithoutGetBody(t *testing.T) {
+	local := time.Date(2024, 5, 1, 10, 59, 50, 0, time.UTC)
+	credentials, _ := NewSecurityProviderHMACCredentials("key", "token")
+	credentials.Now = func() time.Time { return local }
+
+	var calls []*http.Request
+	doer := credentials.ClockSkewDoer(skewedServer(credentials.Now, 30*time.Second, http.StatusUnauthorized, &calls))
+	req := signedClockSkewRequest(t, credentials, `{"a":1}`)
+	req.GetBody = nil
+	if _, err := doer.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("%d calls, want no retry of a body that cannot be replayed", len(calls))
+	}
+}
+
+func TestHMACCredentialsCopiesShareClockOffset(t *testing.T) {
+	credentials, _ := NewSecurityProviderHMACCredentials("key", "token")
+	copied := *credentials
+	credentials.SetClockOffset(3 * time.Second)
+	if copied.ClockOffset() != 3*time.Second {
+		t.Fatal("a copy of the credentials does not see the learnt offset")
+	}
+}
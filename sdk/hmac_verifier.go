@@ -0,0 +1,189 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// Signatures older or newer than this relative to the verifier clock are rejected
+	hmacReplayWindow = 2 * time.Hour
+
+	defaultHMACMaxBodyBytes = 10 << 20
+)
+
+var (
+	ErrHMACMissingAuthorization = errors.New("missing bhesignature authorization")
+	ErrHMACUnknownToken         = errors.New("unknown token id")
+	ErrHMACInvalidRequestDate   = errors.New("invalid RequestDate header")
+	ErrHMACRequestExpired       = errors.New("request date outside of the replay window")
+	ErrHMACInvalidSignature     = errors.New("invalid signature")
+)
+
+// HMACKeyLookup returns the token key for a token id, or ErrHMACUnknownToken
+type HMACKeyLookup func(ctx context.Context, tokenID string) (string, error)
+
+// HMACVerifier checks requests signed the way HMACCredentials.Intercept signs them
+type HMACVerifier struct {
+	LookupKey HMACKeyLookup
+
+	// Now defaults to time.Now
+	Now func() time.Time
+
+	// Window defaults to the two hour replay window
+	Window time.Duration
+
+	// MaxBodyBytes limits the request body read to check the signature. Defaults to 10 MiB; requests with
+	// larger bodies are rejected.
+	MaxBodyBytes int64
+
+	// AllowZoneOffsets accepts request dates with a zone offset other than UTC. They are rejected by default:
+	// only the date up to the hour is signed, so the offset of a captured request can be rewritten to move its
+	// hour by up to a day. HMACCredentials always signs in UTC.
+	AllowZoneOffsets bool
+}
+
+// VerifyHMACRequest checks a bhesignature request with the default replay window and returns the id of the
+// token that signed it. The request body is read and replaced so handlers can still consume it.
+func VerifyHMACRequest(req *http.Request, lookupKey HMACKeyLookup) (string, error) {
+	verifier := HMACVerifier{LookupKey: lookupKey}
+	return verifier.Verify(req)
+}
+
+func (v *HMACVerifier) Verify(req *http.Request) (string, error) {
+	if v.LookupKey == nil {
+		return "", errors.New("no key lookup configured")
+	}
+
+	scheme, tokenID, _ := strings.Cut(req.Header.Get("Authorization"), " ")
+	tokenID = strings.TrimSpace(tokenID)
+	if !strings.EqualFold(scheme, "bhesignature") || tokenID == "" {
+		return "", ErrHMACMissingAuthorization
+	}
+
+	// Only the hour is signed: minutes, seconds and fractions can be rewritten freely, so the age is measured
+	// from the start of the signed hour
+	requestDate := req.Header.Get("RequestDate")
+	stated, err := time.Parse(time.RFC3339Nano, requestDate)
+	if err != nil || len(requestDate) < 13 {
+		return "", ErrHMACInvalidRequestDate
+	}
+	if _, offset := stated.Zone(); !v.AllowZoneOffsets && offset != 0 {
+		return "", ErrHMACInvalidRequestDate
+	}
+	signedHour, err := time.ParseInLocation("2006-01-02T15", requestDate[:13], stated.Location())
+	if err != nil {
+		return "", ErrHMACInvalidRequestDate
+	}
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	window := v.Window
+	if window <= 0 {
+		window = hmacReplayWindow
+	}
+	if age := now.Sub(signedHour); age > window || age < -window {
+		return "", ErrHMACRequestExpired
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(req.Header.Get("Signature"))
+	if err != nil || len(signature) == 0 {
+		return "", ErrHMACInvalidSignature
+	}
+
+	tokenKey, err := v.LookupKey(req.Context(), tokenID)
+	if err != nil {
+		return "", err
+	}
+
+	var body []byte
+	if req.Body != nil {
+		if body, err = io.ReadAll(http.MaxBytesReader(nil, req.Body, v.maxBodyBytes())); err != nil {
+			return "", fmt.Errorf("error reading request body: %w", err)
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := hmacSignature(tokenKey, req.Method, req.URL.RequestURI(), requestDate, body)
+	if !hmac.Equal(expected, signature) {
+		return "", ErrHMACInvalidSignature
+	}
+	return tokenID, nil
+}
+
+func (v *HMACVerifier) maxBodyBytes() int64 {
+	if v.MaxBodyBytes > 0 {
+		return v.MaxBodyBytes
+	}
+	return defaultHMACMaxBodyBytes
+}
+
+// Middleware rejects requests that fail verification with 401 Unauthorized, or 413 Request Entity Too Large
+// for bodies over MaxBodyBytes, and exposes the token id of verified requests through HMACTokenIDFromContext
+func (v *HMACVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Body != nil {
+			req.Body = http.MaxBytesReader(w, req.Body, v.maxBodyBytes())
+		}
+		tokenID, err := v.Verify(req)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), hmacTokenIDContextKey{}, tokenID)))
+	})
+}
+
+// NewHMACMiddleware wraps next with a verifier using the default replay window
+func NewHMACMiddleware(lookupKey HMACKeyLookup, next http.Handler) http.Handler {
+	verifier := &HMACVerifier{LookupKey: lookupKey}
+	return verifier.Middleware(next)
+}
+
+type hmacTokenIDContextKey struct{}
+
+// HMACTokenIDFromContext returns the id of the token that signed the request handled by the middleware
+func HMACTokenIDFromContext(ctx context.Context) (string, bool) {
+	tokenID, ok := ctx.Value(hmacTokenIDContextKey{}).(string)
+	return tokenID, ok
+}
+
+// StaticHMACKeyLookup serves keys from a fixed map of token id to token key
+func StaticHMACKeyLookup(keys map[string]string) HMACKeyLookup {
+	return func(ctx context.Context, tokenID string) (string, error) {
+		if key, ok := keys[tokenID]; ok {
+			return key, nil
+		}
+		return "", ErrHMACUnknownToken
+	}
+}
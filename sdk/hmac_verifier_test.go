@@ -0,0 +1,115 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signedRequest(t *testing.T, signedAt time.Time, body string) *http.Request {
+	t.Helper()
+	credentials, err := NewSecurityProviderHMACCredentials("key", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials.Now = func() time.Time { return signedAt }
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/ingest", strings.NewReader(body))
+	if err := credentials.Intercept(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestHMACVerifierMeasuresAgeFromSignedHour(t *testing.T) {
+	signedAt := time.Date(2024, 5, 1, 10, 5, 0, 0, time.UTC)
+	verifier := &HMACVerifier{
+		LookupKey: StaticHMACKeyLookup(map[string]string{"token": "key"}),
+		Now:       func() time.Time { return signedAt.Add(time.Hour + 50*time.Minute) },
+	}
+
+	if _, err := verifier.Verify(signedRequest(t, signedAt, "{}")); err != nil {
+		t.Fatalf("fresh request rejected: %v", err)
+	}
+
+	// The unsigned minutes are rewritten to push the request date forward
+	req := signedRequest(t, signedAt, "{}")
+	req.Header.Set("RequestDate", "2024-05-01T10:59:59Z")
+	verifier.Now = func() time.Time { return signedAt.Add(2*time.Hour + 10*time.Minute) }
+	if _, err := verifier.Verify(req); !errors.Is(err, ErrHMACRequestExpired) {
+		t.Fatalf("request with rewritten minutes verified past the window: %v", err)
+	}
+}
+
+func TestHMACVerifierRejectsZoneOffsetsByDefault(t *testing.T) {
+	signedAt := time.Date(2024, 5, 1, 10, 5, 0, 0, time.UTC)
+	verifier := &HMACVerifier{
+		LookupKey: StaticHMACKeyLookup(map[string]string{"token": "key"}),
+		Now:       func() time.Time { return signedAt },
+	}
+	req := signedRequest(t, signedAt, "")
+	req.Header.Set("RequestDate", "2024-05-01T10:05:00+02:00")
+	if _, err := verifier.Verify(req); !errors.Is(err, ErrHMACInvalidRequestDate) {
+		t.Fatalf("request date with an offset accepted: %v", err)
+	}
+
+	verifier.AllowZoneOffsets = true
+	req = signedRequest(t, signedAt, "")
+	req.Header.Set("RequestDate", "2024-05-01T10:05:00+02:00")
+	if _, err := verifier.Verify(req); errors.Is(err, ErrHMACInvalidRequestDate) {
+		t.Fatalf("request date with an offset rejected as invalid although offsets are allowed: %v", err)
+	}
+}
+
+func TestHMACVerifierLimitsBody(t *testing.T) {
+	signedAt := time.Now().UTC()
+	verifier := &HMACVerifier{
+		LookupKey:    StaticHMACKeyLookup(map[string]string{"token": "key"}),
+		MaxBodyBytes: 16,
+	}
+	if _, err := verifier.Verify(signedRequest(t, signedAt, strings.Repeat("x", 16))); err != nil {
+		t.Fatalf("body at the limit rejected: %v", err)
+	}
+
+	handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, signedRequest(t, signedAt, strings.Repeat("x", 17)))
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body answered %d, want 413", recorder.Code)
+	}
+}
+
+// signedTestClient returns a client that signs its requests for a server which verifies them before calling handler
+func signedTestClient(t *testing.T, handler http.Handler) *ClientWithResponses {
+	t.Helper()
+	server := httptest.NewServer(NewHMACMiddleware(StaticHMACKeyLookup(map[string]string{"token": "key"}), handler))
+	t.Cleanup(server.Close)
+	credentials, err := NewSecurityProviderHMACCredentials("key", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientWithResponses(server.URL, WithRequestEditorFn(credentials.Intercept))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
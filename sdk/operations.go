@@ -0,0 +1,340 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Editions is a set of BloodHound editions
+type Editions uint8
+
+const (
+	EditionCommunity Editions = 1 << iota
+	EditionEnterprise
+)
+
+func (e Editions) Has(edition Editions) bool {
+	return e&edition == edition
+}
+
+func (e Editions) String() string {
+	switch e {
+	case EditionCommunity:
+		return "community"
+	case EditionEnterprise:
+		return "enterprise"
+	case EditionCommunity | EditionEnterprise:
+		return "community,enterprise"
+	default:
+		return "none"
+	}
+}
+
+// OperationInfo describes an operation of the API
+type OperationInfo struct {
+	Method     string
+	Path       string
+	Editions   Editions
+	Deprecated bool
+}
+
+// Operations maps every operation id of openapi/openapi.json to its route and the editions that serve it, as
+// given by the Community and Enterprise tags of the spec. Keep it in sync when the client is regenerated.
+var Operations = map[string]OperationInfo{
+	"AcceptEula":                                   {Method: http.MethodGet, Path: "/api/v2/accept-eula", Editions: EditionEnterprise},
+	"ActivateUserMfa":                              {Method: http.MethodPost, Path: "/api/v2/bloodhound-users/{user_id}/mfa-activation", Editions: EditionCommunity | EditionEnterprise},
+	"AddUserMfa":                                   {Method: http.MethodPost, Path: "/api/v2/bloodhound-users/{user_id}/mfa", Editions: EditionCommunity | EditionEnterprise},
+	"CancelClientJob":                              {Method: http.MethodPut, Path: "/api/v2/jobs/{job_id}/cancel", Editions: EditionEnterprise},
+	"CreateAssetGroup":                             {Method: http.MethodPost, Path: "/api/v2/asset-groups", Editions: EditionCommunity | EditionEnterprise},
+	"CreateAuthToken":                              {Method: http.MethodPost, Path: "/api/v2/tokens", Editions: EditionCommunity | EditionEnterprise},
+	"CreateClient":                                 {Method: http.MethodPost, Path: "/api/v2/clients", Editions: EditionEnterprise},
+	"CreateClientSchedule":                         {Method: http.MethodPost, Path: "/api/v2/events", Editions: EditionEnterprise},
+	"CreateClientScheduledJob":                     {Method: http.MethodPost, Path: "/api/v2/clients/{client_id}/jobs", Editions: EditionEnterprise},
+	"CreateClientScheduledTask":                    {Method: http.MethodPost, Path: "/api/v2/clients/{client_id}/tasks", Editions: EditionEnterprise, Deprecated: true},
+	"CreateFileUploadJob":                          {Method: http.MethodPost, Path: "/api/v2/file-upload/start", Editions: EditionCommunity | EditionEnterprise},
+	"CreateOrSetUserSecret":                        {Method: http.MethodPut, Path: "/api/v2/bloodhound-users/{user_id}/secret", Editions: EditionCommunity | EditionEnterprise},
+	"CreateSamlProvider":                           {Method: http.MethodPost, Path: "/api/v2/saml/providers", Editions: EditionCommunity | EditionEnterprise},
+	"CreateSavedQuery":                             {Method: http.MethodPost, Path: "/api/v2/saved-queries", Editions: EditionCommunity | EditionEnterprise},
+	"CreateUser":                                   {Method: http.MethodPost, Path: "/api/v2/bloodhound-users", Editions: EditionCommunity | EditionEnterprise},
+	"DeleteAssetGroup":                             {Method: http.MethodDelete, Path: "/api/v2/asset-groups/{asset_group_id}", Editions: EditionCommunity | EditionEnterprise},
+	"DeleteAssetGroupSelector":                     {Method: http.MethodDelete, Path: "/api/v2/asset-groups/{asset_group_id}/selectors/{asset_group_selector_id}", Editions: EditionCommunity | EditionEnterprise},
+	"DeleteAuthToken":                              {Method: http.MethodDelete, Path: "/api/v2/tokens/{token_id}", Editions: EditionCommunity | EditionEnterprise},
+	"DeleteBloodHoundDatabase":                     {Method: http.MethodPost, Path: "/api/v2/clear-database", Editions: EditionCommunity | EditionEnterprise},
+	"DeleteClient":                                 {Method: http.MethodDelete, Path: "/api/v2/clients/{client_id}", Editions: EditionEnterprise},
+	"DeleteClientEvent":                            {Method: http.MethodDelete, Path: "/api/v2/events/{event_id}", Editions: EditionEnterprise},
+	"DeleteSamlProvider":                           {Method: http.MethodDelete, Path: "/api/v2/saml/providers/{saml_provider_id}", Editions: EditionCommunity | EditionEnterprise},
+	"DeleteSavedQuery":                             {Method: http.MethodDelete, Path: "/api/v2/saved-queries/{saved_query_id}", Editions: EditionCommunity | EditionEnterprise},
+	"DeleteSavedQueryPermissions":                  {Method: http.MethodDelete, Path: "/api/v2/saved-queries/{saved_query_id}/permissions", Editions: EditionCommunity | EditionEnterprise},
+	"DeleteUser":                                   {Method: http.MethodDelete, Path: "/api/v2/bloodhound-users/{user_id}", Editions: EditionCommunity | EditionEnterprise},
+	"DeleteUserSecret":                             {Method: http.MethodDelete, Path: "/api/v2/bloodhound-users/{user_id}/secret", Editions: EditionCommunity | EditionEnterprise},
+	"DownloadCollector":                            {Method: http.MethodGet, Path: "/api/v2/collectors/{collector_type}/{release_tag}", Editions: EditionCommunity | EditionEnterprise},
+	"EndClientJob":                                 {Method: http.MethodPost, Path: "/api/v2/jobs/end", Editions: EditionEnterprise},
+	"EndFileUploadJob":                             {Method: http.MethodPost, Path: "/api/v2/file-upload/{file_upload_job_id}/end", Editions: EditionCommunity | EditionEnterprise},
+	"ExportAttackPathFindings":                     {Method: http.MethodGet, Path: "/api/v2/domains/{domain_id}/attack-path-findings", Editions: EditionEnterprise},
+	"GetAdDomainDataQualityStats":                  {Method: http.MethodGet, Path: "/api/v2/ad-domains/{domain_id}/data-quality-stats", Editions: EditionCommunity | EditionEnterprise},
+	"GetAiaCaEntity":                               {Method: http.MethodGet, Path: "/api/v2/aiacas/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetAiaCaEntityControllers":                    {Method: http.MethodGet, Path: "/api/v2/aiacas/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetApiSpec":                                   {Method: http.MethodGet, Path: "/api/v2/spec/openapi.yaml", Editions: EditionCommunity | EditionEnterprise},
+	"GetApiVersion":                                {Method: http.MethodGet, Path: "/api/version", Editions: EditionCommunity | EditionEnterprise},
+	"GetAssetGroup":                                {Method: http.MethodGet, Path: "/api/v2/asset-groups/{asset_group_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetAssetGroupComboNode":                       {Method: http.MethodGet, Path: "/api/v2/asset-groups/{asset_group_id}/combo-node", Editions: EditionEnterprise, Deprecated: true},
+	"GetAssetGroupCustomMemberCount":               {Method: http.MethodGet, Path: "/api/v2/asset-groups/{asset_group_id}/custom-selectors", Editions: EditionCommunity | EditionEnterprise},
+	"GetAvailableDomains":                          {Method: http.MethodGet, Path: "/api/v2/available-domains", Editions: EditionCommunity | EditionEnterprise},
+	"GetAzureEntity":                               {Method: http.MethodGet, Path: "/api/v2/azure/{entity_type}", Editions: EditionCommunity | EditionEnterprise},
+	"GetAzureTenantDataQualityStats":               {Method: http.MethodGet, Path: "/api/v2/azure-tenants/{tenant_id}/data-quality-stats", Editions: EditionCommunity | EditionEnterprise},
+	"GetCertTemplateEntity":                        {Method: http.MethodGet, Path: "/api/v2/certtemplates/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetCertTemplateEntityControllers":             {Method: http.MethodGet, Path: "/api/v2/certtemplates/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetClient":                                    {Method: http.MethodGet, Path: "/api/v2/clients/{client_id}", Editions: EditionEnterprise},
+	"GetClientCurrentJob":                          {Method: http.MethodGet, Path: "/api/v2/jobs/current", Editions: EditionEnterprise},
+	"GetClientJob":                                 {Method: http.MethodGet, Path: "/api/v2/jobs/{job_id}", Editions: EditionEnterprise},
+	"GetClientJobLog":                              {Method: http.MethodGet, Path: "/api/v2/jobs/{job_id}/log", Editions: EditionEnterprise},
+	"GetClientJobs":                                {Method: http.MethodGet, Path: "/api/v2/jobs", Editions: EditionEnterprise},
+	"GetClientSchedule":                            {Method: http.MethodGet, Path: "/api/v2/events/{event_id}", Editions: EditionEnterprise},
+	"GetCollectorChecksum":                         {Method: http.MethodGet, Path: "/api/v2/collectors/{collector_type}/{release_tag}/checksum", Editions: EditionCommunity | EditionEnterprise},
+	"GetCollectorManifest":                         {Method: http.MethodGet, Path: "/api/v2/collectors/{collector_type}", Editions: EditionCommunity | EditionEnterprise},
+	"GetComboTreeGraph":                            {Method: http.MethodGet, Path: "/api/v2/meta-trees/{domain_id}", Editions: EditionEnterprise},
+	"GetCompletenessStats":                         {Method: http.MethodGet, Path: "/api/v2/completeness", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntity":                            {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityAdminRights":                 {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/admin-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityAdmins":                      {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/admin-users", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityConstrainedDelegationRights": {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/constrained-delegation-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityConstrainedUsers":            {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/constrained-users", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityControllables":               {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/controllables", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityControllers":                 {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityDcomRights":                  {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/dcom-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityDcomUsers":                   {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/dcom-users", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityGroupMembership":             {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/group-membership", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityPsRemoteRights":              {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/ps-remote-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityPsRemoteUsers":               {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/ps-remote-users", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityRdpRights":                   {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/rdp-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntityRdpUsers":                    {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/rdp-users", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntitySessions":                    {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/sessions", Editions: EditionCommunity | EditionEnterprise},
+	"GetComputerEntitySqlAdmins":                   {Method: http.MethodGet, Path: "/api/v2/computers/{object_id}/sql-admins", Editions: EditionCommunity | EditionEnterprise},
+	"GetContainerEntity":                           {Method: http.MethodGet, Path: "/api/v2/containers/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetContainerEntityControllers":                {Method: http.MethodGet, Path: "/api/v2/containers/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetDatapipeStatus":                            {Method: http.MethodGet, Path: "/api/v2/datapipe/status", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntity":                              {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityComputers":                     {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/computers", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityControllers":                   {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityDcSyncers":                     {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/dc-syncers", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityForeignAdmins":                 {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/foreign-admins", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityForeignGpoControllers":         {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/foreign-gpo-controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityForeignGroups":                 {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/foreign-groups", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityForeignUsers":                  {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/foreign-users", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityGpos":                          {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/gpos", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityGroups":                        {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/groups", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityInboundTrusts":                 {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/inbound-trusts", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityLinkedGpos":                    {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/linked-gpos", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityOus":                           {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/ous", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityOutboundTrusts":                {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/outbound-trusts", Editions: EditionCommunity | EditionEnterprise},
+	"GetDomainEntityUsers":                         {Method: http.MethodGet, Path: "/api/v2/domains/{object_id}/users", Editions: EditionCommunity | EditionEnterprise},
+	"GetEnterpriseCaEntity":                        {Method: http.MethodGet, Path: "/api/v2/enterprisecas/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetEnterpriseCaEntityControllers":             {Method: http.MethodGet, Path: "/api/v2/enterprisecas/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetEntity":                                    {Method: http.MethodGet, Path: "/api/v2/base/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetEntityControllables":                       {Method: http.MethodGet, Path: "/api/v2/base/{object_id}/controllables", Editions: EditionCommunity | EditionEnterprise},
+	"GetEntityControllers":                         {Method: http.MethodGet, Path: "/api/v2/base/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetGpoEntity":                                 {Method: http.MethodGet, Path: "/api/v2/gpos/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetGpoEntityComputers":                        {Method: http.MethodGet, Path: "/api/v2/gpos/{object_id}/computers", Editions: EditionCommunity | EditionEnterprise},
+	"GetGpoEntityControllers":                      {Method: http.MethodGet, Path: "/api/v2/gpos/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetGpoEntityOus":                              {Method: http.MethodGet, Path: "/api/v2/gpos/{object_id}/ous", Editions: EditionCommunity | EditionEnterprise},
+	"GetGpoEntityTierZero":                         {Method: http.MethodGet, Path: "/api/v2/gpos/{object_id}/tier-zero", Editions: EditionCommunity | EditionEnterprise},
+	"GetGpoEntityUsers":                            {Method: http.MethodGet, Path: "/api/v2/gpos/{object_id}/users", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntity":                               {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntityAdminRights":                    {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}/admin-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntityControllables":                  {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}/controllables", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntityControllers":                    {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntityDcomRights":                     {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}/dcom-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntityMembers":                        {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}/members", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntityMemberships":                    {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}/memberships", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntityPsRemoteRights":                 {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}/ps-remote-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntityRdpRights":                      {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}/rdp-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetGroupEntitySessions":                       {Method: http.MethodGet, Path: "/api/v2/groups/{object_id}/sessions", Editions: EditionCommunity | EditionEnterprise},
+	"GetLatestTierZeroComboNode":                   {Method: http.MethodGet, Path: "/api/v2/meta-nodes/{domain_id}", Editions: EditionEnterprise},
+	"GetMetaEntity":                                {Method: http.MethodGet, Path: "/api/v2/meta/{object_id}", Editions: EditionEnterprise},
+	"GetMfaActivationStatus":                       {Method: http.MethodGet, Path: "/api/v2/bloodhound-users/{user_id}/mfa-activation", Editions: EditionCommunity | EditionEnterprise},
+	"GetNtAuthStoreEntity":                         {Method: http.MethodGet, Path: "/api/v2/ntauthstores/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetNtAuthStoreEntityControllers":              {Method: http.MethodGet, Path: "/api/v2/ntauthstores/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetOuEntity":                                  {Method: http.MethodGet, Path: "/api/v2/ous/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetOuEntityComputers":                         {Method: http.MethodGet, Path: "/api/v2/ous/{object_id}/computers", Editions: EditionCommunity | EditionEnterprise},
+	"GetOuEntityGpos":                              {Method: http.MethodGet, Path: "/api/v2/ous/{object_id}/gpos", Editions: EditionCommunity | EditionEnterprise},
+	"GetOuEntityGroups":                            {Method: http.MethodGet, Path: "/api/v2/ous/{object_id}/groups", Editions: EditionCommunity | EditionEnterprise},
+	"GetOuEntityUsers":                             {Method: http.MethodGet, Path: "/api/v2/ous/{object_id}/users", Editions: EditionCommunity | EditionEnterprise},
+	"GetPathComposition":                           {Method: http.MethodGet, Path: "/api/v2/graphs/edge-composition", Editions: EditionCommunity | EditionEnterprise},
+	"GetPermission":                                {Method: http.MethodGet, Path: "/api/v2/permissions/{permission_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetPlatformDataQualityAggregate":              {Method: http.MethodGet, Path: "/api/v2/platform/{platform_id}/data-quality-stats", Editions: EditionCommunity | EditionEnterprise},
+	"GetPostureStats":                              {Method: http.MethodGet, Path: "/api/v2/posture-stats", Editions: EditionEnterprise},
+	"GetRole":                                      {Method: http.MethodGet, Path: "/api/v2/roles/{role_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetRootCaEntity":                              {Method: http.MethodGet, Path: "/api/v2/rootcas/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetRootCaEntityControllers":                   {Method: http.MethodGet, Path: "/api/v2/rootcas/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetSamlProvider":                              {Method: http.MethodGet, Path: "/api/v2/saml/providers/{saml_provider_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetSamlSignSignOnEndpoints":                   {Method: http.MethodGet, Path: "/api/v2/saml/sso", Editions: EditionCommunity | EditionEnterprise},
+	"GetSearchResult":                              {Method: http.MethodGet, Path: "/api/v2/graph-search", Editions: EditionCommunity | EditionEnterprise},
+	"GetSelf":                                      {Method: http.MethodGet, Path: "/api/v2/self", Editions: EditionCommunity | EditionEnterprise},
+	"GetShortestPath":                              {Method: http.MethodGet, Path: "/api/v2/graphs/shortest-path", Editions: EditionCommunity | EditionEnterprise},
+	"GetUser":                                      {Method: http.MethodGet, Path: "/api/v2/bloodhound-users/{user_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntity":                                {Method: http.MethodGet, Path: "/api/v2/users/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntityAdminRights":                     {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/admin-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntityConstrainedDelegationRights":     {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/constrained-delegation-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntityControllables":                   {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/controllables", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntityControllers":                     {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/controllers", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntityDcomRights":                      {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/dcom-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntityMembership":                      {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/memberships", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntityPsRemoteRights":                  {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/ps-remote-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntityRdpRights":                       {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/rdp-rights", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntitySessions":                        {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/sessions", Editions: EditionCommunity | EditionEnterprise},
+	"GetUserEntitySqlAdminRights":                  {Method: http.MethodGet, Path: "/api/v2/users/{object_id}/sql-admin-rights", Editions: EditionCommunity | EditionEnterprise},
+	"IngestData":                                   {Method: http.MethodPost, Path: "/api/v2/ingest", Editions: EditionEnterprise},
+	"ListAcceptedFileUploadTypes":                  {Method: http.MethodGet, Path: "/api/v2/file-upload/accepted-types", Editions: EditionCommunity | EditionEnterprise},
+	"ListAppConfigParams":                          {Method: http.MethodGet, Path: "/api/v2/config", Editions: EditionCommunity | EditionEnterprise},
+	"ListAssetGroupCollections":                    {Method: http.MethodGet, Path: "/api/v2/asset-groups/{asset_group_id}/collections", Editions: EditionCommunity | EditionEnterprise},
+	"ListAssetGroupMemberCountByKind":              {Method: http.MethodGet, Path: "/api/v2/asset-groups/{asset_group_id}/members/counts", Editions: EditionCommunity | EditionEnterprise},
+	"ListAssetGroupMembers":                        {Method: http.MethodGet, Path: "/api/v2/asset-groups/{asset_group_id}/members", Editions: EditionCommunity | EditionEnterprise},
+	"ListAssetGroups":                              {Method: http.MethodGet, Path: "/api/v2/asset-groups", Editions: EditionCommunity | EditionEnterprise},
+	"ListAttackPathSparklineValues":                {Method: http.MethodGet, Path: "/api/v2/domains/{domain_id}/sparkline", Editions: EditionEnterprise},
+	"ListAttackPathTypes":                          {Method: http.MethodGet, Path: "/api/v2/attack-path-types", Editions: EditionEnterprise},
+	"ListAuditLogs":                                {Method: http.MethodGet, Path: "/api/v2/audit", Editions: EditionCommunity | EditionEnterprise},
+	"ListAuthTokens":                               {Method: http.MethodGet, Path: "/api/v2/tokens", Editions: EditionCommunity | EditionEnterprise},
+	"ListAvailableAttackPathTypesForDomain":        {Method: http.MethodGet, Path: "/api/v2/domains/{domain_id}/available-types", Editions: EditionEnterprise},
+	"ListAvailableClientJobs":                      {Method: http.MethodGet, Path: "/api/v2/jobs/available", Editions: EditionEnterprise},
+	"ListClientCompletedJobs":                      {Method: http.MethodGet, Path: "/api/v2/clients/{client_id}/completed-jobs", Editions: EditionEnterprise},
+	"ListClientCompletedTasks":                     {Method: http.MethodGet, Path: "/api/v2/clients/{client_id}/completed-tasks", Editions: EditionEnterprise, Deprecated: true},
+	"ListClientFinishedJobs":                       {Method: http.MethodGet, Path: "/api/v2/jobs/finished", Editions: EditionEnterprise},
+	"ListClientSchedules":                          {Method: http.MethodGet, Path: "/api/v2/events", Editions: EditionEnterprise},
+	"ListClients":                                  {Method: http.MethodGet, Path: "/api/v2/clients", Editions: EditionEnterprise},
+	"ListDomainAttackPathsDetails":                 {Method: http.MethodGet, Path: "/api/v2/domains/{domain_id}/details", Editions: EditionEnterprise},
+	"ListFeatureFlags":                             {Method: http.MethodGet, Path: "/api/v2/features", Editions: EditionCommunity | EditionEnterprise},
+	"ListFileUploadJobs":                           {Method: http.MethodGet, Path: "/api/v2/file-upload", Editions: EditionCommunity | EditionEnterprise},
+	"ListPermissions":                              {Method: http.MethodGet, Path: "/api/v2/permissions", Editions: EditionCommunity | EditionEnterprise},
+	"ListRoles":                                    {Method: http.MethodGet, Path: "/api/v2/roles", Editions: EditionCommunity | EditionEnterprise},
+	"ListSamlProviders":                            {Method: http.MethodGet, Path: "/api/v2/saml", Editions: EditionCommunity | EditionEnterprise},
+	"ListSavedQueries":                             {Method: http.MethodGet, Path: "/api/v2/saved-queries", Editions: EditionCommunity | EditionEnterprise},
+	"ListUsers":                                    {Method: http.MethodGet, Path: "/api/v2/bloodhound-users", Editions: EditionCommunity | EditionEnterprise},
+	"LogClientError":                               {Method: http.MethodPost, Path: "/api/v2/clients/error", Editions: EditionEnterprise},
+	"Login":                                        {Method: http.MethodPost, Path: "/api/v2/login", Editions: EditionCommunity | EditionEnterprise},
+	"Logout":                                       {Method: http.MethodPost, Path: "/api/v2/logout", Editions: EditionCommunity | EditionEnterprise},
+	"Pathfinding":                                  {Method: http.MethodGet, Path: "/api/v2/pathfinding", Editions: EditionCommunity | EditionEnterprise, Deprecated: true},
+	"RemoveUserMfa":                                {Method: http.MethodDelete, Path: "/api/v2/bloodhound-users/{user_id}/mfa", Editions: EditionCommunity | EditionEnterprise},
+	"ReplaceClientToken":                           {Method: http.MethodPut, Path: "/api/v2/clients/{client_id}/token", Editions: EditionEnterprise},
+	"RunCypherQuery":                               {Method: http.MethodPost, Path: "/api/v2/graphs/cypher", Editions: EditionCommunity | EditionEnterprise},
+	"Search":                                       {Method: http.MethodGet, Path: "/api/v2/search", Editions: EditionCommunity | EditionEnterprise},
+	"SetAppConfigParam":                            {Method: http.MethodPut, Path: "/api/v2/config", Editions: EditionCommunity | EditionEnterprise},
+	"ShareSavedQuery":                              {Method: http.MethodPut, Path: "/api/v2/saved-queries/{saved_query_id}/permissions", Editions: EditionCommunity | EditionEnterprise},
+	"StartAnalysis":                                {Method: http.MethodPut, Path: "/api/v2/analysis", Editions: EditionCommunity | EditionEnterprise},
+	"StartAnalysisBhe":                             {Method: http.MethodPut, Path: "/api/v2/attack-paths", Editions: EditionEnterprise},
+	"StartClientJob":                               {Method: http.MethodPost, Path: "/api/v2/jobs/start", Editions: EditionEnterprise},
+	"ToggleFeatureFlag":                            {Method: http.MethodPut, Path: "/api/v2/features/{feature_id}/toggle", Editions: EditionCommunity | EditionEnterprise},
+	"UpdateAssetGroup":                             {Method: http.MethodPut, Path: "/api/v2/asset-groups/{asset_group_id}", Editions: EditionCommunity | EditionEnterprise},
+	"UpdateAssetGroupSelectors":                    {Method: http.MethodPut, Path: "/api/v2/asset-groups/{asset_group_id}/selectors", Editions: EditionCommunity | EditionEnterprise},
+	"UpdateAssetGroupSelectorsDeprecated":          {Method: http.MethodPost, Path: "/api/v2/asset-groups/{asset_group_id}/selectors", Editions: EditionCommunity | EditionEnterprise, Deprecated: true},
+	"UpdateAttackPathRisk":                         {Method: http.MethodPut, Path: "/api/v2/attack-paths/{attack_path_id}/acceptance", Editions: EditionEnterprise},
+	"UpdateClient":                                 {Method: http.MethodPut, Path: "/api/v2/clients/{client_id}", Editions: EditionEnterprise},
+	"UpdateClientEvent":                            {Method: http.MethodPut, Path: "/api/v2/events/{event_id}", Editions: EditionEnterprise},
+	"UpdateClientInfo":                             {Method: http.MethodPut, Path: "/api/v2/clients/update", Editions: EditionEnterprise},
+	"UpdateDomainEntity":                           {Method: http.MethodPatch, Path: "/api/v2/domains/{object_id}", Editions: EditionCommunity | EditionEnterprise},
+	"UpdateSavedQuery":                             {Method: http.MethodPut, Path: "/api/v2/saved-queries/{saved_query_id}", Editions: EditionCommunity | EditionEnterprise},
+	"UpdateUser":                                   {Method: http.MethodPatch, Path: "/api/v2/bloodhound-users/{user_id}", Editions: EditionCommunity | EditionEnterprise},
+	"UploadFileToJob":                              {Method: http.MethodPost, Path: "/api/v2/file-upload/{file_upload_job_id}", Editions: EditionCommunity | EditionEnterprise},
+}
+
+type operationRoute struct {
+	operation string
+	method    string
+	segments  []string
+	literals  int
+}
+
+var (
+	operationRoutesOnce sync.Once
+	operationRoutes     []operationRoute
+)
+
+// OperationForRequest finds the operation id of a request built by the client. The server URL may carry a path
+// prefix in front of /api/.
+func OperationForRequest(req *http.Request) (string, bool) {
+	operationRoutesOnce.Do(func() {
+		operationRoutes = newOperationRoutes(Operations)
+	})
+
+	path := req.URL.Path
+	if index := strings.Index(path, "/api/"); index >= 0 {
+		path = path[index:]
+	}
+	return matchOperationRoute(operationRoutes, req.Method, strings.Split(strings.Trim(path, "/"), "/"))
+}
+
+// Routes ordered from the most specific: more literal segments first, then a literal before a parameter at the
+// first segment where they differ, then by operation id, so the first match is the same on every run. Routes of
+// different lengths never match the same path and are only ordered by length to keep the order total.
+func newOperationRoutes(operations map[string]OperationInfo) []operationRoute {
+	var routes []operationRoute
+	for operation, info := range operations {
+		route := operationRoute{
+			operation: operation,
+			method:    info.Method,
+			segments:  strings.Split(strings.Trim(info.Path, "/"), "/"),
+		}
+		for _, segment := range route.segments {
+			if !isPathParameter(segment) {
+				route.literals++
+			}
+		}
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		a, b := routes[i], routes[j]
+		if a.literals != b.literals {
+			return a.literals > b.literals
+		}
+		if len(a.segments) != len(b.segments) {
+			return len(a.segments) < len(b.segments)
+		}
+		for k := range a.segments {
+			if aParam, bParam := isPathParameter(a.segments[k]), isPathParameter(b.segments[k]); aParam != bParam {
+				return bParam
+			}
+		}
+		return a.operation < b.operation
+	})
+	return routes
+}
+
+func matchOperationRoute(routes []operationRoute, method string, segments []string) (string, bool) {
+	for _, route := range routes {
+		if route.method != method || len(route.segments) != len(segments) {
+			continue
+		}
+		matched := true
+		for i, segment := range route.segments {
+			if !isPathParameter(segment) && segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return route.operation, true
+		}
+	}
+	return "", false
+}
+
+func isPathParameter(segment string) bool {
+	return strings.HasPrefix(segment, "{")
+}
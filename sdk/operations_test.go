@@ -0,0 +1,117 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOperationsMatchSpec(t *testing.T) {
+	content, err := os.ReadFile("../openapi/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(content, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for path, methods := range spec.Paths {
+		for method, raw := range methods {
+			var operation struct {
+				OperationId string   `json:"operationId"`
+				Tags        []string `json:"tags"`
+				Deprecated  bool     `json:"deprecated"`
+			}
+			if method == "parameters" || json.Unmarshal(raw, &operation) != nil || operation.OperationId == "" {
+				continue
+			}
+			seen[operation.OperationId] = true
+
+			info, ok := Operations[operation.OperationId]
+			if !ok {
+				t.Errorf("%s is missing from Operations", operation.OperationId)
+				continue
+			}
+			var editions Editions
+			for _, tag := range operation.Tags {
+				switch tag {
+				case "Community":
+					editions |= EditionCommunity
+				case "Enterprise":
+					editions |= EditionEnterprise
+				}
+			}
+			if info.Method != strings.ToUpper(method) || info.Path != path {
+				t.Errorf("%s is %s %s in Operations, %s %s in the spec", operation.OperationId, info.Method, info.Path, strings.ToUpper(method), path)
+			}
+			if info.Deprecated != operation.Deprecated {
+				t.Errorf("%s is deprecated %t in Operations, %t in the spec", operation.OperationId, info.Deprecated, operation.Deprecated)
+			}
+			if info.Editions != editions {
+				t.Errorf("%s has editions %s in Operations, %s in the spec", operation.OperationId, info.Editions, editions)
+			}
+		}
+	}
+	for operation := range Operations {
+		if !seen[operation] {
+			t.Errorf("%s is in Operations but not in the spec", operation)
+		}
+	}
+}
+
+func TestOperationForRequestFindsEveryOperation(t *testing.T) {
+	for operation, info := range Operations {
+		segments := strings.Split(info.Path, "/")
+		for i, segment := range segments {
+			if isPathParameter(segment) {
+				segments[i] = "value"
+			}
+		}
+		req := httptest.NewRequest(info.Method, "https://bloodhound.example/prefix"+strings.Join(segments, "/"), nil)
+		if found, ok := OperationForRequest(req); !ok || found != operation {
+			t.Errorf("%s %s was routed to %q", info.Method, info.Path, found)
+		}
+	}
+}
+
+func TestOperationRoutesBreakTiesDeterministically(t *testing.T) {
+	operations := map[string]OperationInfo{
+		"ByName":    {Method: http.MethodGet, Path: "/api/v2/things/{id}/name"},
+		"ByKind":    {Method: http.MethodGet, Path: "/api/v2/things/kind/{kind}"},
+		"OtherKind": {Method: http.MethodGet, Path: "/api/v2/things/kind/{other}"},
+		"Any":       {Method: http.MethodGet, Path: "/api/v2/things/{id}/{field}"},
+	}
+	for i := 0; i < 20; i++ {
+		routes := newOperationRoutes(operations)
+		// Both routes have three literals; the one whose literal comes first is more specific
+		if found, _ := matchOperationRoute(routes, http.MethodGet, []string{"api", "v2", "things", "kind", "name"}); found != "ByKind" {
+			t.Fatalf("/api/v2/things/kind/name was routed to %q, want ByKind", found)
+		}
+		if found, _ := matchOperationRoute(routes, http.MethodGet, []string{"api", "v2", "things", "1", "name"}); found != "ByName" {
+			t.Fatalf("/api/v2/things/1/name was routed to %q, want ByName", found)
+		}
+	}
+}
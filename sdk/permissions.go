@@ -0,0 +1,584 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// Permission is a permission granted through roles, written authority.name as in "graphdb.Read"
+type Permission struct {
+	Authority string
+	Name      string
+}
+
+// The permissions of the BloodHound roles
+var (
+	PermissionAppReadConfig       = Permission{"app", "ReadAppConfig"}
+	PermissionAppWriteConfig      = Permission{"app", "WriteAppConfig"}
+	PermissionAuthCreateToken     = Permission{"auth", "CreateToken"}
+	PermissionAuthManageProviders = Permission{"auth", "ManageProviders"}
+	PermissionAuthManageSelf      = Permission{"auth", "ManageSelf"}
+	PermissionAuthManageUsers     = Permission{"auth", "ManageUsers"}
+	PermissionClientsManage       = Permission{"clients", "Manage"}
+	PermissionClientsRead         = Permission{"clients", "Read"}
+	PermissionClientsTasking      = Permission{"clients", "Tasking"}
+	PermissionDBWipe              = Permission{"db", "Wipe"}
+	PermissionGraphDBIngest       = Permission{"graphdb", "Ingest"}
+	PermissionGraphDBMutate       = Permission{"graphdb", "Mutate"}
+	PermissionGraphDBRead         = Permission{"graphdb", "Read"}
+	PermissionGraphDBWrite        = Permission{"graphdb", "Write"}
+	PermissionRisksGenerateReport = Permission{"risks", "GenerateReport"}
+	PermissionRisksManageRisks    = Permission{"risks", "ManageRisks"}
+	PermissionSavedQueriesRead    = Permission{"saved_queries", "Read"}
+	PermissionSavedQueriesWrite   = Permission{"saved_queries", "Write"}
+)
+
+// ParsePermission reads a permission written authority.name
+func ParsePermission(value string) (Permission, error) {
+	authority, name, ok := strings.Cut(strings.TrimSpace(value), ".")
+	if !ok || authority == "" || name == "" {
+		return Permission{}, fmt.Errorf("invalid permission %q, expected authority.name", value)
+	}
+	return Permission{Authority: authority, Name: name}, nil
+}
+
+func (p Permission) String() string {
+	return p.Authority + "." + p.Name
+}
+
+func (p Permission) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Permission) UnmarshalText(text []byte) error {
+	permission, err := ParsePermission(string(text))
+	if err != nil {
+		return err
+	}
+	*p = permission
+	return nil
+}
+
+// OperationAccess describes who may call an operation
+type OperationAccess struct {
+	// Public operations need no authentication
+	Public bool
+
+	// Client operations are only served to collector clients, which authenticate with their own token
+	Client bool
+
+	// Permissions are all required of a user; an authenticated operation without permissions is open to every
+	// user
+	Permissions []Permission
+}
+
+// OperationPermissions maps every operation id of Operations to who may call it. Public operations are those
+// the spec marks with an empty security requirement; the permissions follow the authorization of the API
+// routes, which the spec does not describe. Keep it in sync with Operations. RunCypherQuery needs
+// PermissionGraphDBMutate on top of PermissionGraphDBRead for queries that change the graph.
+var OperationPermissions = map[string]OperationAccess{
+	"AcceptEula":                                   {},
+	"ActivateUserMfa":                              {Permissions: []Permission{PermissionAuthManageSelf}},
+	"AddUserMfa":                                   {Permissions: []Permission{PermissionAuthManageSelf}},
+	"CancelClientJob":                              {Permissions: []Permission{PermissionClientsTasking}},
+	"CreateAssetGroup":                             {Permissions: []Permission{PermissionGraphDBWrite}},
+	"CreateAuthToken":                              {Permissions: []Permission{PermissionAuthCreateToken}},
+	"CreateClient":                                 {Permissions: []Permission{PermissionClientsManage}},
+	"CreateClientSchedule":                         {Permissions: []Permission{PermissionClientsTasking}},
+	"CreateClientScheduledJob":                     {Permissions: []Permission{PermissionClientsTasking}},
+	"CreateClientScheduledTask":                    {Permissions: []Permission{PermissionClientsTasking}},
+	"CreateFileUploadJob":                          {Permissions: []Permission{PermissionGraphDBIngest}},
+	"CreateOrSetUserSecret":                        {Permissions: []Permission{PermissionAuthManageSelf}},
+	"CreateSamlProvider":                           {Permissions: []Permission{PermissionAuthManageProviders}},
+	"CreateSavedQuery":                             {Permissions: []Permission{PermissionSavedQueriesWrite}},
+	"CreateUser":                                   {Permissions: []Permission{PermissionAuthManageUsers}},
+	"DeleteAssetGroup":                             {Permissions: []Permission{PermissionGraphDBWrite}},
+	"DeleteAssetGroupSelector":                     {Permissions: []Permission{PermissionGraphDBWrite}},
+	"DeleteAuthToken":                              {Permissions: []Permission{PermissionAuthCreateToken}},
+	"DeleteBloodHoundDatabase":                     {Permissions: []Permission{PermissionDBWipe}},
+	"DeleteClient":                                 {Permissions: []Permission{PermissionClientsManage}},
+	"DeleteClientEvent":                            {Permissions: []Permission{PermissionClientsTasking}},
+	"DeleteSamlProvider":                           {Permissions: []Permission{PermissionAuthManageProviders}},
+	"DeleteSavedQuery":                             {Permissions: []Permission{PermissionSavedQueriesWrite}},
+	"DeleteSavedQueryPermissions":                  {Permissions: []Permission{PermissionSavedQueriesWrite}},
+	"DeleteUser":                                   {Permissions: []Permission{PermissionAuthManageUsers}},
+	"DeleteUserSecret":                             {Permissions: []Permission{PermissionAuthManageUsers}},
+	"DownloadCollector":                            {},
+	"EndClientJob":                                 {Client: true},
+	"EndFileUploadJob":                             {Permissions: []Permission{PermissionGraphDBIngest}},
+	"ExportAttackPathFindings":                     {Permissions: []Permission{PermissionRisksGenerateReport}},
+	"GetAdDomainDataQualityStats":                  {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetAiaCaEntity":                               {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetAiaCaEntityControllers":                    {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetApiSpec":                                   {Public: true},
+	"GetApiVersion":                                {},
+	"GetAssetGroup":                                {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetAssetGroupComboNode":                       {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetAssetGroupCustomMemberCount":               {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetAvailableDomains":                          {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetAzureEntity":                               {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetAzureTenantDataQualityStats":               {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetCertTemplateEntity":                        {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetCertTemplateEntityControllers":             {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetClient":                                    {Permissions: []Permission{PermissionClientsRead}},
+	"GetClientCurrentJob":                          {Client: true},
+	"GetClientJob":                                 {Permissions: []Permission{PermissionClientsRead}},
+	"GetClientJobLog":                              {Permissions: []Permission{PermissionClientsRead}},
+	"GetClientJobs":                                {Permissions: []Permission{PermissionClientsRead}},
+	"GetClientSchedule":                            {Permissions: []Permission{PermissionClientsRead}},
+	"GetCollectorChecksum":                         {},
+	"GetCollectorManifest":                         {},
+	"GetComboTreeGraph":                            {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetCompletenessStats":                         {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntity":                            {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityAdminRights":                 {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityAdmins":                      {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityConstrainedDelegationRights": {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityConstrainedUsers":            {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityControllables":               {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityControllers":                 {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityDcomRights":                  {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityDcomUsers":                   {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityGroupMembership":             {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityPsRemoteRights":              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityPsRemoteUsers":               {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityRdpRights":                   {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntityRdpUsers":                    {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntitySessions":                    {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetComputerEntitySqlAdmins":                   {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetContainerEntity":                           {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetContainerEntityControllers":                {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDatapipeStatus":                            {},
+	"GetDomainEntity":                              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityComputers":                     {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityControllers":                   {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityDcSyncers":                     {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityForeignAdmins":                 {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityForeignGpoControllers":         {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityForeignGroups":                 {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityForeignUsers":                  {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityGpos":                          {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityGroups":                        {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityInboundTrusts":                 {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityLinkedGpos":                    {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityOus":                           {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityOutboundTrusts":                {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetDomainEntityUsers":                         {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetEnterpriseCaEntity":                        {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetEnterpriseCaEntityControllers":             {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetEntity":                                    {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetEntityControllables":                       {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetEntityControllers":                         {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGpoEntity":                                 {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGpoEntityComputers":                        {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGpoEntityControllers":                      {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGpoEntityOus":                              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGpoEntityTierZero":                         {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGpoEntityUsers":                            {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntity":                               {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntityAdminRights":                    {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntityControllables":                  {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntityControllers":                    {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntityDcomRights":                     {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntityMembers":                        {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntityMemberships":                    {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntityPsRemoteRights":                 {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntityRdpRights":                      {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetGroupEntitySessions":                       {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetLatestTierZeroComboNode":                   {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetMetaEntity":                                {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetMfaActivationStatus":                       {Permissions: []Permission{PermissionAuthManageSelf}},
+	"GetNtAuthStoreEntity":                         {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetNtAuthStoreEntityControllers":              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetOuEntity":                                  {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetOuEntityComputers":                         {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetOuEntityGpos":                              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetOuEntityGroups":                            {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetOuEntityUsers":                             {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetPathComposition":                           {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetPermission":                                {Permissions: []Permission{PermissionAuthManageSelf}},
+	"GetPlatformDataQualityAggregate":              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetPostureStats":                              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetRole":                                      {Permissions: []Permission{PermissionAuthManageSelf}},
+	"GetRootCaEntity":                              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetRootCaEntityControllers":                   {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetSamlProvider":                              {Permissions: []Permission{PermissionAuthManageProviders}},
+	"GetSamlSignSignOnEndpoints":                   {Public: true},
+	"GetSearchResult":                              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetSelf":                                      {Public: true},
+	"GetShortestPath":                              {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUser":                                      {Permissions: []Permission{PermissionAuthManageUsers}},
+	"GetUserEntity":                                {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntityAdminRights":                     {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntityConstrainedDelegationRights":     {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntityControllables":                   {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntityControllers":                     {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntityDcomRights":                      {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntityMembership":                      {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntityPsRemoteRights":                  {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntityRdpRights":                       {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntitySessions":                        {Permissions: []Permission{PermissionGraphDBRead}},
+	"GetUserEntitySqlAdminRights":                  {Permissions: []Permission{PermissionGraphDBRead}},
+	"IngestData":                                   {Permissions: []Permission{PermissionGraphDBIngest}},
+	"ListAcceptedFileUploadTypes":                  {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListAppConfigParams":                          {Permissions: []Permission{PermissionAppReadConfig}},
+	"ListAssetGroupCollections":                    {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListAssetGroupMemberCountByKind":              {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListAssetGroupMembers":                        {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListAssetGroups":                              {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListAttackPathSparklineValues":                {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListAttackPathTypes":                          {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListAuditLogs":                                {Permissions: []Permission{PermissionAuthManageUsers}},
+	"ListAuthTokens":                               {Permissions: []Permission{PermissionAuthManageSelf}},
+	"ListAvailableAttackPathTypesForDomain":        {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListAvailableClientJobs":                      {Client: true},
+	"ListClientCompletedJobs":                      {Permissions: []Permission{PermissionClientsRead}},
+	"ListClientCompletedTasks":                     {Permissions: []Permission{PermissionClientsRead}},
+	"ListClientFinishedJobs":                       {Permissions: []Permission{PermissionClientsRead}},
+	"ListClientSchedules":                          {Permissions: []Permission{PermissionClientsRead}},
+	"ListClients":                                  {Permissions: []Permission{PermissionClientsRead}},
+	"ListDomainAttackPathsDetails":                 {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListFeatureFlags":                             {},
+	"ListFileUploadJobs":                           {Permissions: []Permission{PermissionGraphDBRead}},
+	"ListPermissions":                              {Permissions: []Permission{PermissionAuthManageSelf}},
+	"ListRoles":                                    {Permissions: []Permission{PermissionAuthManageSelf}},
+	"ListSamlProviders":                            {Permissions: []Permission{PermissionAuthManageProviders}},
+	"ListSavedQueries":                             {Permissions: []Permission{PermissionSavedQueriesRead}},
+	"ListUsers":                                    {Permissions: []Permission{PermissionAuthManageUsers}},
+	"LogClientError":                               {Client: true},
+	"Login":                                        {Public: true},
+	"Logout":                                       {Public: true},
+	"Pathfinding":                                  {Permissions: []Permission{PermissionGraphDBRead}},
+	"RemoveUserMfa":                                {Permissions: []Permission{PermissionAuthManageSelf}},
+	"ReplaceClientToken":                           {Permissions: []Permission{PermissionClientsManage}},
+	"RunCypherQuery":                               {Permissions: []Permission{PermissionGraphDBRead}},
+	"Search":                                       {Permissions: []Permission{PermissionGraphDBRead}},
+	"SetAppConfigParam":                            {Permissions: []Permission{PermissionAppWriteConfig}},
+	"ShareSavedQuery":                              {Permissions: []Permission{PermissionSavedQueriesWrite}},
+	"StartAnalysis":                                {Permissions: []Permission{PermissionGraphDBWrite}},
+	"StartAnalysisBhe":                             {Permissions: []Permission{PermissionGraphDBWrite}},
+	"StartClientJob":                               {Client: true},
+	"ToggleFeatureFlag":                            {Permissions: []Permission{PermissionAppWriteConfig}},
+	"UpdateAssetGroup":                             {Permissions: []Permission{PermissionGraphDBWrite}},
+	"UpdateAssetGroupSelectors":                    {Permissions: []Permission{PermissionGraphDBWrite}},
+	"UpdateAssetGroupSelectorsDeprecated":          {Permissions: []Permission{PermissionGraphDBWrite}},
+	"UpdateAttackPathRisk":                         {Permissions: []Permission{PermissionRisksManageRisks}},
+	"UpdateClient":                                 {Permissions: []Permission{PermissionClientsManage}},
+	"UpdateClientEvent":                            {Permissions: []Permission{PermissionClientsTasking}},
+	"UpdateClientInfo":                             {Client: true},
+	"UpdateDomainEntity":                           {Permissions: []Permission{PermissionGraphDBWrite}},
+	"UpdateSavedQuery":                             {Permissions: []Permission{PermissionSavedQueriesWrite}},
+	"UpdateUser":                                   {Permissions: []Permission{PermissionAuthManageUsers}},
+	"UploadFileToJob":                              {Permissions: []Permission{PermissionGraphDBIngest}},
+}
+
+// UserPermissions lists the permissions a user holds through their roles, sorted
+func UserPermissions(user ModelUser) []Permission {
+	granted := map[Permission]bool{}
+	if user.Roles != nil {
+		for _, role := range *user.Roles {
+			addRolePermissions(granted, role)
+		}
+	}
+	return sortedPermissions(granted)
+}
+
+func addRolePermissions(granted map[Permission]bool, role ModelRole) {
+	if role.Permissions == nil {
+		return
+	}
+	for _, permission := range *role.Permissions {
+		if permission.Authority != nil && permission.Name != nil {
+			granted[Permission{Authority: *permission.Authority, Name: *permission.Name}] = true
+		}
+	}
+}
+
+func sortedPermissions(set map[Permission]bool) []Permission {
+	permissions := make([]Permission, 0, len(set))
+	for permission := range set {
+		permissions = append(permissions, permission)
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].String() < permissions[j].String() })
+	return permissions
+}
+
+// Permissions an operation needs that are not granted; client operations need a client token, which is
+// reported as an error
+func missingPermissions(operation string, granted map[Permission]bool) ([]Permission, error) {
+	access, ok := OperationPermissions[operation]
+	if !ok {
+		return nil, fmt.Errorf("unknown operation %q", operation)
+	}
+	if access.Client {
+		return nil, fmt.Errorf("%s is only served to collector clients", operation)
+	}
+	var missing []Permission
+	if !access.Public {
+		for _, permission := range access.Permissions {
+			if !granted[permission] {
+				missing = append(missing, permission)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// ErrPermissionDenied is matched by the error returned when the current user lacks permissions
+var ErrPermissionDenied = errors.New("permission denied")
+
+// MissingPermissionsError names, by operation id, the permissions the current user lacks
+type MissingPermissionsError struct {
+	Operations map[string][]Permission
+}
+
+func (e *MissingPermissionsError) Error() string {
+	var parts []string
+	for _, operation := range sortedKeys(e.Operations) {
+		var names []string
+		for _, permission := range e.Operations[operation] {
+			names = append(names, permission.String())
+		}
+		parts = append(parts, fmt.Sprintf("%s needs %s", operation, strings.Join(names, ", ")))
+	}
+	return "permission denied: " + strings.Join(parts, "; ")
+}
+
+func (e *MissingPermissionsError) Unwrap() error {
+	return ErrPermissionDenied
+}
+
+// Permissions returns the permissions the current user holds through their roles
+func (c *ClientWithResponses) Permissions(ctx context.Context) ([]Permission, error) {
+	user, err := c.self(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return UserPermissions(user), nil
+}
+
+// Can reports whether the current user holds the permissions an operation needs, by its operation id such as
+// "ListUsers". Client operations are never allowed to users.
+func (c *ClientWithResponses) Can(ctx context.Context, operation string) (bool, error) {
+	if access, ok := OperationPermissions[operation]; ok && access.Public {
+		return true, nil
+	} else if ok && access.Client {
+		return false, nil
+	}
+	err := c.RequirePermissions(ctx, operation)
+	if errors.Is(err, ErrPermissionDenied) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RequirePermissions returns a *MissingPermissionsError naming every operation the current user lacks the
+// permissions for, so a job can fail before making any change
+func (c *ClientWithResponses) RequirePermissions(ctx context.Context, operations ...string) error {
+	user, err := c.self(ctx)
+	if err != nil {
+		return err
+	}
+	granted := map[Permission]bool{}
+	for _, permission := range UserPermissions(user) {
+		granted[permission] = true
+	}
+
+	denied := map[string][]Permission{}
+	for _, operation := range operations {
+		missing, err := missingPermissions(operation, granted)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			denied[operation] = missing
+		}
+	}
+	if len(denied) > 0 {
+		return &MissingPermissionsError{Operations: denied}
+	}
+	return nil
+}
+
+func (c *ClientWithResponses) self(ctx context.Context) (ModelUser, error) {
+	response, err := c.GetSelfWithResponse(ctx, nil)
+	if err != nil {
+		return ModelUser{}, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil {
+		return ModelUser{}, newStatusError("GetSelf", response.StatusCode(), response.Body)
+	}
+	return response.JSON200.Data.AsModelUser()
+}
+
+// PrivilegePolicy declares the permissions users need. Entries are permissions such as "graphdb.Read" or
+// operation ids such as "RunCypherQuery", which stand for the permissions the operation needs.
+type PrivilegePolicy struct {
+	// Default applies to every user
+	Default []string `json:"default,omitempty"`
+
+	// Users adds to Default by principal name, matched case-insensitively
+	Users map[string][]string `json:"users,omitempty"`
+}
+
+// Permissions the entries stand for
+func resolvePolicyEntries(entries []string, allowed map[Permission]bool) error {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, ".") {
+			permission, err := ParsePermission(entry)
+			if err != nil {
+				return err
+			}
+			allowed[permission] = true
+		} else if access, ok := OperationPermissions[entry]; ok {
+			for _, permission := range access.Permissions {
+				allowed[permission] = true
+			}
+		} else {
+			return fmt.Errorf("policy entry %q is neither a permission nor an operation id", entry)
+		}
+	}
+	return nil
+}
+
+// OverPrivilegedUser is a user holding permissions beyond what the policy allows them
+type OverPrivilegedUser struct {
+	UserId        openapi_types.UUID `json:"user_id"`
+	PrincipalName string             `json:"principal_name"`
+	Roles         []string           `json:"roles"`
+	Excess        []Permission       `json:"excess_permissions"`
+}
+
+// PrivilegeReport compares the permissions of the enabled users with a PrivilegePolicy
+type PrivilegeReport struct {
+	GeneratedAt    time.Time            `json:"generated_at"`
+	UsersChecked   int                  `json:"users_checked"`
+	OverPrivileged []OverPrivilegedUser `json:"over_privileged"`
+
+	// UnknownUsers are the principal names of the policy no user has
+	UnknownUsers []string `json:"unknown_users,omitempty"`
+}
+
+// BuildPrivilegeReport finds the enabled users whose roles grant permissions the policy does not allow them.
+// Role permissions come from ListRoles, so roles edited since a user was loaded are judged as they are now.
+func (c *ClientWithResponses) BuildPrivilegeReport(ctx context.Context, policy PrivilegePolicy) (*PrivilegeReport, error) {
+	defaults := map[Permission]bool{}
+	if err := resolvePolicyEntries(policy.Default, defaults); err != nil {
+		return nil, err
+	}
+	declared := map[string][]string{}
+	for principal, entries := range policy.Users {
+		if err := resolvePolicyEntries(entries, map[Permission]bool{}); err != nil {
+			return nil, fmt.Errorf("policy for %s: %w", principal, err)
+		}
+		key := strings.ToLower(principal)
+		declared[key] = append(declared[key], entries...)
+	}
+
+	roles, err := c.rolesById(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users, err := c.usersByPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PrivilegeReport{GeneratedAt: time.Now().UTC()}
+	for _, key := range sortedKeys(users) {
+		user := users[key]
+		if user.IsDisabled != nil && *user.IsDisabled {
+			continue
+		}
+		report.UsersChecked++
+
+		allowed := map[Permission]bool{}
+		for permission := range defaults {
+			allowed[permission] = true
+		}
+		_ = resolvePolicyEntries(declared[key], allowed)
+
+		granted := map[Permission]bool{}
+		var roleNames []string
+		if user.Roles != nil {
+			for _, role := range *user.Roles {
+				if role.Id != nil {
+					if current, ok := roles[*role.Id]; ok {
+						role = current
+					}
+				}
+				if role.Name != nil {
+					roleNames = append(roleNames, *role.Name)
+				}
+				addRolePermissions(granted, role)
+			}
+		}
+
+		excess := map[Permission]bool{}
+		for permission := range granted {
+			if !allowed[permission] {
+				excess[permission] = true
+			}
+		}
+		if len(excess) > 0 {
+			sort.Strings(roleNames)
+			report.OverPrivileged = append(report.OverPrivileged, OverPrivilegedUser{
+				UserId:        *user.Id,
+				PrincipalName: *user.PrincipalName,
+				Roles:         roleNames,
+				Excess:        sortedPermissions(excess),
+			})
+		}
+	}
+
+	for _, key := range sortedKeys(declared) {
+		if _, ok := users[key]; !ok {
+			report.UnknownUsers = append(report.UnknownUsers, key)
+		}
+	}
+	return report, nil
+}
+
+// Roles with their permissions by role id
+func (c *ClientWithResponses) rolesById(ctx context.Context) (map[int32]ModelRole, error) {
+	response, err := c.ListRolesWithResponse(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, newStatusError("ListRoles", response.StatusCode(), response.Body)
+	}
+	roles := map[int32]ModelRole{}
+	if response.JSON200.Data != nil && response.JSON200.Data.Roles != nil {
+		for _, role := range *response.JSON200.Data.Roles {
+			if role.Id != nil {
+				roles[*role.Id] = role
+			}
+		}
+	}
+	return roles, nil
+}
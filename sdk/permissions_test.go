@@ -0,0 +1,126 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOperationPermissionsCoverOperations(t *testing.T) {
+	for operation := range Operations {
+		if _, ok := OperationPermissions[operation]; !ok {
+			t.Errorf("%s is missing from OperationPermissions", operation)
+		}
+	}
+	for operation, access := range OperationPermissions {
+		if _, ok := Operations[operation]; !ok {
+			t.Errorf("%s is in OperationPermissions but not in Operations", operation)
+		}
+		if access.Public && (access.Client || len(access.Permissions) > 0) {
+			t.Errorf("%s is public but also needs a client or permissions", operation)
+		}
+	}
+}
+
+const (
+	testAdministratorRole = `{"id":1,"name":"Administrator","permissions":[{"authority":"auth","name":"ManageUsers"},{"authority":"graphdb","name":"Read"},{"authority":"graphdb","name":"Write"}]}`
+	testReadOnlyRole      = `{"id":3,"name":"Read-Only","permissions":[{"authority":"graphdb","name":"Read"}]}`
+)
+
+func permissionsServer(t *testing.T) *ClientWithResponses {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/self":
+			fmt.Fprintf(w, `{"data":{"id":"22222222-2222-2222-2222-222222222222","principal_name":"bob","roles":[%s]}}`, testReadOnlyRole)
+		case "/api/v2/roles":
+			fmt.Fprintf(w, `{"data":{"roles":[%s,%s]}}`, testAdministratorRole, testReadOnlyRole)
+		case "/api/v2/bloodhound-users":
+			// Users carry role ids only, the permissions come from the roles listing
+			fmt.Fprint(w, `{"data":{"users":[
+				{"id":"11111111-1111-1111-1111-111111111111","principal_name":"alice","roles":[{"id":1}]},
+				{"id":"22222222-2222-2222-2222-222222222222","principal_name":"bob","roles":[{"id":3}]},
+				{"id":"33333333-3333-3333-3333-333333333333","principal_name":"carol","is_disabled":true,"roles":[{"id":1}]}]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestCanChecksPermissionsOfCurrentUser(t *testing.T) {
+	client := permissionsServer(t)
+	for operation, want := range map[string]bool{
+		"RunCypherQuery": true,
+		"ListUsers":      false,
+		"GetSelf":        true,
+	} {
+		allowed, err := client.Can(context.Background(), operation)
+		if err != nil {
+			t.Fatalf("%s: %v", operation, err)
+		}
+		if allowed != want {
+			t.Errorf("Can(%s) = %v, want %v", operation, allowed, want)
+		}
+	}
+
+	err := client.RequirePermissions(context.Background(), "RunCypherQuery", "ListUsers")
+	var missing *MissingPermissionsError
+	if !errors.Is(err, ErrPermissionDenied) || !errors.As(err, &missing) {
+		t.Fatalf("RequirePermissions returned %v, want a MissingPermissionsError", err)
+	}
+	if len(missing.Operations) != 1 || fmt.Sprint(missing.Operations["ListUsers"]) != fmt.Sprint([]Permission{PermissionAuthManageUsers}) {
+		t.Errorf("missing permissions are %v, want only auth.ManageUsers for ListUsers", missing.Operations)
+	}
+}
+
+func TestBuildPrivilegeReportFindsExcessPermissions(t *testing.T) {
+	report, err := permissionsServer(t).BuildPrivilegeReport(context.Background(), PrivilegePolicy{
+		Default: []string{"graphdb.Read"},
+		Users: map[string][]string{
+			"Alice": {"ListUsers"},
+			"dave":  {"graphdb.Write"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.UsersChecked != 2 {
+		t.Errorf("checked %d users, want the 2 enabled ones", report.UsersChecked)
+	}
+	if len(report.OverPrivileged) != 1 {
+		t.Fatalf("over privileged users are %+v, want only alice", report.OverPrivileged)
+	}
+	alice := report.OverPrivileged[0]
+	if alice.PrincipalName != "alice" || fmt.Sprint(alice.Roles) != "[Administrator]" || fmt.Sprint(alice.Excess) != fmt.Sprint([]Permission{PermissionGraphDBWrite}) {
+		t.Errorf("alice is reported as %+v, want graphdb.Write in excess through Administrator", alice)
+	}
+	if fmt.Sprint(report.UnknownUsers) != "[dave]" {
+		t.Errorf("unknown users are %v, want [dave]", report.UnknownUsers)
+	}
+}
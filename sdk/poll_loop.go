@@ -0,0 +1,118 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"time"
+)
+
+// pollLoop is the follow loop of the polling streams. The items of each poll are delivered in order, then the
+// loop waits for the next tick. When deliver refuses an item, the items not handled are handed to rewind so the
+// next poll returns them again.
+type pollLoop[T any] struct {
+	interval time.Duration
+
+	// poll returns the new items, and done when following should stop once they are delivered
+	poll   func(ctx context.Context) (items []T, done bool, err error)
+	rewind func(undelivered []T)
+
+	// commit, when set, runs after the items of a poll are delivered and after a rewind
+	commit func(ctx context.Context) error
+}
+
+// Run the loop until the context is cancelled, deliver refuses an item, a poll reports done or fails. The error
+// that stopped the loop is returned; context cancellation is not an error. The item deliver refuses was never
+// handed over, as with a channel send abandoned on cancellation, so it is rewound with the rest.
+func (l pollLoop[T]) follow(ctx context.Context, deliver func(T) bool) error {
+	return l.run(ctx, deliver, false)
+}
+
+// Run the loop for an iterator. Its yield returns false once the loop body ran and broke, so the item it refuses
+// counts as handled and only the items after it are rewound.
+func (l pollLoop[T]) iterate(ctx context.Context, yield func(T) bool) error {
+	return l.run(ctx, yield, true)
+}
+
+func (l pollLoop[T]) run(ctx context.Context, deliver func(T) bool, refusedHandled bool) error {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		items, done, err := l.poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for i, item := range items {
+			if !deliver(item) {
+				if refusedHandled {
+					i++
+				}
+				l.rewind(items[i:])
+				if l.commit != nil {
+					return l.commit(context.WithoutCancel(ctx))
+				}
+				return nil
+			}
+		}
+		if l.commit != nil {
+			if err := l.commit(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run follow in a goroutine delivering on a channel. The error channel receives at most one error and both
+// channels are closed when follow returns.
+func followChannel[T any](ctx context.Context, follow func(context.Context, func(T) bool) error) (<-chan T, <-chan error) {
+	items := make(chan T)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(items)
+		defer close(errs)
+
+		err := follow(ctx, func(item T) bool {
+			select {
+			case items <- item:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return items, errs
+}
@@ -0,0 +1,461 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportFormat selects how a report is rendered
+type ReportFormat string
+
+const (
+	ReportFormatMarkdown ReportFormat = "markdown"
+	ReportFormatHTML     ReportFormat = "html"
+	ReportFormatJSON     ReportFormat = "json"
+)
+
+// PostureMetric names a time series tracked by the posture report. Every metric is "higher is worse".
+type PostureMetric string
+
+const (
+	PostureMetricExposureIndex      PostureMetric = "exposure_index"
+	PostureMetricTierZeroCount      PostureMetric = "tier_zero_count"
+	PostureMetricCriticalRiskCount  PostureMetric = "critical_risk_count"
+	PostureMetricCompositeRisk      PostureMetric = "composite_risk"
+	PostureMetricFindingCount       PostureMetric = "finding_count"
+	PostureMetricImpactedAssetCount PostureMetric = "impacted_asset_count"
+)
+
+// Metrics sourced from GetPostureStats and ListAttackPathSparklineValues, in report order
+var (
+	postureStatMetrics = []PostureMetric{PostureMetricExposureIndex, PostureMetricTierZeroCount, PostureMetricCriticalRiskCount}
+	sparklineMetrics   = []PostureMetric{PostureMetricCompositeRisk, PostureMetricFindingCount, PostureMetricImpactedAssetCount}
+)
+
+const week = 7 * 24 * time.Hour
+
+// PostureReportOptions controls the window, domains and alignment of a posture report
+type PostureReportOptions struct {
+	// DomainSIDs to report on. Required.
+	DomainSIDs []string
+
+	// From and To bound the window. To defaults to now and From to 30 days before To.
+	From time.Time
+	To   time.Time
+
+	// Interval every series is aligned to. Defaults to one day.
+	Interval time.Duration
+
+	// Findings passed to ListAttackPathSparklineValues. Sparkline values are summed across findings.
+	// When empty every finding available for the domain is used.
+	Findings []string
+
+	// RegressionThreshold is the relative increase between two aligned points, as a fraction, above
+	// which the increase is reported as a regression. Zero reports every increase.
+	RegressionThreshold float64
+}
+
+// PosturePoint is a value aligned to the start of an interval. Filled is set when the value was carried
+// forward from an earlier interval because the server reported nothing in this one.
+type PosturePoint struct {
+	Time   time.Time `json:"time"`
+	Value  float64   `json:"value"`
+	Filled bool      `json:"filled,omitempty"`
+}
+
+// PostureRegression records an increase of a metric between two consecutive aligned points
+type PostureRegression struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Before float64   `json:"before"`
+	After  float64   `json:"after"`
+}
+
+// PostureMetricTrend is an aligned series together with its summary statistics
+type PostureMetricTrend struct {
+	Metric PostureMetric  `json:"metric"`
+	Points []PosturePoint `json:"points"`
+	First  float64        `json:"first"`
+	Last   float64        `json:"last"`
+	Delta  float64        `json:"delta"`
+
+	// WeekOverWeek is the relative change, as a fraction, between the last point and the point one week
+	// earlier. Nil when the series is shorter than a week or the earlier value is zero.
+	WeekOverWeek *float64            `json:"week_over_week,omitempty"`
+	Regressions  []PostureRegression `json:"regressions,omitempty"`
+}
+
+// DomainPostureTrend groups the metric trends of one domain
+type DomainPostureTrend struct {
+	DomainSID string               `json:"domain_sid"`
+	Metrics   []PostureMetricTrend `json:"metrics"`
+}
+
+// PostureReport is the result of BuildPostureReport and renders to Markdown, HTML or JSON
+type PostureReport struct {
+	GeneratedAt time.Time            `json:"generated_at"`
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Interval    time.Duration        `json:"interval"`
+	Domains     []DomainPostureTrend `json:"domains"`
+}
+
+type postureSample struct {
+	time  time.Time
+	value float64
+}
+
+// Fetch the posture stats and sparkline series of each domain over the window, align them to a common
+// interval and compute deltas, regressions and week over week change
+func BuildPostureReport(ctx context.Context, client ClientWithResponsesInterface, options PostureReportOptions) (*PostureReport, error) {
+	if len(options.DomainSIDs) == 0 {
+		return nil, errors.New("at least one domain SID is required")
+	}
+	if options.To.IsZero() {
+		options.To = time.Now()
+	}
+	if options.From.IsZero() {
+		options.From = options.To.Add(-30 * 24 * time.Hour)
+	}
+	if options.Interval <= 0 {
+		options.Interval = 24 * time.Hour
+	}
+	if !options.From.Before(options.To) {
+		return nil, fmt.Errorf("report window start %s is not before its end %s", options.From.Format(time.RFC3339), options.To.Format(time.RFC3339))
+	}
+
+	report := &PostureReport{
+		GeneratedAt: time.Now().UTC(),
+		From:        options.From.UTC(),
+		To:          options.To.UTC(),
+		Interval:    options.Interval,
+	}
+	buckets := postureBuckets(report.From, report.To, options.Interval)
+
+	for _, domainSID := range options.DomainSIDs {
+		statSeries, err := fetchPostureStats(ctx, client, domainSID, report.From, report.To)
+		if err != nil {
+			return nil, err
+		}
+		sparklineSeries, err := fetchSparklineSeries(ctx, client, domainSID, options.Findings, report.From, report.To, buckets, options.Interval)
+		if err != nil {
+			return nil, err
+		}
+
+		domain := DomainPostureTrend{DomainSID: domainSID}
+		for _, metric := range postureStatMetrics {
+			domain.Metrics = append(domain.Metrics, newPostureMetricTrend(metric, alignPostureSamples(statSeries[metric], buckets, options.Interval), options))
+		}
+		for _, metric := range sparklineMetrics {
+			domain.Metrics = append(domain.Metrics, newPostureMetricTrend(metric, sparklineSeries[metric], options))
+		}
+		report.Domains = append(report.Domains, domain)
+	}
+	return report, nil
+}
+
+func fetchPostureStats(ctx context.Context, client ClientWithResponsesInterface, domainSID string, from, to time.Time) (map[PostureMetric][]postureSample, error) {
+	// The window goes through the from and to parameters so that it is part of the query the client signs
+	response, err := client.GetPostureStatsWithResponse(ctx, &GetPostureStatsParams{
+		DomainSid:      ptr("eq:" + domainSID),
+		SortBy:         ptr("created_at"),
+		FromDeprecated: ptr(from.UTC()),
+		ToDeprecated:   ptr(to.UTC()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != 200 || response.JSON200 == nil {
+		return nil, newStatusError("GetPostureStats", response.StatusCode(), response.Body)
+	}
+
+	series := map[PostureMetric][]postureSample{}
+	if response.JSON200.Data == nil {
+		return series, nil
+	}
+	for _, stat := range *response.JSON200.Data {
+		// Servers that ignore the created_at filter return the whole history
+		if stat.CreatedAt == nil || stat.CreatedAt.Before(from) || !stat.CreatedAt.Before(to) {
+			continue
+		}
+		if stat.ExposureIndex != nil {
+			series[PostureMetricExposureIndex] = append(series[PostureMetricExposureIndex], postureSample{*stat.CreatedAt, *stat.ExposureIndex})
+		}
+		if stat.TierZeroCount != nil {
+			series[PostureMetricTierZeroCount] = append(series[PostureMetricTierZeroCount], postureSample{*stat.CreatedAt, float64(*stat.TierZeroCount)})
+		}
+		if stat.CriticalRiskCount != nil {
+			series[PostureMetricCriticalRiskCount] = append(series[PostureMetricCriticalRiskCount], postureSample{*stat.CreatedAt, float64(*stat.CriticalRiskCount)})
+		}
+	}
+	return series, nil
+}
+
+// The sparkline reports one value per finding, so each finding is aligned on its own before the findings are
+// summed into a single point per interval
+func fetchSparklineSeries(ctx context.Context, client ClientWithResponsesInterface, domainSID string, findings []string, from, to time.Time, buckets []time.Time, interval time.Duration) (map[PostureMetric][]PosturePoint, error) {
+	if len(findings) == 0 {
+		available, err := client.ListAvailableAttackPathTypesForDomainWithResponse(ctx, domainSID, nil)
+		if err != nil {
+			return nil, err
+		}
+		if available.StatusCode() != 200 || available.JSON200 == nil {
+			return nil, newStatusError("ListAvailableAttackPathTypesForDomain", available.StatusCode(), available.Body)
+		}
+		if available.JSON200.Data != nil {
+			findings = *available.JSON200.Data
+		}
+	}
+
+	perFinding := map[string]map[PostureMetric][]postureSample{}
+	for _, finding := range findings {
+		response, err := client.ListAttackPathSparklineValuesWithResponse(ctx, domainSID, &ListAttackPathSparklineValuesParams{
+			Finding: "eq:" + finding,
+			From:    ptr(from),
+			To:      ptr(to),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode() != 200 || response.JSON200 == nil {
+			return nil, newStatusError("ListAttackPathSparklineValues", response.StatusCode(), response.Body)
+		}
+		if response.JSON200.Data == nil {
+			continue
+		}
+
+		for _, counts := range *response.JSON200.Data {
+			if counts.CreatedAt == nil {
+				continue
+			}
+			name := finding
+			if counts.Finding != nil {
+				name = *counts.Finding
+			}
+			if perFinding[name] == nil {
+				perFinding[name] = map[PostureMetric][]postureSample{}
+			}
+			series := perFinding[name]
+			if counts.CompositeRisk != nil {
+				series[PostureMetricCompositeRisk] = append(series[PostureMetricCompositeRisk], postureSample{*counts.CreatedAt, *counts.CompositeRisk})
+			}
+			if counts.FindingCount != nil {
+				series[PostureMetricFindingCount] = append(series[PostureMetricFindingCount], postureSample{*counts.CreatedAt, float64(*counts.FindingCount)})
+			}
+			if counts.ImpactedAssetCount != nil {
+				series[PostureMetricImpactedAssetCount] = append(series[PostureMetricImpactedAssetCount], postureSample{*counts.CreatedAt, float64(*counts.ImpactedAssetCount)})
+			}
+		}
+	}
+
+	summed := map[PostureMetric][]PosturePoint{}
+	for _, metric := range sparklineMetrics {
+		var total []PosturePoint
+		for _, series := range perFinding {
+			total = sumPosturePoints(total, alignPostureSamples(series[metric], buckets, interval))
+		}
+		summed[metric] = total
+	}
+	return summed, nil
+}
+
+func postureBuckets(from, to time.Time, interval time.Duration) []time.Time {
+	var buckets []time.Time
+	for bucket := from.Truncate(interval); bucket.Before(to); bucket = bucket.Add(interval) {
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// Align samples so there is one point per bucket holding the last sample seen in it. Empty buckets carry the
+// previous value forward and buckets before the first sample are dropped.
+func alignPostureSamples(samples []postureSample, buckets []time.Time, interval time.Duration) []PosturePoint {
+	if len(samples) == 0 || len(buckets) == 0 {
+		return nil
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].time.Before(samples[j].time)
+	})
+
+	var (
+		points  []PosturePoint
+		next    int
+		current *float64
+	)
+	for _, bucket := range buckets {
+		end := bucket.Add(interval)
+		filled := true
+		for next < len(samples) && samples[next].time.Before(end) {
+			current = ptr(samples[next].value)
+			filled = false
+			next++
+		}
+		if current == nil {
+			continue
+		}
+		points = append(points, PosturePoint{Time: bucket, Value: *current, Filled: filled})
+	}
+	return points
+}
+
+func sumPosturePoints(total, points []PosturePoint) []PosturePoint {
+	byTime := map[time.Time]int{}
+	for i, point := range total {
+		byTime[point.Time] = i
+	}
+	for _, point := range points {
+		if i, ok := byTime[point.Time]; ok {
+			total[i].Value += point.Value
+			total[i].Filled = total[i].Filled && point.Filled
+		} else {
+			byTime[point.Time] = len(total)
+			total = append(total, point)
+		}
+	}
+	sort.Slice(total, func(i, j int) bool {
+		return total[i].Time.Before(total[j].Time)
+	})
+	return total
+}
+
+func newPostureMetricTrend(metric PostureMetric, points []PosturePoint, options PostureReportOptions) PostureMetricTrend {
+	trend := PostureMetricTrend{
+		Metric: metric,
+		Points: points,
+	}
+	if len(points) == 0 {
+		return trend
+	}
+
+	trend.First = points[0].Value
+	trend.Last = points[len(points)-1].Value
+	trend.Delta = trend.Last - trend.First
+
+	for i := 1; i < len(points); i++ {
+		before, after := points[i-1].Value, points[i].Value
+		if after <= before {
+			continue
+		}
+		if before != 0 && (after-before)/before <= options.RegressionThreshold {
+			continue
+		}
+		trend.Regressions = append(trend.Regressions, PostureRegression{
+			From:   points[i-1].Time,
+			To:     points[i].Time,
+			Before: before,
+			After:  after,
+		})
+	}
+
+	// Use the latest point at or before one week prior to the last point
+	last := points[len(points)-1]
+	for i := len(points) - 1; i >= 0; i-- {
+		if last.Time.Sub(points[i].Time) >= week {
+			if points[i].Value != 0 {
+				trend.WeekOverWeek = ptr((last.Value - points[i].Value) / points[i].Value)
+			}
+			break
+		}
+	}
+	return trend
+}
+
+// Render the report in the given format
+func (r *PostureReport) Render(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportFormatMarkdown:
+		return r.RenderMarkdown(w)
+	case ReportFormatHTML:
+		return r.RenderHTML(w)
+	case ReportFormatJSON:
+		return r.RenderJSON(w)
+	default:
+		return fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+func (r *PostureReport) RenderJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func (r *PostureReport) RenderMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Posture report\n\n")
+	fmt.Fprintf(&b, "Window: %s to %s, interval %s\n", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339), r.Interval)
+	for _, domain := range r.Domains {
+		fmt.Fprintf(&b, "\n## %s\n\n", domain.DomainSID)
+		fmt.Fprintf(&b, "| Metric | First | Last | Delta | Week over week | Regressions |\n")
+		fmt.Fprintf(&b, "|--------|-------|------|-------|----------------|-------------|\n")
+		for _, trend := range domain.Metrics {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %d |\n",
+				trend.Metric, formatPostureValue(trend.First), formatPostureValue(trend.Last),
+				formatPostureDelta(trend.Delta), formatWeekOverWeek(trend.WeekOverWeek), len(trend.Regressions))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var postureReportHTMLTemplate = template.Must(template.New("posture").Funcs(template.FuncMap{
+	"value":        formatPostureValue,
+	"delta":        formatPostureDelta,
+	"weekOverWeek": formatWeekOverWeek,
+	"rfc3339":      func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Posture report</title></head>
+<body>
+<h1>Posture report</h1>
+<p>Window: {{rfc3339 .From}} to {{rfc3339 .To}}, interval {{.Interval}}</p>
+{{range .Domains}}<h2>{{.DomainSID}}</h2>
+<table>
+<tr><th>Metric</th><th>First</th><th>Last</th><th>Delta</th><th>Week over week</th><th>Regressions</th></tr>
+{{range .Metrics}}<tr><td>{{.Metric}}</td><td>{{value .First}}</td><td>{{value .Last}}</td><td>{{delta .Delta}}</td><td>{{weekOverWeek .WeekOverWeek}}</td><td>{{len .Regressions}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+func (r *PostureReport) RenderHTML(w io.Writer) error {
+	return postureReportHTMLTemplate.Execute(w, r)
+}
+
+func formatPostureValue(value float64) string {
+	return fmt.Sprintf("%.2f", value)
+}
+
+func formatPostureDelta(value float64) string {
+	return fmt.Sprintf("%+.2f", value)
+}
+
+func formatWeekOverWeek(value *float64) string {
+	if value == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", *value*100)
+}
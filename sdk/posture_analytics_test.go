@@ -0,0 +1,53 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFetchPostureStatsSignsWindow(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	client := signedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("from") != from.Format(time.RFC3339) || query.Get("to") != to.Format(time.RFC3339) {
+			t.Errorf("window not sent: %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": [
+			{"created_at": "2024-04-30T00:00:00Z", "exposure_index": 1},
+			{"created_at": "2024-05-02T00:00:00Z", "exposure_index": 2, "tier_zero_count": 3},
+			{"created_at": "2024-05-08T00:00:00Z", "exposure_index": 4}
+		]}`))
+	}))
+
+	series, err := fetchPostureStats(context.Background(), client, "S-1-5-21-1", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exposure := series[PostureMetricExposureIndex]
+	if len(exposure) != 1 || exposure[0].value != 2 {
+		t.Fatalf("exposure series = %+v, want the single sample inside the window", exposure)
+	}
+	if tierZero := series[PostureMetricTierZeroCount]; len(tierZero) != 1 || tierZero[0].value != 3 {
+		t.Fatalf("tier zero series = %+v", tierZero)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OperationClass groups operations that share a default server side timeout
+type OperationClass string
+
+const (
+	OperationClassGraphQuery OperationClass = "graph_query"
+	OperationClassEntityRead OperationClass = "entity_read"
+	OperationClassRead       OperationClass = "read"
+	OperationClassWrite      OperationClass = "write"
+)
+
+// Operations that walk the graph and may legitimately run for minutes
+var graphQueryOperations = map[string]struct{}{
+	"RunCypherQuery":     {},
+	"GetShortestPath":    {},
+	"Pathfinding":        {},
+	"GetPathComposition": {},
+	"GetComboTreeGraph":  {},
+	"GetSearchResult":    {},
+}
+
+// ClassifyOperation returns the class of an operation id
+func ClassifyOperation(operation string) OperationClass {
+	if _, ok := graphQueryOperations[operation]; ok {
+		return OperationClassGraphQuery
+	}
+	info, ok := Operations[operation]
+	if ok && info.Method != http.MethodGet {
+		return OperationClassWrite
+	}
+	if strings.Contains(operation, "Entity") {
+		return OperationClassEntityRead
+	}
+	return OperationClassRead
+}
+
+// PreferWaitPolicy fills in the RFC 7240 "Prefer: wait=N" header on requests that do not set one through their
+// params. The wait is the default of the operation class, shortened to the time left before the context
+// deadline so the server gives up no later than the caller.
+type PreferWaitPolicy struct {
+	// Defaults per operation class. Classes without an entry send no header unless the context has a deadline.
+	Defaults map[OperationClass]time.Duration
+
+	// Overrides by operation id, such as "RunCypherQuery"
+	Overrides map[string]time.Duration
+}
+
+// NewPreferWaitPolicy returns a policy with long waits for graph queries and short waits for entity reads
+func NewPreferWaitPolicy() *PreferWaitPolicy {
+	return &PreferWaitPolicy{
+		Defaults: map[OperationClass]time.Duration{
+			OperationClassGraphQuery: 5 * time.Minute,
+			OperationClassEntityRead: 15 * time.Second,
+			OperationClassRead:       30 * time.Second,
+			OperationClassWrite:      60 * time.Second,
+		},
+		Overrides: map[string]time.Duration{},
+	}
+}
+
+// Wait returns the wait to send for an operation given the context, and false if no header should be sent
+func (p *PreferWaitPolicy) Wait(ctx context.Context, operation string) (time.Duration, bool) {
+	wait, ok := p.Overrides[operation]
+	if !ok {
+		wait, ok = p.Defaults[ClassifyOperation(operation)]
+	}
+
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
+		remaining := time.Until(deadline)
+		if !ok || remaining < wait {
+			wait, ok = remaining, true
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+
+	// The header carries whole seconds, and a wait of zero would ask the server not to wait at all
+	wait = wait.Truncate(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait, true
+}
+
+// Intercept is a RequestEditorFn applying the policy
+func (p *PreferWaitPolicy) Intercept(ctx context.Context, req *http.Request) error {
+	if req.Header.Get("Prefer") != "" {
+		return nil
+	}
+	operation, _ := OperationForRequest(req)
+	if wait, ok := p.Wait(ctx, operation); ok {
+		req.Header.Set("Prefer", fmt.Sprintf("wait=%d", int(wait/time.Second)))
+	}
+	return nil
+}
+
+// WithPreferWaitPolicy applies the policy to every request of the client
+func WithPreferWaitPolicy(policy *PreferWaitPolicy) ClientOption {
+	return WithRequestEditorFn(policy.Intercept)
+}
@@ -0,0 +1,431 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Page size used when walking the paginated attack path details listing
+const attackPathDetailsPageSize = 100
+
+// AttackPathFinding is the flattened form of the list and relationship findings returned by
+// ListDomainAttackPathsDetails. Principal is set for list findings, FromPrincipal and ToPrincipal for
+// relationship findings.
+type AttackPathFinding struct {
+	Id            int64
+	DomainSID     string
+	Finding       string
+	Principal     string
+	FromPrincipal string
+	ToPrincipal   string
+	Accepted      bool
+	AcceptedUntil *time.Time
+}
+
+// The list and relationship finding schemas disagree on the casing of the accepted until key
+type attackPathFindingJSON struct {
+	Id                 *int64     `json:"id"`
+	DomainSID          string     `json:"DomainSID"`
+	Finding            string     `json:"Finding"`
+	Principal          string     `json:"Principal"`
+	FromPrincipal      string     `json:"FromPrincipal"`
+	ToPrincipal        string     `json:"ToPrincipal"`
+	Accepted           bool       `json:"Accepted"`
+	AcceptedUntil      *time.Time `json:"AcceptedUntil"`
+	AcceptedUntilSnake *time.Time `json:"accepted_until"`
+}
+
+func (s attackPathFindingJSON) finding() AttackPathFinding {
+	finding := AttackPathFinding{
+		DomainSID:     s.DomainSID,
+		Finding:       s.Finding,
+		Principal:     s.Principal,
+		FromPrincipal: s.FromPrincipal,
+		ToPrincipal:   s.ToPrincipal,
+		Accepted:      s.Accepted,
+	}
+	if s.Id != nil {
+		finding.Id = *s.Id
+	}
+
+	// The server reports the zero time for findings that were never accepted
+	acceptedUntil := s.AcceptedUntil
+	if acceptedUntil == nil {
+		acceptedUntil = s.AcceptedUntilSnake
+	}
+	if acceptedUntil != nil && !acceptedUntil.IsZero() {
+		finding.AcceptedUntil = acceptedUntil
+	}
+	return finding
+}
+
+// RiskAcceptance is a ledger entry recording why and until when a finding was accepted
+type RiskAcceptance struct {
+	AttackPathId  int64     `json:"attack_path_id"`
+	DomainSID     string    `json:"domain_sid"`
+	Finding       string    `json:"finding"`
+	Principal     string    `json:"principal,omitempty"`
+	FromPrincipal string    `json:"from_principal,omitempty"`
+	ToPrincipal   string    `json:"to_principal,omitempty"`
+	AcceptedUntil time.Time `json:"accepted_until"`
+	Justification string    `json:"justification"`
+	RecordedAt    time.Time `json:"recorded_at"`
+}
+
+// RiskAcceptanceLedger stores the justifications for accepted risks, which the API has no field for
+type RiskAcceptanceLedger interface {
+	Record(acceptance RiskAcceptance) error
+	Remove(attackPathId int64) error
+	Get(attackPathId int64) (RiskAcceptance, bool, error)
+	List() ([]RiskAcceptance, error)
+}
+
+// MemoryRiskAcceptanceLedger keeps ledger entries in memory only
+type MemoryRiskAcceptanceLedger struct {
+	mu      sync.Mutex
+	entries map[int64]RiskAcceptance
+}
+
+func NewMemoryRiskAcceptanceLedger() *MemoryRiskAcceptanceLedger {
+	return &MemoryRiskAcceptanceLedger{
+		entries: map[int64]RiskAcceptance{},
+	}
+}
+
+func (l *MemoryRiskAcceptanceLedger) Record(acceptance RiskAcceptance) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[acceptance.AttackPathId] = acceptance
+	return nil
+}
+
+func (l *MemoryRiskAcceptanceLedger) Remove(attackPathId int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, attackPathId)
+	return nil
+}
+
+func (l *MemoryRiskAcceptanceLedger) Get(attackPathId int64) (RiskAcceptance, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	acceptance, ok := l.entries[attackPathId]
+	return acceptance, ok, nil
+}
+
+func (l *MemoryRiskAcceptanceLedger) List() ([]RiskAcceptance, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return sortedRiskAcceptances(l.entries), nil
+}
+
+// FileRiskAcceptanceLedger keeps ledger entries in a JSON file that is rewritten on every change
+type FileRiskAcceptanceLedger struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFileRiskAcceptanceLedger(path string) (*FileRiskAcceptanceLedger, error) {
+	if path == "" {
+		return nil, errors.New("ledger path must not be empty")
+	}
+	return &FileRiskAcceptanceLedger{
+		Path: path,
+	}, nil
+}
+
+func (l *FileRiskAcceptanceLedger) Record(acceptance RiskAcceptance) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, err := l.load()
+	if err != nil {
+		return err
+	}
+	entries[acceptance.AttackPathId] = acceptance
+	return l.save(entries)
+}
+
+func (l *FileRiskAcceptanceLedger) Remove(attackPathId int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, err := l.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[attackPathId]; !ok {
+		return nil
+	}
+	delete(entries, attackPathId)
+	return l.save(entries)
+}
+
+func (l *FileRiskAcceptanceLedger) Get(attackPathId int64) (RiskAcceptance, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, err := l.load()
+	if err != nil {
+		return RiskAcceptance{}, false, err
+	}
+	acceptance, ok := entries[attackPathId]
+	return acceptance, ok, nil
+}
+
+func (l *FileRiskAcceptanceLedger) List() ([]RiskAcceptance, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	return sortedRiskAcceptances(entries), nil
+}
+
+func (l *FileRiskAcceptanceLedger) load() (map[int64]RiskAcceptance, error) {
+	entries := map[int64]RiskAcceptance{}
+	content, err := os.ReadFile(l.Path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var list []RiskAcceptance
+	if err := json.Unmarshal(content, &list); err != nil {
+		return nil, fmt.Errorf("error reading risk acceptance ledger %s: %w", l.Path, err)
+	}
+	for _, acceptance := range list {
+		entries[acceptance.AttackPathId] = acceptance
+	}
+	return entries, nil
+}
+
+func (l *FileRiskAcceptanceLedger) save(entries map[int64]RiskAcceptance) error {
+	content, err := json.MarshalIndent(sortedRiskAcceptances(entries), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(l.Path, content)
+}
+
+func sortedRiskAcceptances(entries map[int64]RiskAcceptance) []RiskAcceptance {
+	list := make([]RiskAcceptance, 0, len(entries))
+	for _, acceptance := range entries {
+		list = append(list, acceptance)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].AttackPathId < list[j].AttackPathId
+	})
+	return list
+}
+
+// RiskAcceptanceManager accepts, re-accepts and revokes attack path findings in bulk while keeping the
+// justification for each acceptance in a ledger
+type RiskAcceptanceManager struct {
+	Client ClientWithResponsesInterface
+	Ledger RiskAcceptanceLedger
+}
+
+func NewRiskAcceptanceManager(client ClientWithResponsesInterface, ledger RiskAcceptanceLedger) (*RiskAcceptanceManager, error) {
+	if client == nil {
+		return nil, errors.New("client must not be nil")
+	}
+	if ledger == nil {
+		ledger = NewMemoryRiskAcceptanceLedger()
+	}
+	return &RiskAcceptanceManager{
+		Client: client,
+		Ledger: ledger,
+	}, nil
+}
+
+// List every finding for the domain matching params, walking all pages. Skip and Limit in params are ignored.
+func (m *RiskAcceptanceManager) ListFindings(ctx context.Context, domainId string, params *ListDomainAttackPathsDetailsParams) ([]AttackPathFinding, error) {
+	var pageParams ListDomainAttackPathsDetailsParams
+	if params != nil {
+		pageParams = *params
+	}
+	pageParams.Limit = ptr(attackPathDetailsPageSize)
+
+	var findings []AttackPathFinding
+	for skip := 0; ; {
+		pageParams.Skip = ptr(skip)
+		response, err := m.Client.ListDomainAttackPathsDetailsWithResponse(ctx, domainId, &pageParams)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode() != 200 {
+			return nil, newStatusError("ListDomainAttackPathsDetails", response.StatusCode(), response.Body)
+		}
+
+		var page struct {
+			ApiResponsePagination
+			Data []attackPathFindingJSON `json:"data"`
+		}
+		if err := json.Unmarshal(response.Body, &page); err != nil {
+			return nil, fmt.Errorf("error decoding attack path details: %w", err)
+		}
+		for _, item := range page.Data {
+			findings = append(findings, item.finding())
+		}
+
+		skip += len(page.Data)
+		if len(page.Data) == 0 || (page.Count != nil && skip >= *page.Count) {
+			return findings, nil
+		}
+	}
+}
+
+// Accept every finding matching params and filter until the given time, recording the justification in the
+// ledger. A nil filter accepts every listed finding. The acceptances that succeeded are returned alongside
+// the joined errors of those that failed.
+func (m *RiskAcceptanceManager) AcceptFindings(ctx context.Context, domainId string, params *ListDomainAttackPathsDetailsParams, filter func(AttackPathFinding) bool, until time.Time, justification string) ([]RiskAcceptance, error) {
+	if justification == "" {
+		return nil, errors.New("a justification is required to accept risk")
+	}
+
+	findings, err := m.ListFindings(ctx, domainId, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []AttackPathFinding
+	for _, finding := range findings {
+		if filter == nil || filter(finding) {
+			selected = append(selected, finding)
+		}
+	}
+	return m.accept(ctx, selected, until, func(AttackPathFinding) string { return justification })
+}
+
+// List the accepted findings for the domain whose acceptance runs out within the given number of days,
+// including those that already expired but are still flagged as accepted
+func (m *RiskAcceptanceManager) Expiring(ctx context.Context, domainId string, days int) ([]AttackPathFinding, error) {
+	findings, err := m.ListFindings(ctx, domainId, &ListDomainAttackPathsDetailsParams{
+		Accepted: ptr("eq:true"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	var expiring []AttackPathFinding
+	for _, finding := range findings {
+		if finding.AcceptedUntil != nil && !finding.AcceptedUntil.After(deadline) {
+			expiring = append(expiring, finding)
+		}
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].AcceptedUntil.Before(*expiring[j].AcceptedUntil)
+	})
+	return expiring, nil
+}
+
+// Extend the acceptance of the given findings until the new time. The justification already in the ledger
+// is kept, unless justification is set in which case it replaces it.
+func (m *RiskAcceptanceManager) Reaccept(ctx context.Context, findings []AttackPathFinding, until time.Time, justification string) ([]RiskAcceptance, error) {
+	return m.accept(ctx, findings, until, func(finding AttackPathFinding) string {
+		if justification != "" {
+			return justification
+		}
+		if previous, ok, err := m.Ledger.Get(finding.Id); err == nil && ok {
+			return previous.Justification
+		}
+		return ""
+	})
+}
+
+// Revoke the acceptance of the given findings and drop them from the ledger. The ids of the revoked findings
+// are returned alongside the joined errors of those that failed.
+func (m *RiskAcceptanceManager) Revoke(ctx context.Context, findings []AttackPathFinding) ([]int64, error) {
+	var (
+		revoked []int64
+		errs    []error
+	)
+	for _, finding := range findings {
+		if err := m.update(ctx, finding, UpdateAttackPathRiskJSONBody{
+			Accepted: ptr(false),
+			RiskType: ptr(finding.Finding),
+		}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := m.Ledger.Remove(finding.Id); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		revoked = append(revoked, finding.Id)
+	}
+	return revoked, errors.Join(errs...)
+}
+
+func (m *RiskAcceptanceManager) accept(ctx context.Context, findings []AttackPathFinding, until time.Time, justification func(AttackPathFinding) string) ([]RiskAcceptance, error) {
+	if !until.After(time.Now()) {
+		return nil, fmt.Errorf("accept until %s is not in the future", until.Format(time.RFC3339))
+	}
+
+	var (
+		accepted []RiskAcceptance
+		errs     []error
+	)
+	for _, finding := range findings {
+		if err := m.update(ctx, finding, UpdateAttackPathRiskJSONBody{
+			Accepted:    ptr(true),
+			AcceptUntil: ptr(until.UTC()),
+			RiskType:    ptr(finding.Finding),
+		}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		acceptance := RiskAcceptance{
+			AttackPathId:  finding.Id,
+			DomainSID:     finding.DomainSID,
+			Finding:       finding.Finding,
+			Principal:     finding.Principal,
+			FromPrincipal: finding.FromPrincipal,
+			ToPrincipal:   finding.ToPrincipal,
+			AcceptedUntil: until.UTC(),
+			Justification: justification(finding),
+			RecordedAt:    time.Now().UTC(),
+		}
+		if err := m.Ledger.Record(acceptance); err != nil {
+			errs = append(errs, fmt.Errorf("finding %d was accepted but not recorded: %w", finding.Id, err))
+			continue
+		}
+		accepted = append(accepted, acceptance)
+	}
+	return accepted, errors.Join(errs...)
+}
+
+func (m *RiskAcceptanceManager) update(ctx context.Context, finding AttackPathFinding, body UpdateAttackPathRiskJSONBody) error {
+	response, err := m.Client.UpdateAttackPathRiskWithResponse(ctx, finding.Id, nil, UpdateAttackPathRiskJSONRequestBody(body))
+	if err != nil {
+		return fmt.Errorf("error updating finding %d: %w", finding.Id, err)
+	}
+	if response.StatusCode() != 200 {
+		return fmt.Errorf("error updating finding %d: %w", finding.Id, newStatusError("UpdateAttackPathRisk", response.StatusCode(), response.Body))
+	}
+	return nil
+}
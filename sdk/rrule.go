@@ -0,0 +1,782 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecurrenceFrequency is the FREQ of a recurrence rule. Only the frequencies collection schedules use are
+// supported.
+type RecurrenceFrequency string
+
+const (
+	FrequencyHourly  RecurrenceFrequency = "HOURLY"
+	FrequencyDaily   RecurrenceFrequency = "DAILY"
+	FrequencyWeekly  RecurrenceFrequency = "WEEKLY"
+	FrequencyMonthly RecurrenceFrequency = "MONTHLY"
+)
+
+// RecurrenceWeekday is a BYDAY entry. N selects the nth weekday of the month, counting from the end when
+// negative, and is only allowed for monthly rules; zero means every such weekday.
+type RecurrenceWeekday struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Recurrence is an RFC 5545 recurrence rule with its DTSTART, restricted to the parts collection schedules
+// use: FREQ, INTERVAL, COUNT, UNTIL, BYMONTHDAY, BYDAY, BYHOUR and BYMINUTE
+type Recurrence struct {
+	// Start is the DTSTART, its location is the time zone occurrences are computed in
+	Start time.Time
+
+	Frequency RecurrenceFrequency
+
+	// Interval between periods, zero is the same as one
+	Interval int
+
+	ByDay      []RecurrenceWeekday
+	ByMonthDay []int
+	ByHour     []int
+	ByMinute   []int
+
+	// Count and Until bound the rule, zero values leave it unbounded
+	Count int
+	Until time.Time
+}
+
+// Rules whose DTSTART lies this far in the past with no occurrence for as many consecutive periods can never
+// occur again, such as the 31st of every second February
+const maxEmptyRecurrencePeriods = 10000
+
+// Calendar day the friendly schedule specs anchor DTSTART to. It is a Monday so interval phases are stable.
+var recurrenceSpecAnchor = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var ordinalNames = map[string]int{
+	"first":  1,
+	"second": 2,
+	"third":  3,
+	"fourth": 4,
+	"fifth":  5,
+	"last":   -1,
+}
+
+// ParseRecurrence builds a recurrence from either an RRULE, with or without its DTSTART line, or a friendly
+// spec such as "weekdays at 02:00 UTC", "every 6 hours", "weekly on mon,thu at 22:30 Europe/Paris" or
+// "monthly on the first sunday at 03:00". Friendly specs start on 2024-01-01 in their time zone, UTC unless
+// one is given as the last word.
+func ParseRecurrence(spec string) (*Recurrence, error) {
+	spec = strings.TrimSpace(spec)
+	upper := strings.ToUpper(spec)
+	if strings.Contains(upper, "FREQ=") || strings.HasPrefix(upper, "DTSTART") || strings.HasPrefix(upper, "RRULE:") {
+		return ParseRRule(spec)
+	}
+	return parseRecurrenceSpec(spec)
+}
+
+// ParseRRule parses an RRULE, optionally preceded by a DTSTART line. Without DTSTART the rule starts at the
+// current minute in UTC.
+func ParseRRule(rule string) (*Recurrence, error) {
+	recurrence := &Recurrence{}
+	var sawRule bool
+	for _, line := range strings.FieldsFunc(rule, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		line = strings.TrimSpace(line)
+		upper := strings.ToUpper(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(upper, "DTSTART"):
+			start, err := parseDTStart(line)
+			if err != nil {
+				return nil, err
+			}
+			recurrence.Start = start
+		case strings.HasPrefix(upper, "RRULE:"), strings.HasPrefix(upper, "FREQ="):
+			if sawRule {
+				return nil, errors.New("only one RRULE is supported")
+			}
+			sawRule = true
+			if err := recurrence.parseRuleParts(strings.TrimPrefix(upper, "RRULE:")); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported recurrence line %q", line)
+		}
+	}
+	if !sawRule {
+		return nil, errors.New("no RRULE found")
+	}
+	if recurrence.Start.IsZero() {
+		recurrence.Start = time.Now().UTC().Truncate(time.Minute)
+	}
+	if err := recurrence.Validate(); err != nil {
+		return nil, err
+	}
+	return recurrence, nil
+}
+
+// DTSTART:20240101T020000Z, DTSTART;TZID=Europe/Paris:20240101T020000 or a floating time, taken as UTC
+func parseDTStart(line string) (time.Time, error) {
+	name, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid DTSTART %q", line)
+	}
+	location := time.UTC
+	for _, param := range strings.Split(name, ";")[1:] {
+		key, paramValue, _ := strings.Cut(param, "=")
+		if strings.EqualFold(key, "TZID") {
+			var err error
+			if location, err = time.LoadLocation(paramValue); err != nil {
+				return time.Time{}, fmt.Errorf("invalid DTSTART time zone: %w", err)
+			}
+		}
+	}
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if start, err := time.ParseInLocation(layout, value, location); err == nil {
+			if strings.HasSuffix(value, "Z") {
+				start = start.UTC()
+			}
+			return start, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid DTSTART %q", line)
+}
+
+func (r *Recurrence) parseRuleParts(rule string) error {
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("invalid RRULE part %q", part)
+		}
+		var err error
+		switch key {
+		case "FREQ":
+			r.Frequency = RecurrenceFrequency(value)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+		case "UNTIL":
+			r.Until, err = parseDTStart("UNTIL:" + value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseIntList(value)
+		case "BYHOUR":
+			r.ByHour, err = parseIntList(value)
+		case "BYMINUTE":
+			r.ByMinute, err = parseIntList(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "WKST":
+			// Weeks are computed from Monday, the RFC 5545 default
+			if value != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			err = errors.New("not supported")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid RRULE part %s: %w", part, err)
+		}
+	}
+	return nil
+}
+
+func parseIntList(value string) ([]int, error) {
+	var list []int
+	for _, item := range strings.Split(value, ",") {
+		number, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, number)
+	}
+	return list, nil
+}
+
+func parseByDay(value string) ([]RecurrenceWeekday, error) {
+	var days []RecurrenceWeekday
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+		weekday, ok := weekdayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+		day := RecurrenceWeekday{Weekday: weekday}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weekday %q", item)
+			}
+			day.N = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// Validate checks the rule only uses supported parts with values in range
+func (r *Recurrence) Validate() error {
+	switch r.Frequency {
+	case FrequencyHourly, FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+	case "":
+		return errors.New("recurrence has no frequency")
+	default:
+		return fmt.Errorf("unsupported recurrence frequency %s", r.Frequency)
+	}
+	if r.Start.IsZero() {
+		return errors.New("recurrence has no start")
+	}
+	if r.Interval < 0 {
+		return errors.New("recurrence interval must not be negative")
+	}
+	if r.Count < 0 {
+		return errors.New("recurrence count must not be negative")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("recurrence cannot have both a count and an until")
+	}
+	for _, day := range r.ByDay {
+		if day.Weekday < time.Sunday || day.Weekday > time.Saturday {
+			return fmt.Errorf("invalid weekday %d", day.Weekday)
+		}
+		if day.N != 0 && (r.Frequency != FrequencyMonthly || day.N < -5 || day.N > 5) {
+			return fmt.Errorf("invalid weekday ordinal %d for a %s rule", day.N, r.Frequency)
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Frequency == FrequencyWeekly {
+		return errors.New("BYMONTHDAY is not supported for a WEEKLY rule")
+	}
+	for _, day := range r.ByMonthDay {
+		if day == 0 || day < -31 || day > 31 {
+			return fmt.Errorf("invalid month day %d", day)
+		}
+	}
+	for _, hour := range r.ByHour {
+		if hour < 0 || hour > 23 {
+			return fmt.Errorf("invalid hour %d", hour)
+		}
+	}
+	for _, minute := range r.ByMinute {
+		if minute < 0 || minute > 59 {
+			return fmt.Errorf("invalid minute %d", minute)
+		}
+	}
+	return nil
+}
+
+// String formats the rule as the DTSTART and RRULE lines schedules store. Lists are sorted so equivalent
+// rules format the same.
+func (r *Recurrence) String() string {
+	var b strings.Builder
+	// Local has no TZID another host could load, so such starts are written in UTC
+	if location := r.Start.Location(); location == time.UTC || location == time.Local || location.String() == "Local" {
+		fmt.Fprintf(&b, "DTSTART:%s\n", r.Start.UTC().Format("20060102T150405Z"))
+	} else {
+		fmt.Fprintf(&b, "DTSTART;TZID=%s:%s\n", r.Start.Location(), r.Start.Format("20060102T150405"))
+	}
+
+	parts := []string{"FREQ=" + string(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+formatIntList(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := append([]RecurrenceWeekday(nil), r.ByDay...)
+		sort.Slice(days, func(i, j int) bool {
+			if days[i].N != days[j].N {
+				return days[i].N < days[j].N
+			}
+			return mondayIndex(days[i].Weekday) < mondayIndex(days[j].Weekday)
+		})
+		var codes []string
+		for _, day := range days {
+			code := strings.ToUpper(day.Weekday.String()[:2])
+			if day.N != 0 {
+				code = strconv.Itoa(day.N) + code
+			}
+			if len(codes) == 0 || codes[len(codes)-1] != code {
+				codes = append(codes, code)
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByHour) > 0 {
+		parts = append(parts, "BYHOUR="+formatIntList(r.ByHour))
+	}
+	if len(r.ByMinute) > 0 {
+		parts = append(parts, "BYMINUTE="+formatIntList(r.ByMinute))
+	}
+	b.WriteString("RRULE:" + strings.Join(parts, ";"))
+	return b.String()
+}
+
+func formatIntList(list []int) string {
+	sorted := uniqueSorted(list)
+	items := make([]string, len(sorted))
+	for i, item := range sorted {
+		items[i] = strconv.Itoa(item)
+	}
+	return strings.Join(items, ",")
+}
+
+func uniqueSorted(list []int) []int {
+	sorted := append([]int(nil), list...)
+	sort.Ints(sorted)
+	unique := sorted[:0]
+	for i, item := range sorted {
+		if i == 0 || item != sorted[i-1] {
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
+
+// Days since Monday, the start of RFC 5545 weeks
+func mondayIndex(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+// Next returns up to n occurrences strictly after the given time, fewer when the rule ends first
+func (r *Recurrence) Next(after time.Time, n int) []time.Time {
+	var occurrences []time.Time
+	if n <= 0 || r.Validate() != nil {
+		return occurrences
+	}
+	r.each(func(occurrence time.Time) bool {
+		if occurrence.After(after) {
+			occurrences = append(occurrences, occurrence)
+		}
+		return len(occurrences) < n
+	})
+	return occurrences
+}
+
+// Call yield for every occurrence in order, starting at DTSTART, until it returns false or the rule ends
+func (r *Recurrence) each(yield func(time.Time) bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	count := 0
+	for period, empty := 0, 0; empty < maxEmptyRecurrencePeriods; period++ {
+		candidates := r.period(period * interval)
+		if len(candidates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, candidate := range candidates {
+			if candidate.Before(r.Start) {
+				continue
+			}
+			if !r.Until.IsZero() && candidate.After(r.Until) {
+				return
+			}
+			count++
+			if !yield(candidate) || (r.Count > 0 && count >= r.Count) {
+				return
+			}
+		}
+	}
+}
+
+// The sorted candidate occurrences of the period offset frequency units from the one DTSTART falls in
+func (r *Recurrence) period(offset int) []time.Time {
+	start := r.Start
+	location := start.Location()
+	year, month, day := start.Date()
+
+	var days []time.Time
+	switch r.Frequency {
+	case FrequencyHourly:
+		hour := time.Date(year, month, day, start.Hour(), 0, 0, 0, location).Add(time.Duration(offset) * time.Hour)
+		var candidates []time.Time
+		for _, minute := range r.minutes() {
+			candidate := hour.Add(time.Duration(minute)*time.Minute + time.Duration(start.Second())*time.Second)
+			if r.matchesDay(candidate) && (len(r.ByHour) == 0 || containsInt(r.ByHour, candidate.Hour())) {
+				candidates = append(candidates, candidate)
+			}
+		}
+		return candidates
+	case FrequencyDaily:
+		days = []time.Time{time.Date(year, month, day+offset, 0, 0, 0, 0, location)}
+	case FrequencyWeekly:
+		monday := day - mondayIndex(start.Weekday()) + 7*offset
+		weekdays := []time.Weekday{start.Weekday()}
+		if len(r.ByDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, byDay := range r.ByDay {
+				weekdays = append(weekdays, byDay.Weekday)
+			}
+		}
+		for _, weekday := range weekdays {
+			days = append(days, time.Date(year, month, monday+mondayIndex(weekday), 0, 0, 0, 0, location))
+		}
+	case FrequencyMonthly:
+		days = r.monthDays(time.Date(year, month+time.Month(offset), 1, 0, 0, 0, 0, location))
+	}
+
+	var candidates []time.Time
+	for _, date := range days {
+		if r.Frequency != FrequencyMonthly && !r.matchesDay(date) {
+			continue
+		}
+		for _, hour := range r.hours() {
+			for _, minute := range r.minutes() {
+				candidates = append(candidates, time.Date(date.Year(), date.Month(), date.Day(), hour, minute, start.Second(), 0, location))
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	unique := candidates[:0]
+	for i, candidate := range candidates {
+		if i == 0 || !candidate.Equal(candidates[i-1]) {
+			unique = append(unique, candidate)
+		}
+	}
+	return unique
+}
+
+// The days of the month starting at first that BYMONTHDAY and BYDAY select, the DTSTART day when neither is set
+func (r *Recurrence) monthDays(first time.Time) []time.Time {
+	daysInMonth := first.AddDate(0, 1, -1).Day()
+	selected := map[int]bool{}
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, day := range r.ByMonthDay {
+			if day < 0 {
+				day = daysInMonth + day + 1
+			}
+			if day >= 1 && day <= daysInMonth {
+				selected[day] = true
+			}
+		}
+	case len(r.ByDay) == 0:
+		if day := r.Start.Day(); day <= daysInMonth {
+			selected[day] = true
+		}
+	}
+
+	// BYDAY expands the month when BYMONTHDAY is absent and limits it otherwise
+	byDay := map[int]bool{}
+	for _, weekdayRule := range r.ByDay {
+		var matching []int
+		for day := 1; day <= daysInMonth; day++ {
+			if first.AddDate(0, 0, day-1).Weekday() == weekdayRule.Weekday {
+				matching = append(matching, day)
+			}
+		}
+		switch {
+		case weekdayRule.N == 0:
+			for _, day := range matching {
+				byDay[day] = true
+			}
+		case weekdayRule.N > 0 && weekdayRule.N <= len(matching):
+			byDay[matching[weekdayRule.N-1]] = true
+		case weekdayRule.N < 0 && -weekdayRule.N <= len(matching):
+			byDay[matching[len(matching)+weekdayRule.N]] = true
+		}
+	}
+	if len(r.ByDay) > 0 {
+		if len(r.ByMonthDay) == 0 {
+			selected = byDay
+		} else {
+			for day := range selected {
+				if !byDay[day] {
+					delete(selected, day)
+				}
+			}
+		}
+	}
+
+	var days []time.Time
+	for day := 1; day <= daysInMonth; day++ {
+		if selected[day] {
+			days = append(days, first.AddDate(0, 0, day-1))
+		}
+	}
+	return days
+}
+
+// Whether a day passes the BYDAY and BYMONTHDAY limits of non-monthly rules
+func (r *Recurrence) matchesDay(date time.Time) bool {
+	if len(r.ByDay) > 0 {
+		found := false
+		for _, day := range r.ByDay {
+			found = found || day.Weekday == date.Weekday()
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.ByMonthDay) > 0 {
+		daysInMonth := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+		found := false
+		for _, day := range r.ByMonthDay {
+			found = found || day == date.Day() || daysInMonth+day+1 == date.Day()
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Recurrence) hours() []int {
+	if len(r.ByHour) > 0 {
+		return uniqueSorted(r.ByHour)
+	}
+	return []int{r.Start.Hour()}
+}
+
+func (r *Recurrence) minutes() []int {
+	if len(r.ByMinute) > 0 {
+		return uniqueSorted(r.ByMinute)
+	}
+	return []int{r.Start.Minute()}
+}
+
+func containsInt(list []int, value int) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse a friendly spec: a frequency phrase, an optional "at HH:MM" and an optional trailing time zone
+func parseRecurrenceSpec(spec string) (*Recurrence, error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid schedule %q: %s", spec, reason)
+	}
+	words := strings.Fields(strings.NewReplacer(", ", ",", " ,", ",").Replace(spec))
+	if len(words) == 0 {
+		return nil, invalid("empty")
+	}
+
+	location := time.UTC
+	if last := words[len(words)-1]; strings.EqualFold(last, "utc") || strings.EqualFold(last, "z") {
+		words = words[:len(words)-1]
+	} else if strings.Contains(last, "/") {
+		var err error
+		if location, err = time.LoadLocation(last); err != nil {
+			return nil, invalid(err.Error())
+		}
+		words = words[:len(words)-1]
+	}
+	for i := range words {
+		words[i] = strings.ToLower(words[i])
+	}
+
+	hour, minute := 0, 0
+	for i := 0; i < len(words); i++ {
+		if words[i] != "at" {
+			continue
+		}
+		if i+1 >= len(words) {
+			return nil, invalid(`"at" needs a time`)
+		}
+		clock, err := time.Parse("15:04", words[i+1])
+		if err != nil {
+			return nil, invalid(fmt.Sprintf("%q is not a HH:MM time", words[i+1]))
+		}
+		hour, minute = clock.Hour(), clock.Minute()
+		words = append(words[:i], words[i+2:]...)
+		break
+	}
+
+	recurrence := &Recurrence{
+		Start: time.Date(recurrenceSpecAnchor.Year(), recurrenceSpecAnchor.Month(), recurrenceSpecAnchor.Day(), hour, minute, 0, 0, location),
+	}
+	if len(words) == 0 {
+		return nil, invalid("no frequency")
+	}
+
+	rest := words[1:]
+	switch words[0] {
+	case "hourly":
+		recurrence.Frequency = FrequencyHourly
+	case "daily":
+		recurrence.Frequency = FrequencyDaily
+	case "weekly":
+		recurrence.Frequency = FrequencyWeekly
+	case "monthly":
+		recurrence.Frequency = FrequencyMonthly
+	case "weekdays", "weekends":
+		recurrence.Frequency = FrequencyWeekly
+		recurrence.ByDay = weekdaySet(words[0])
+	case "every":
+		if len(rest) == 0 {
+			return nil, invalid(`"every" needs a period`)
+		}
+		if interval, err := strconv.Atoi(rest[0]); err == nil {
+			if interval < 1 {
+				return nil, invalid("the interval must be positive")
+			}
+			recurrence.Interval = interval
+			rest = rest[1:]
+			if len(rest) == 0 {
+				return nil, invalid("the interval needs a unit")
+			}
+		}
+		unit := rest[0]
+		rest = rest[1:]
+		switch strings.TrimSuffix(unit, "s") {
+		case "hour":
+			recurrence.Frequency = FrequencyHourly
+		case "day":
+			recurrence.Frequency = FrequencyDaily
+		case "week":
+			recurrence.Frequency = FrequencyWeekly
+		case "month":
+			recurrence.Frequency = FrequencyMonthly
+		case "weekday", "weekend":
+			recurrence.Frequency = FrequencyWeekly
+			recurrence.ByDay = weekdaySet(strings.TrimSuffix(unit, "s") + "s")
+		default:
+			days, err := parseWeekdayNames(unit)
+			if err != nil {
+				return nil, invalid(fmt.Sprintf("unknown period %q", unit))
+			}
+			recurrence.Frequency = FrequencyWeekly
+			recurrence.ByDay = days
+		}
+	default:
+		return nil, invalid(fmt.Sprintf("unknown frequency %q", words[0]))
+	}
+	if recurrence.Interval == 1 {
+		recurrence.Interval = 0
+	}
+
+	if len(rest) > 0 {
+		if rest[0] != "on" || len(rest) < 2 {
+			return nil, invalid(fmt.Sprintf("unexpected %q", strings.Join(rest, " ")))
+		}
+		if err := recurrence.parseSpecDays(rest[1:]); err != nil {
+			return nil, invalid(err.Error())
+		}
+	}
+	if err := recurrence.Validate(); err != nil {
+		return nil, invalid(err.Error())
+	}
+	return recurrence, nil
+}
+
+// The words after "on": weekday names for weekly rules, "day 1,15", "the last day" or "the first monday"
+// for monthly ones
+func (r *Recurrence) parseSpecDays(words []string) error {
+	if words[0] == "the" {
+		words = words[1:]
+	}
+	switch {
+	case r.Frequency == FrequencyWeekly && len(words) == 1 && len(r.ByDay) == 0:
+		days, err := parseWeekdayNames(words[0])
+		if err != nil {
+			return err
+		}
+		r.ByDay = days
+	case r.Frequency == FrequencyMonthly && len(words) == 2 && (words[0] == "day" || words[0] == "days"):
+		days, err := parseIntList(words[1])
+		if err != nil {
+			return fmt.Errorf("%q is not a list of month days", words[1])
+		}
+		r.ByMonthDay = days
+	case r.Frequency == FrequencyMonthly && len(words) == 2 && words[0] == "last" && words[1] == "day":
+		r.ByMonthDay = []int{-1}
+	case r.Frequency == FrequencyMonthly && len(words) == 2:
+		n, ok := ordinalNames[words[0]]
+		if !ok {
+			return fmt.Errorf("unknown ordinal %q", words[0])
+		}
+		days, err := parseWeekdayNames(words[1])
+		if err != nil {
+			return err
+		}
+		for i := range days {
+			days[i].N = n
+		}
+		r.ByDay = days
+	default:
+		return fmt.Errorf("unexpected %q for a %s schedule", "on "+strings.Join(words, " "), strings.ToLower(string(r.Frequency)))
+	}
+	return nil
+}
+
+func parseWeekdayNames(list string) ([]RecurrenceWeekday, error) {
+	var days []RecurrenceWeekday
+	for _, name := range strings.Split(list, ",") {
+		weekday, ok := weekdayNames[strings.TrimSuffix(name, "s")]
+		if !ok {
+			if weekday, ok = weekdayNames[name]; !ok {
+				return nil, fmt.Errorf("unknown weekday %q", name)
+			}
+		}
+		days = append(days, RecurrenceWeekday{Weekday: weekday})
+	}
+	return days, nil
+}
+
+func weekdaySet(name string) []RecurrenceWeekday {
+	if name == "weekends" {
+		return []RecurrenceWeekday{{Weekday: time.Saturday}, {Weekday: time.Sunday}}
+	}
+	return []RecurrenceWeekday{
+		{Weekday: time.Monday},
+		{Weekday: time.Tuesday},
+		{Weekday: time.Wednesday},
+		{Weekday: time.Thursday},
+		{Weekday: time.Friday},
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		rule  string
+		after string
+		n     int
+		want  []string
+	}{
+		{
+			name:  "daily keeps the wall clock across the Paris DST changes",
+			rule:  "DTSTART;TZID=Europe/Paris:20240329T090000\nRRULE:FREQ=DAILY",
+			after: "2024-03-29T00:00:00Z",
+			n:     4,
+			want:  []string{"2024-03-29T08:00:00Z", "2024-03-30T08:00:00Z", "2024-03-31T07:00:00Z", "2024-04-01T07:00:00Z"},
+		},
+		{
+			name:  "weekly keeps the wall clock when Paris leaves DST",
+			rule:  "DTSTART;TZID=Europe/Paris:20241020T220000\nRRULE:FREQ=WEEKLY;BYDAY=SU",
+			after: "2024-10-01T00:00:00Z",
+			n:     2,
+			want:  []string{"2024-10-20T20:00:00Z", "2024-10-27T21:00:00Z"},
+		},
+		{
+			name:  "last sunday of the month",
+			rule:  "DTSTART:20240101T030000Z\nRRULE:FREQ=MONTHLY;BYDAY=-1SU",
+			after: "2024-01-01T00:00:00Z",
+			n:     4,
+			want:  []string{"2024-01-28T03:00:00Z", "2024-02-25T03:00:00Z", "2024-03-31T03:00:00Z", "2024-04-28T03:00:00Z"},
+		},
+		{
+			name:  "first monday of the month",
+			rule:  "DTSTART:20240101T030000Z\nRRULE:FREQ=MONTHLY;BYDAY=1MO",
+			after: "2024-01-01T03:00:00Z",
+			n:     2,
+			want:  []string{"2024-02-05T03:00:00Z", "2024-03-04T03:00:00Z"},
+		},
+		{
+			name:  "the 31st skips shorter months",
+			rule:  "DTSTART:20240101T000000Z\nRRULE:FREQ=MONTHLY;BYMONTHDAY=31",
+			after: "2024-01-01T00:00:00Z",
+			n:     4,
+			want:  []string{"2024-01-31T00:00:00Z", "2024-03-31T00:00:00Z", "2024-05-31T00:00:00Z", "2024-07-31T00:00:00Z"},
+		},
+		{
+			name:  "the last day of each month",
+			rule:  "DTSTART:20240101T000000Z\nRRULE:FREQ=MONTHLY;BYMONTHDAY=-1",
+			after: "2024-01-01T00:00:00Z",
+			n:     3,
+			want:  []string{"2024-01-31T00:00:00Z", "2024-02-29T00:00:00Z", "2024-03-31T00:00:00Z"},
+		},
+		{
+			name:  "count includes occurrences before the after time",
+			rule:  "DTSTART:20240101T020000Z\nRRULE:FREQ=DAILY;COUNT=3",
+			after: "2024-01-01T12:00:00Z",
+			n:     10,
+			want:  []string{"2024-01-02T02:00:00Z", "2024-01-03T02:00:00Z"},
+		},
+		{
+			name:  "until is inclusive",
+			rule:  "DTSTART:20240101T020000Z\nRRULE:FREQ=DAILY;UNTIL=20240103T020000Z",
+			after: "2024-01-01T00:00:00Z",
+			n:     10,
+			want:  []string{"2024-01-01T02:00:00Z", "2024-01-02T02:00:00Z", "2024-01-03T02:00:00Z"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			recurrence, err := ParseRRule(test.rule)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", test.rule, err)
+			}
+			after, err := time.Parse(time.RFC3339, test.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, occurrence := range recurrence.Next(after, test.n) {
+				got = append(got, occurrence.UTC().Format(time.RFC3339))
+			}
+			if strings.Join(got, " ") != strings.Join(test.want, " ") {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceValidateRejectsWeeklyByMonthDay(t *testing.T) {
+	if _, err := ParseRRule("DTSTART:20240101T000000Z\nRRULE:FREQ=WEEKLY;BYMONTHDAY=1"); err == nil {
+		t.Fatal("a weekly rule with BYMONTHDAY was accepted")
+	}
+}
+
+func TestRecurrenceStringRoundTrips(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := time.Date(2024, time.January, 1, 2, 0, 0, 0, time.Local)
+	for _, test := range []struct {
+		start time.Time
+		want  string
+	}{
+		{start: time.Date(2024, time.January, 1, 2, 0, 0, 0, paris), want: "DTSTART;TZID=Europe/Paris:20240101T020000\n"},
+		{start: local, want: "DTSTART:" + local.UTC().Format("20060102T150405Z") + "\n"},
+	} {
+		recurrence := &Recurrence{Start: test.start, Frequency: FrequencyDaily}
+		formatted := recurrence.String()
+		if !strings.HasPrefix(formatted, test.want) {
+			t.Errorf("got %q, want the prefix %q", formatted, test.want)
+		}
+		parsed, err := ParseRRule(formatted)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", formatted, err)
+		}
+		if !parsed.Start.Equal(test.start) {
+			t.Errorf("round trip started at %s, want %s", parsed.Start, test.start)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// TokenSecret is a freshly minted API token handed to a TokenSecretSink
+type TokenSecret struct {
+	TokenID   string    `json:"token_id"`
+	TokenKey  string    `json:"token_key"`
+	Name      string    `json:"name,omitempty"`
+	UserId    string    `json:"user_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenSecretSink stores a new token where the services using it will pick it up
+type TokenSecretSink interface {
+	Store(ctx context.Context, secret TokenSecret) error
+}
+
+// FileTokenSecretSink writes the token as a JSON document readable only by the owner
+type FileTokenSecretSink struct {
+	Path string
+}
+
+func (s *FileTokenSecretSink) Store(ctx context.Context, secret TokenSecret) error {
+	content, err := json.MarshalIndent(secret, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Path, content)
+}
+
+// EnvFileTokenSecretSink updates the token variables of a KEY=value env file, keeping every other line. The
+// variable names default to API_TOKEN_KEY and API_TOKEN_ID, as used by the examples.
+type EnvFileTokenSecretSink struct {
+	Path   string
+	KeyVar string
+	IDVar  string
+}
+
+func (s *EnvFileTokenSecretSink) Store(ctx context.Context, secret TokenSecret) error {
+	keyVar, idVar := s.KeyVar, s.IDVar
+	if keyVar == "" {
+		keyVar = "API_TOKEN_KEY"
+	}
+	if idVar == "" {
+		idVar = "API_TOKEN_ID"
+	}
+
+	var lines []string
+	if content, err := os.ReadFile(s.Path); err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			name, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "export "), "=")
+			if name == keyVar || name == idVar {
+				continue
+			}
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	lines = append(lines, keyVar+"="+secret.TokenKey, idVar+"="+secret.TokenID)
+
+	return writeFileAtomic(s.Path, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+// ExecTokenSecretSink runs a hook command to store the token. The secret is passed in the environment as
+// BLOODHOUND_TOKEN_ID, BLOODHOUND_TOKEN_KEY and BLOODHOUND_TOKEN_NAME, never on the command line.
+type ExecTokenSecretSink struct {
+	Command []string
+}
+
+func (s *ExecTokenSecretSink) Store(ctx context.Context, secret TokenSecret) error {
+	if len(s.Command) == 0 {
+		return errors.New("no secret sink command configured")
+	}
+	cmd := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"BLOODHOUND_TOKEN_ID="+secret.TokenID,
+		"BLOODHOUND_TOKEN_KEY="+secret.TokenKey,
+		"BLOODHOUND_TOKEN_NAME="+secret.Name,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret sink command failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// TokenRotator replaces an API token with a new one without a window where no valid token is stored
+type TokenRotator struct {
+	// Client used to create and delete tokens
+	Client ClientWithResponsesInterface
+
+	// Server and HTTPClient are used to build the client that checks the new token
+	Server     string
+	HTTPClient HttpRequestDoer
+
+	Sink TokenSecretSink
+}
+
+func NewTokenRotator(client ClientWithResponsesInterface, server string, sink TokenSecretSink) (*TokenRotator, error) {
+	if client == nil {
+		return nil, errors.New("client must not be nil")
+	}
+	if sink == nil {
+		return nil, errors.New("secret sink must not be nil")
+	}
+	return &TokenRotator{
+		Client: client,
+		Server: server,
+		Sink:   sink,
+	}, nil
+}
+
+// TokenRotation describes a completed rotation
+type TokenRotation struct {
+	UserId     openapi_types.UUID
+	OldTokenId openapi_types.UUID
+	NewTokenId openapi_types.UUID
+	Name       string
+}
+
+// Mint a new token for the user, check it authenticates with GetSelf, hand it to the sink and only then delete
+// the old token. The old token must belong to the user, so a wrong id cannot revoke another user's token. The
+// check runs first so the sink never receives a token that does not work. If any step before the deletion fails
+// the new token is deleted again and the old one is left untouched.
+func (r *TokenRotator) Rotate(ctx context.Context, userId openapi_types.UUID, oldTokenId openapi_types.UUID, name string) (*TokenRotation, error) {
+	if err := r.checkOwner(ctx, userId, oldTokenId); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = "rotated " + time.Now().UTC().Format(time.RFC3339)
+	}
+
+	created, err := r.Client.CreateAuthTokenWithResponse(ctx, nil, CreateAuthTokenJSONRequestBody{
+		TokenName: ptr(name),
+		UserId:    ptr(userId),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created.StatusCode() != 200 || created.JSON200 == nil || created.JSON200.Data == nil {
+		return nil, newStatusError("CreateAuthToken", created.StatusCode(), created.Body)
+	}
+	token := created.JSON200.Data
+	if token.Id == nil || token.Key == nil {
+		return nil, errors.New("created token is missing its id or key")
+	}
+
+	secret := TokenSecret{
+		TokenID:   token.Id.String(),
+		TokenKey:  *token.Key,
+		Name:      name,
+		UserId:    userId.String(),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := r.verify(ctx, secret, userId); err != nil {
+		return nil, r.rollback(ctx, *token.Id, fmt.Errorf("error verifying new token: %w", err))
+	}
+	if err := r.Sink.Store(ctx, secret); err != nil {
+		return nil, r.rollback(ctx, *token.Id, fmt.Errorf("error storing new token: %w", err))
+	}
+
+	deleted, err := r.Client.DeleteAuthTokenWithResponse(ctx, oldTokenId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new token %s is in place but the old token was not deleted: %w", token.Id, err)
+	}
+	if deleted.StatusCode() != 200 && deleted.StatusCode() != 204 {
+		return nil, fmt.Errorf("new token %s is in place but the old token was not deleted: %w", token.Id, newStatusError("DeleteAuthToken", deleted.StatusCode(), deleted.Body))
+	}
+
+	return &TokenRotation{
+		UserId:     userId,
+		OldTokenId: oldTokenId,
+		NewTokenId: *token.Id,
+		Name:       name,
+	}, nil
+}
+
+// Check through ListAuthTokens that the token exists and belongs to the user
+func (r *TokenRotator) checkOwner(ctx context.Context, userId, tokenId openapi_types.UUID) error {
+	// The generated id param is a bare uuid with no room for the predicate, so the filter is added before signing.
+	// Other clients list every token and rely on the id check below.
+	raw, sent, err := doWithQuery(ctx, r.Client, func(server string) (*http.Request, error) {
+		return NewListAuthTokensRequest(server, &ListAuthTokensParams{})
+	}, url.Values{"id": {"eq:" + tokenId.String()}})
+	if err != nil {
+		return err
+	}
+	var response *ListAuthTokensResponse
+	if sent {
+		response, err = ParseListAuthTokensResponse(raw)
+	} else {
+		response, err = r.Client.ListAuthTokensWithResponse(ctx, &ListAuthTokensParams{})
+	}
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != 200 || response.JSON200 == nil {
+		return newStatusError("ListAuthTokens", response.StatusCode(), response.Body)
+	}
+	if data := response.JSON200.Data; data != nil && data.Tokens != nil {
+		for _, token := range *data.Tokens {
+			if token.Id == nil || *token.Id != tokenId {
+				continue
+			}
+			if token.UserId == nil || token.UserId.Uuid == nil || *token.UserId.Uuid != userId {
+				return fmt.Errorf("token %s does not belong to user %s", tokenId, userId)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("token %s not found", tokenId)
+}
+
+func (r *TokenRotator) verify(ctx context.Context, secret TokenSecret, userId openapi_types.UUID) error {
+	credentials, err := NewSecurityProviderHMACCredentials(secret.TokenKey, secret.TokenID)
+	if err != nil {
+		return err
+	}
+	options := []ClientOption{WithRequestEditorFn(credentials.Intercept)}
+	if r.HTTPClient != nil {
+		options = append(options, WithHTTPClient(r.HTTPClient))
+	}
+	client, err := NewClientWithResponses(r.Server, options...)
+	if err != nil {
+		return err
+	}
+
+	self, err := client.GetSelfWithResponse(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if self.StatusCode() != 200 || self.JSON200 == nil || self.JSON200.Data == nil {
+		return newStatusError("GetSelf", self.StatusCode(), self.Body)
+	}
+	user, err := self.JSON200.Data.AsModelUser()
+	if err != nil {
+		return err
+	}
+	if user.Id == nil || *user.Id != userId {
+		return errors.New("new token authenticates as a different user")
+	}
+	return nil
+}
+
+func (r *TokenRotator) rollback(ctx context.Context, tokenId openapi_types.UUID, cause error) error {
+	response, err := r.Client.DeleteAuthTokenWithResponse(ctx, tokenId, nil)
+	if err != nil {
+		return errors.Join(cause, fmt.Errorf("error deleting new token %s: %w", tokenId, err))
+	}
+	if response.StatusCode() != 200 && response.StatusCode() != 204 {
+		return errors.Join(cause, fmt.Errorf("error deleting new token %s: %w", tokenId, newStatusError("DeleteAuthToken", response.StatusCode(), response.Body)))
+	}
+	return cause
+}
+
+// TokenIssue is a reason a token is flagged by the token report
+type TokenIssue string
+
+const (
+	TokenIssueStale     TokenIssue = "stale"
+	TokenIssueNeverUsed TokenIssue = "never_used"
+	TokenIssueUnnamed   TokenIssue = "unnamed"
+)
+
+// TokenReportEntry is a token flagged by the report together with the reasons why
+type TokenReportEntry struct {
+	Token  ModelAuthToken `json:"token"`
+	Issues []TokenIssue   `json:"issues"`
+}
+
+// TokenReport lists the tokens that should be rotated or removed
+type TokenReport struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	StaleAfter  time.Duration      `json:"stale_after"`
+	Entries     []TokenReportEntry `json:"entries"`
+}
+
+// Flag tokens not used within staleAfter, tokens older than staleAfter that were never used, and tokens without
+// a name. params may be nil to report on every token visible to the client.
+func BuildTokenReport(ctx context.Context, client ClientWithResponsesInterface, params *ListAuthTokensParams, staleAfter time.Duration) (*TokenReport, error) {
+	response, err := client.ListAuthTokensWithResponse(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != 200 || response.JSON200 == nil {
+		return nil, newStatusError("ListAuthTokens", response.StatusCode(), response.Body)
+	}
+
+	report := &TokenReport{
+		GeneratedAt: time.Now().UTC(),
+		StaleAfter:  staleAfter,
+	}
+	if response.JSON200.Data == nil || response.JSON200.Data.Tokens == nil {
+		return report, nil
+	}
+
+	cutoff := report.GeneratedAt.Add(-staleAfter)
+	for _, token := range *response.JSON200.Data.Tokens {
+		var issues []TokenIssue
+		if token.LastAccess == nil || token.LastAccess.IsZero() {
+			if token.CreatedAt != nil && token.CreatedAt.Before(cutoff) {
+				issues = append(issues, TokenIssueNeverUsed)
+			}
+		} else if token.LastAccess.Before(cutoff) {
+			issues = append(issues, TokenIssueStale)
+		}
+		if token.Name == nil || token.Name.String == nil || strings.TrimSpace(*token.Name.String) == "" {
+			issues = append(issues, TokenIssueUnnamed)
+		}
+		if len(issues) > 0 {
+			report.Entries = append(report.Entries, TokenReportEntry{Token: token, Issues: issues})
+		}
+	}
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return len(report.Entries[i].Issues) > len(report.Entries[j].Issues)
+	})
+	return report, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+func TestTokenRotatorRefusesTokenOfAnotherUser(t *testing.T) {
+	userId := openapi_types.UUID{1}
+	otherUserId := openapi_types.UUID{2}
+	oldTokenId := openapi_types.UUID{3}
+
+	var listed int
+	var mutations []string
+	client := signedTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet && r.URL.Path == "/api/v2/tokens" {
+			if id := r.URL.Query().Get("id"); !strings.HasPrefix(id, "eq:") {
+				t.Errorf("tokens listed with id filter %q", id)
+			}
+			listed++
+			fmt.Fprintf(w, `{"data":{"tokens":[{"id":%q,"user_id":{"uuid":%q,"valid":true}}]}}`, oldTokenId, otherUserId)
+			return
+		}
+		mutations = append(mutations, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	rotator, err := NewTokenRotator(client, client.ClientInterface.(*Client).Server, &FileTokenSecretSink{Path: filepath.Join(t.TempDir(), "token.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rotator.Rotate(context.Background(), userId, oldTokenId, ""); err == nil {
+		t.Fatal("rotated a token that belongs to another user")
+	}
+	if _, err := rotator.Rotate(context.Background(), userId, openapi_types.UUID{4}, ""); err == nil {
+		t.Fatal("rotated a token that does not exist")
+	}
+	if listed != 2 {
+		t.Errorf("tokens listed %d times, want one signed listing per rotation", listed)
+	}
+	if len(mutations) > 0 {
+		t.Errorf("tokens were changed: %v", mutations)
+	}
+}
+
+func TestWriteFileAtomicReplacesFileWithoutLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token.env")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "new\n" {
+		t.Errorf("content is %q, want %q", content, "new\n")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode is %v, want 0600", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the written file", len(entries))
+	}
+}
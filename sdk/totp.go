@@ -0,0 +1,252 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TOTP generates RFC 6238 time-based one-time passwords, the codes authenticator apps show
+type TOTP struct {
+	Secret []byte
+
+	// Digits defaults to 6, Period to 30 seconds and Algorithm to SHA1
+	Digits    int
+	Period    time.Duration
+	Algorithm string
+
+	// Issuer and Account label the secret in an otpauth URI
+	Issuer  string
+	Account string
+}
+
+// ParseTOTP reads a TOTP secret given either as base32, as returned in the totp_secret of AddUserMfa, or as
+// an otpauth://totp/ URI, the payload of the enrollment QR code. QR code images cannot be read; pass the
+// totp_secret instead.
+func ParseTOTP(value string) (*TOTP, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(value, "data:image/"):
+		return nil, errors.New("TOTP secret is a QR code image, which cannot be read; use the totp_secret")
+	case strings.HasPrefix(strings.ToLower(value), "otpauth:"):
+		return parseOtpauthURI(value)
+	}
+	secret, err := decodeTOTPSecret(value)
+	if err != nil {
+		return nil, err
+	}
+	return &TOTP{Secret: secret}, nil
+}
+
+func parseOtpauthURI(value string) (*TOTP, error) {
+	uri, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+	if !strings.EqualFold(uri.Host, "totp") {
+		return nil, fmt.Errorf("unsupported otpauth type %q, only totp is supported", uri.Host)
+	}
+	query := uri.Query()
+	secret, err := decodeTOTPSecret(query.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+	totp := &TOTP{Secret: secret, Issuer: query.Get("issuer"), Algorithm: strings.ToUpper(query.Get("algorithm"))}
+
+	// The label is "issuer:account" or just the account
+	label := strings.TrimPrefix(uri.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		totp.Account = strings.TrimSpace(account)
+		if totp.Issuer == "" {
+			totp.Issuer = issuer
+		}
+	} else {
+		totp.Account = label
+	}
+	if digits := query.Get("digits"); digits != "" {
+		if totp.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, fmt.Errorf("invalid otpauth digits %q", digits)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		seconds, err := strconv.Atoi(period)
+		if err != nil {
+			return nil, fmt.Errorf("invalid otpauth period %q", period)
+		}
+		totp.Period = time.Duration(seconds) * time.Second
+	}
+	return totp, totp.Validate()
+}
+
+// Base32 as authenticator apps accept it: any case, spaces and dashes between groups, padding optional
+func decodeTOTPSecret(value string) ([]byte, error) {
+	cleaned := strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "=", "").Replace(value))
+	if cleaned == "" {
+		return nil, errors.New("TOTP secret is empty")
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("TOTP secret is not base32: %w", err)
+	}
+	return secret, nil
+}
+
+// Validate checks the secret is present and the settings are supported
+func (t *TOTP) Validate() error {
+	if len(t.Secret) == 0 {
+		return errors.New("TOTP secret is empty")
+	}
+	if t.Digits != 0 && (t.Digits < 6 || t.Digits > 10) {
+		return fmt.Errorf("TOTP digits must be between 6 and 10, got %d", t.Digits)
+	}
+	if t.Period < 0 || (t.Period > 0 && t.Period%time.Second != 0) {
+		return fmt.Errorf("TOTP period must be a whole number of seconds, got %s", t.Period)
+	}
+	if _, err := t.hash(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *TOTP) digits() int {
+	if t.Digits == 0 {
+		return 6
+	}
+	return t.Digits
+}
+
+func (t *TOTP) period() time.Duration {
+	if t.Period <= 0 {
+		return 30 * time.Second
+	}
+	return t.Period
+}
+
+func (t *TOTP) hash() (func() hash.Hash, error) {
+	switch t.Algorithm {
+	case "", "SHA1":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported TOTP algorithm %q", t.Algorithm)
+	}
+}
+
+// Code returns the one-time password valid at the given time
+func (t *TOTP) Code(at time.Time) (string, error) {
+	if err := t.Validate(); err != nil {
+		return "", err
+	}
+	newHash, _ := t.hash()
+	counter := uint64(at.Unix()) / uint64(t.period()/time.Second)
+
+	// RFC 4226 HOTP with dynamic truncation over the time step counter
+	mac := hmac.New(newHash, t.Secret)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff)
+
+	modulus := uint64(1)
+	for i := 0; i < t.digits(); i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", t.digits(), value%modulus), nil
+}
+
+// Now returns the current one-time password. Close to the end of its time step, the code of the next step is
+// returned after waiting for it to start, so the server does not see a code that has just expired. The wait
+// ends early with the context's error when it is cancelled.
+func (t *TOTP) Now(ctx context.Context) (string, error) {
+	now := time.Now()
+	period := t.period()
+	if remaining := period - time.Duration(now.UnixNano()%int64(period)); remaining < 2*time.Second {
+		timer := time.NewTimer(remaining)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case now = <-timer.C:
+		}
+	}
+	return t.Code(now)
+}
+
+// Verify reports whether code is valid at the given time, allowing skew time steps either side
+func (t *TOTP) Verify(code string, at time.Time, skew int) bool {
+	for step := -skew; step <= skew; step++ {
+		expected, err := t.Code(at.Add(time.Duration(step) * t.period()))
+		if err == nil && hmac.Equal([]byte(expected), []byte(code)) {
+			return true
+		}
+	}
+	return false
+}
+
+// URI returns the otpauth URI of the secret, which authenticator apps and password managers import
+func (t *TOTP) URI() string {
+	query := url.Values{}
+	query.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(t.Secret))
+	if t.Issuer != "" {
+		query.Set("issuer", t.Issuer)
+	}
+	if t.Algorithm != "" {
+		query.Set("algorithm", t.Algorithm)
+	}
+	if t.Digits != 0 {
+		query.Set("digits", strconv.Itoa(t.Digits))
+	}
+	if t.Period != 0 {
+		query.Set("period", strconv.Itoa(int(t.Period/time.Second)))
+	}
+	label := t.Account
+	if t.Issuer != "" {
+		label = t.Issuer + ":" + t.Account
+	}
+	return (&url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: query.Encode()}).String()
+}
+
+// LoginBody returns the body of a Login for a user with MFA, carrying both the secret and the current
+// one-time password
+func (t *TOTP) LoginBody(ctx context.Context, username, secret string) (LoginJSONRequestBody, error) {
+	otp, err := t.Now(ctx)
+	if err != nil {
+		return LoginJSONRequestBody{}, err
+	}
+	return LoginJSONRequestBody{
+		LoginMethod: Secret,
+		Username:    username,
+		Secret:      &secret,
+		Otp:         &otp,
+	}, nil
+}
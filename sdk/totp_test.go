@@ -0,0 +1,79 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// The test vectors of RFC 6238 Appendix B, whose seeds are the ASCII digits repeated to the hash size
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	seeds := map[string][]byte{
+		"SHA1":   []byte("12345678901234567890"),
+		"SHA256": []byte("12345678901234567890123456789012"),
+		"SHA512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	for _, test := range []struct {
+		unix      int64
+		algorithm string
+		want      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	} {
+		totp := &TOTP{Secret: seeds[test.algorithm], Digits: 8, Algorithm: test.algorithm}
+		code, err := totp.Code(time.Unix(test.unix, 0))
+		if err != nil {
+			t.Fatalf("%s at %d: %v", test.algorithm, test.unix, err)
+		}
+		if code != test.want {
+			t.Errorf("%s at %d: got %s, want %s", test.algorithm, test.unix, code, test.want)
+		}
+		if !totp.Verify(test.want, time.Unix(test.unix+30, 0), 1) {
+			t.Errorf("%s at %d: the code of the previous step was rejected with a skew of one", test.algorithm, test.unix)
+		}
+	}
+}
+
+func TestTOTPNowReturnsWhenCancelled(t *testing.T) {
+	// A one second period is always within two seconds of its end, so Now waits for the next step
+	totp := &TOTP{Secret: []byte("12345678901234567890"), Period: time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := totp.Now(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
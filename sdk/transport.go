@@ -0,0 +1,201 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// TransportConfig describes the http.Client used to talk to BloodHound. The zero value builds a client that
+// behaves like http.DefaultClient: proxies from the environment, HTTP/2 and the system certificate pool.
+type TransportConfig struct {
+	// CABundleFile is a PEM file of additional certificate authorities trusted for the server certificate
+	CABundleFile string
+
+	// RootCAs replaces the system certificate pool when set. CABundleFile is added to it.
+	RootCAs *x509.CertPool
+
+	// ClientCertFile and ClientKeyFile are the PEM certificate and key presented for mTLS
+	ClientCertFile string
+	ClientKeyFile  string
+
+	// PinnedCertificates are SHA-256 fingerprints, in hex with optional colons, of server certificates. When
+	// set, the connection is refused unless one certificate of the presented chain matches.
+	PinnedCertificates []string
+
+	// ProxyURL is an http, https, socks5 or socks5h proxy. When empty the proxy is taken from HTTPS_PROXY,
+	// HTTP_PROXY and NO_PROXY unless DisableProxy is set.
+	ProxyURL     string
+	DisableProxy bool
+
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+
+	// Timeout bounds a whole request on the built http.Client, including reading the body
+	Timeout time.Duration
+
+	// ResolveLocalhostSubdomains dials subdomain.localhost[:port] as localhost[:port] RFC 6761
+	ResolveLocalhostSubdomains bool
+}
+
+// NewTransport builds an http.Transport from the configuration
+func NewTransport(config TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if config.DialTimeout > 0 {
+		dialer.Timeout = config.DialTimeout
+	}
+	if config.KeepAlive != 0 {
+		dialer.KeepAlive = config.KeepAlive
+	}
+	transport.DialContext = dialer.DialContext
+	if config.ResolveLocalhostSubdomains {
+		transport.DialContext = localhostSubdomainDialContext(dialer.DialContext)
+	}
+
+	if config.DisableProxy {
+		transport.Proxy = nil
+	} else if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	}
+	if config.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	return transport, nil
+}
+
+// NewHTTPClient builds an http.Client from the configuration, ready to pass to WithHTTPClient
+func NewHTTPClient(config TransportConfig) (*http.Client, error) {
+	transport, err := NewTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+	}, nil
+}
+
+func (config TransportConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    config.RootCAs,
+	}
+
+	if config.CABundleFile != "" {
+		bundle, err := os.ReadFile(config.CABundleFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %w", err)
+		}
+		if tlsConfig.RootCAs == nil {
+			if tlsConfig.RootCAs, err = x509.SystemCertPool(); err != nil {
+				tlsConfig.RootCAs = x509.NewCertPool()
+			}
+		} else {
+			tlsConfig.RootCAs = tlsConfig.RootCAs.Clone()
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", config.CABundleFile)
+		}
+	}
+
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		if config.ClientCertFile == "" || config.ClientKeyFile == "" {
+			return nil, errors.New("both a client certificate and key are required for mTLS")
+		}
+		certificate, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if len(config.PinnedCertificates) > 0 {
+		pins := map[string]struct{}{}
+		for _, fingerprint := range config.PinnedCertificates {
+			normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+			if decoded, err := hex.DecodeString(normalized); err != nil || len(decoded) != sha256.Size {
+				return nil, fmt.Errorf("invalid SHA-256 certificate fingerprint %q", fingerprint)
+			}
+			pins[normalized] = struct{}{}
+		}
+
+		// Pinning is checked on top of the regular chain verification
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			for _, certificate := range state.PeerCertificates {
+				sum := sha256.Sum256(certificate.Raw)
+				if _, ok := pins[hex.EncodeToString(sum[:])]; ok {
+					return nil
+				}
+			}
+			return errors.New("server certificate does not match any pinned fingerprint")
+		}
+	}
+	return tlsConfig, nil
+}
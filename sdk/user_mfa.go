@@ -0,0 +1,115 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// MFAStatus returns whether multi-factor authentication of a user is activated, pending or deactivated
+func (c *ClientWithResponses) MFAStatus(ctx context.Context, userId openapi_types.UUID) (EnumMfaActivationStatus, error) {
+	response, err := c.GetMfaActivationStatusWithResponse(ctx, userId, nil)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil || response.JSON200.Data.Status == nil {
+		return "", newStatusError("GetMfaActivationStatus", response.StatusCode(), response.Body)
+	}
+	return *response.JSON200.Data.Status, nil
+}
+
+// EnrollMFA adds multi-factor authentication to a user, which takes their login secret, and activates it with
+// a code generated from the returned TOTP secret, so no authenticator app is needed. Keep the returned TOTP
+// to log in with LoginWithTOTP afterwards.
+func (c *ClientWithResponses) EnrollMFA(ctx context.Context, userId openapi_types.UUID, secret string) (*TOTP, error) {
+	added, err := c.AddUserMfaWithResponse(ctx, userId, nil, AddUserMfaJSONRequestBody{Secret: &secret})
+	if err != nil {
+		return nil, err
+	}
+	if added.StatusCode() != http.StatusOK || added.JSON200 == nil || added.JSON200.Data == nil {
+		return nil, newStatusError("AddUserMfa", added.StatusCode(), added.Body)
+	}
+
+	var totp *TOTP
+	if data := added.JSON200.Data; data.TotpSecret != nil && *data.TotpSecret != "" {
+		totp, err = ParseTOTP(*data.TotpSecret)
+	} else if data.QrCode != nil {
+		totp, err = ParseTOTP(*data.QrCode)
+	} else {
+		err = errors.New("AddUserMfa returned no TOTP secret")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	otp, err := totp.Now(ctx)
+	if err != nil {
+		return nil, err
+	}
+	activated, err := c.ActivateUserMfaWithResponse(ctx, userId, nil, ActivateUserMfaJSONRequestBody{Otp: &otp})
+	if err != nil {
+		return nil, err
+	}
+	if activated.StatusCode() != http.StatusOK {
+		return nil, newStatusError("ActivateUserMfa", activated.StatusCode(), activated.Body)
+	}
+
+	status, err := c.MFAStatus(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if status != Activated {
+		return nil, fmt.Errorf("MFA of user %s is %s after activation", userId, status)
+	}
+	return totp, nil
+}
+
+// RemoveMFA removes multi-factor authentication from a user, which takes their login secret
+func (c *ClientWithResponses) RemoveMFA(ctx context.Context, userId openapi_types.UUID, secret string) error {
+	response, err := c.RemoveUserMfaWithResponse(ctx, userId, nil, RemoveUserMfaJSONRequestBody{Secret: &secret})
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != http.StatusOK {
+		return newStatusError("RemoveUserMfa", response.StatusCode(), response.Body)
+	}
+	return nil
+}
+
+// LoginWithTOTP logs in a user with MFA and returns the session token
+func (c *ClientWithResponses) LoginWithTOTP(ctx context.Context, username, secret string, totp *TOTP) (string, error) {
+	body, err := totp.LoginBody(ctx, username, secret)
+	if err != nil {
+		return "", err
+	}
+	response, err := c.LoginWithResponse(ctx, nil, body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil || response.JSON200.Data == nil || response.JSON200.Data.SessionToken == nil {
+		return "", newStatusError("Login", response.StatusCode(), response.Body)
+	}
+	if data := response.JSON200.Data; data.AuthExpired != nil && *data.AuthExpired {
+		return "", errors.New("the secret has expired and must be reset")
+	}
+	return *response.JSON200.Data.SessionToken, nil
+}
@@ -0,0 +1,686 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	openapi_types "github.com/oapi-codegen/runtime/types"
+)
+
+// ProvisionedUser is a user as declared by a provisioning source. Empty names, email address and SAML
+// provider leave the values of an existing user as they are.
+type ProvisionedUser struct {
+	PrincipalName string   `json:"principal_name"`
+	FirstName     string   `json:"first_name,omitempty"`
+	LastName      string   `json:"last_name,omitempty"`
+	EmailAddress  string   `json:"email_address,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	Disabled      bool     `json:"disabled,omitempty"`
+
+	// SamlProviderId and Secret are only used when the user is created
+	SamlProviderId string `json:"saml_provider_id,omitempty"`
+	Secret         string `json:"-"`
+}
+
+// ParseProvisioningCSV reads users from CSV with a header row. The principal_name column is required; the
+// optional columns are first_name, last_name, email, roles (separated by ";", "," or "|"), disabled or
+// active, saml_provider_id and secret.
+func ParseProvisioningCSV(r io.Reader) ([]ProvisionedUser, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch name {
+		case "principal", "principalname", "username", "user_name":
+			name = "principal_name"
+		case "firstname", "given_name":
+			name = "first_name"
+		case "lastname", "family_name":
+			name = "last_name"
+		case "email_address", "mail":
+			name = "email"
+		case "password":
+			name = "secret"
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["principal_name"]; !ok {
+		return nil, errors.New("CSV has no principal_name column")
+	}
+
+	var users []ProvisionedUser
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return users, nil
+		} else if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		user := ProvisionedUser{
+			PrincipalName:  field("principal_name"),
+			FirstName:      field("first_name"),
+			LastName:       field("last_name"),
+			EmailAddress:   field("email"),
+			SamlProviderId: field("saml_provider_id"),
+			Secret:         field("secret"),
+		}
+		if user.PrincipalName == "" {
+			return nil, fmt.Errorf("CSV line %d has no principal name", line)
+		}
+		for _, role := range strings.FieldsFunc(field("roles"), func(r rune) bool { return r == ';' || r == ',' || r == '|' }) {
+			if role = strings.TrimSpace(role); role != "" {
+				user.Roles = append(user.Roles, role)
+			}
+		}
+		if value := field("disabled"); value != "" {
+			if user.Disabled, err = parseProvisioningBool(value); err != nil {
+				return nil, fmt.Errorf("CSV line %d: invalid disabled value %q", line, value)
+			}
+		} else if value := field("active"); value != "" {
+			active, err := parseProvisioningBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("CSV line %d: invalid active value %q", line, value)
+			}
+			user.Disabled = !active
+		}
+		users = append(users, user)
+	}
+}
+
+func parseProvisioningBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// The parts of the SCIM 2.0 User and Group schemas provisioning uses
+type scimResource struct {
+	Schemas  []string `json:"schemas"`
+	Id       string   `json:"id"`
+	UserName string   `json:"userName"`
+	Name     *struct {
+		GivenName  string `json:"givenName"`
+		FamilyName string `json:"familyName"`
+	} `json:"name"`
+	Emails []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	} `json:"emails"`
+	Active   *bool           `json:"active"`
+	Password string          `json:"password"`
+	Roles    []scimReference `json:"roles"`
+	Groups   []scimReference `json:"groups"`
+
+	DisplayName string          `json:"displayName"`
+	Members     []scimReference `json:"members"`
+}
+
+type scimReference struct {
+	Value   string `json:"value"`
+	Display string `json:"display"`
+}
+
+func (r scimResource) isGroup() bool {
+	for _, schema := range r.Schemas {
+		if strings.HasSuffix(schema, ":Group") {
+			return true
+		}
+	}
+	return r.UserName == "" && r.DisplayName != ""
+}
+
+// ParseSCIMUsers reads users from a SCIM 2.0 payload: a User or Group resource, a list of resources or a
+// ListResponse. User roles become role names. Groups, both Group resources and the groups of a user, grant
+// the role of the same name to their members; the groups no role is named after are left out by
+// PlanUserProvisioning.
+func ParseSCIMUsers(data []byte) ([]ProvisionedUser, error) {
+	var resources []scimResource
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &resources); err != nil {
+			return nil, fmt.Errorf("error reading SCIM resources: %w", err)
+		}
+	} else {
+		var envelope struct {
+			scimResource
+			Resources []scimResource `json:"Resources"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return nil, fmt.Errorf("error reading SCIM resources: %w", err)
+		}
+		if envelope.Resources != nil {
+			resources = envelope.Resources
+		} else {
+			resources = []scimResource{envelope.scimResource}
+		}
+	}
+
+	var (
+		users  []ProvisionedUser
+		groups []scimResource
+		byRef  = map[string]int{}
+	)
+	for _, resource := range resources {
+		if resource.isGroup() {
+			groups = append(groups, resource)
+			continue
+		}
+		if resource.UserName == "" {
+			return nil, errors.New("SCIM user has no userName")
+		}
+		user := ProvisionedUser{
+			PrincipalName: resource.UserName,
+			Secret:        resource.Password,
+			Disabled:      resource.Active != nil && !*resource.Active,
+		}
+		if resource.Name != nil {
+			user.FirstName, user.LastName = resource.Name.GivenName, resource.Name.FamilyName
+		}
+		for _, email := range resource.Emails {
+			if user.EmailAddress == "" || email.Primary {
+				user.EmailAddress = email.Value
+			}
+		}
+		for _, role := range resource.Roles {
+			user.Roles = appendReferenceName(user.Roles, role)
+		}
+		for _, group := range resource.Groups {
+			user.Roles = appendReferenceName(user.Roles, scimReference{Display: groupRolePrefix + referenceName(group)})
+		}
+		if resource.Id != "" {
+			byRef[resource.Id] = len(users)
+		}
+		byRef[strings.ToLower(resource.UserName)] = len(users)
+		users = append(users, user)
+	}
+
+	for _, group := range groups {
+		for _, member := range group.Members {
+			i, ok := byRef[member.Value]
+			if !ok {
+				i, ok = byRef[strings.ToLower(member.Value)]
+			}
+			if !ok {
+				i, ok = byRef[strings.ToLower(member.Display)]
+			}
+			if !ok {
+				// Members of a group-only payload are known by their display name, taken as the user name
+				if member.Display == "" {
+					return nil, fmt.Errorf("SCIM group %q has member %q that is not in the payload", group.DisplayName, member.Value)
+				}
+				i = len(users)
+				byRef[strings.ToLower(member.Display)] = i
+				users = append(users, ProvisionedUser{PrincipalName: member.Display})
+			}
+			users[i].Roles = appendReferenceName(users[i].Roles, scimReference{Display: groupRolePrefix + group.DisplayName})
+		}
+	}
+	return users, nil
+}
+
+// Roles granted through a group carry this prefix until PlanUserProvisioning resolves them, so groups that
+// are not named after a role can be left out rather than failing the plan
+const groupRolePrefix = "group:"
+
+func referenceName(reference scimReference) string {
+	if reference.Display != "" {
+		return reference.Display
+	}
+	return reference.Value
+}
+
+func appendReferenceName(names []string, reference scimReference) []string {
+	name := referenceName(reference)
+	if name == "" {
+		return names
+	}
+	for _, existing := range names {
+		if strings.EqualFold(existing, name) {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// UserProvisioningOptions controls defaults and safety of PlanUserProvisioning
+type UserProvisioningOptions struct {
+	// DefaultRoles are given to declared users that name no role
+	DefaultRoles []string
+
+	// SamlProviderId is given to created users that name no SAML provider
+	SamlProviderId string
+
+	// InitialSecret is set on created users that have neither a secret of their own nor a SAML provider.
+	// Users created with a secret must reset it on first login.
+	InitialSecret string
+
+	// DisableMissing disables the enabled users missing from the source. Without it only the declared users
+	// are created and updated, so a partial source never locks anyone out.
+	DisableMissing bool
+
+	// KeepPrincipals are never disabled. The authenticated user is never disabled either.
+	KeepPrincipals []string
+}
+
+// UserProvisioningAction is what a UserChange does
+type UserProvisioningAction string
+
+const (
+	UserProvisioningCreate  UserProvisioningAction = "create"
+	UserProvisioningUpdate  UserProvisioningAction = "update"
+	UserProvisioningDisable UserProvisioningAction = "disable"
+)
+
+// UserChange is one change of a provisioning plan. Differences lists the changed fields as
+// "field: old -> new" for the dry-run diff.
+type UserChange struct {
+	Action        UserProvisioningAction `json:"action"`
+	PrincipalName string                 `json:"principal_name"`
+	UserId        *openapi_types.UUID    `json:"user_id,omitempty"`
+	Differences   []string               `json:"differences,omitempty"`
+
+	create *CreateUserJSONRequestBody
+	update *UpdateUserJSONRequestBody
+}
+
+// UserProvisioningPlan lists the changes that bring the users of the server to the declared set
+type UserProvisioningPlan struct {
+	Changes   []UserChange `json:"changes"`
+	Unchanged []string     `json:"unchanged"`
+
+	// IgnoredGroups are SCIM groups no role is named after
+	IgnoredGroups []string `json:"ignored_groups,omitempty"`
+}
+
+// Diff formats the plan one change per line: + for creations, ~ for updates and - for users disabled
+func (p *UserProvisioningPlan) Diff() string {
+	var b strings.Builder
+	for _, change := range p.Changes {
+		marker := map[UserProvisioningAction]string{
+			UserProvisioningCreate:  "+",
+			UserProvisioningUpdate:  "~",
+			UserProvisioningDisable: "-",
+		}[change.Action]
+		fmt.Fprintf(&b, "%s %s %s\n", marker, change.Action, change.PrincipalName)
+		for _, difference := range change.Differences {
+			fmt.Fprintf(&b, "    %s\n", difference)
+		}
+	}
+	return b.String()
+}
+
+// PlanUserProvisioning compares the declared users with the users of the server, matched case-insensitively
+// by principal name, and resolves role names to role ids. Nothing is changed until the plan is applied.
+func (c *ClientWithResponses) PlanUserProvisioning(ctx context.Context, declared []ProvisionedUser, options UserProvisioningOptions) (*UserProvisioningPlan, error) {
+	roles, roleNames, err := c.roleIds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := c.usersByPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := map[string]bool{}
+	for _, name := range options.KeepPrincipals {
+		keep[strings.ToLower(name)] = true
+	}
+	// Planning fails rather than risk disabling the account running the sync
+	self, err := c.self(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error identifying the authenticated user: %w", err)
+	}
+	if self.PrincipalName == nil {
+		return nil, errors.New("the authenticated user has no principal name")
+	}
+	keep[strings.ToLower(*self.PrincipalName)] = true
+
+	plan := &UserProvisioningPlan{}
+	ignoredGroups := map[string]bool{}
+	seen := map[string]bool{}
+	var unknownRoles []string
+	for _, user := range declared {
+		key := strings.ToLower(user.PrincipalName)
+		if key == "" {
+			return nil, errors.New("every provisioned user needs a principal name")
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("user %q is declared twice", user.PrincipalName)
+		}
+		seen[key] = true
+
+		names := user.Roles
+		if len(names) == 0 {
+			names = options.DefaultRoles
+		}
+		var roleIds []int32
+		for _, name := range names {
+			group := strings.HasPrefix(name, groupRolePrefix)
+			name = strings.TrimPrefix(name, groupRolePrefix)
+			id, ok := roles[strings.ToLower(name)]
+			switch {
+			case ok:
+				roleIds = appendRoleId(roleIds, id)
+			case group:
+				ignoredGroups[name] = true
+			default:
+				unknownRoles = append(unknownRoles, name)
+			}
+		}
+
+		current, exists := existing[key]
+		if !exists {
+			change, err := plannedCreate(user, roleIds, roleNames, options)
+			if err != nil {
+				return nil, err
+			}
+			plan.Changes = append(plan.Changes, change)
+		} else if change, changed := plannedUpdate(current, user, roleIds, roleNames); changed {
+			plan.Changes = append(plan.Changes, change)
+		} else {
+			plan.Unchanged = append(plan.Unchanged, *current.PrincipalName)
+		}
+	}
+	if len(unknownRoles) > 0 {
+		sort.Strings(unknownRoles)
+		return nil, fmt.Errorf("unknown roles: %s", strings.Join(unknownRoles, ", "))
+	}
+	plan.IgnoredGroups = sortedKeys(ignoredGroups)
+
+	if options.DisableMissing {
+		for _, key := range sortedKeys(existing) {
+			current := existing[key]
+			if seen[key] || keep[key] || (current.IsDisabled != nil && *current.IsDisabled) {
+				continue
+			}
+			body := currentUserUpdate(current)
+			body.IsDisabled = ptr(true)
+			plan.Changes = append(plan.Changes, UserChange{
+				Action:        UserProvisioningDisable,
+				PrincipalName: *current.PrincipalName,
+				UserId:        current.Id,
+				update:        &body,
+			})
+		}
+	}
+	return plan, nil
+}
+
+func appendRoleId(ids []int32, id int32) []int32 {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func plannedCreate(user ProvisionedUser, roleIds []int32, roleNames map[int32]string, options UserProvisioningOptions) (UserChange, error) {
+	if len(roleIds) == 0 {
+		return UserChange{}, fmt.Errorf("user %q has no role to be created with", user.PrincipalName)
+	}
+	body := CreateUserJSONRequestBody{
+		Principal:  ptr(user.PrincipalName),
+		FirstName:  ptr(user.FirstName),
+		LastName:   ptr(user.LastName),
+		Roles:      ptr(roleIds),
+		IsDisabled: ptr(user.Disabled),
+	}
+	if user.EmailAddress != "" {
+		body.EmailAddress = ptr(openapi_types.Email(user.EmailAddress))
+	}
+	samlProviderId := user.SamlProviderId
+	if samlProviderId == "" {
+		samlProviderId = options.SamlProviderId
+	}
+	secret := user.Secret
+	if secret == "" && samlProviderId == "" {
+		secret = options.InitialSecret
+	}
+	if samlProviderId != "" {
+		body.SamlProviderId = ptr(samlProviderId)
+	} else if secret != "" {
+		body.Secret = ptr(secret)
+		body.NeedsPasswordReset = ptr(true)
+	}
+
+	change := UserChange{
+		Action:        UserProvisioningCreate,
+		PrincipalName: user.PrincipalName,
+		create:        &body,
+		Differences:   []string{fmt.Sprintf("roles: %s", formatRoles(roleIds, roleNames))},
+	}
+	switch {
+	case body.SamlProviderId != nil:
+		change.Differences = append(change.Differences, "saml provider: "+samlProviderId)
+	case body.Secret != nil:
+		change.Differences = append(change.Differences, "initial secret: set")
+	}
+	if user.Disabled {
+		change.Differences = append(change.Differences, "disabled: true")
+	}
+	return change, nil
+}
+
+// The update body that keeps every field of a user as it is, since updates replace all of them
+func currentUserUpdate(current ModelUser) UpdateUserJSONRequestBody {
+	body := UpdateUserJSONRequestBody{
+		Principal:  current.PrincipalName,
+		FirstName:  ptr(nullStringValue(current.FirstName)),
+		LastName:   ptr(nullStringValue(current.LastName)),
+		IsDisabled: ptr(current.IsDisabled != nil && *current.IsDisabled),
+		Roles:      ptr(currentRoleIds(current)),
+	}
+	if email := nullStringValue(current.EmailAddress); email != "" {
+		body.EmailAddress = ptr(openapi_types.Email(email))
+	}
+	if id := current.SamlProviderId; id != nil && id.Valid != nil && *id.Valid && id.Int32 != nil {
+		body.SamlProviderId = ptr(strconv.Itoa(int(*id.Int32)))
+	}
+	return body
+}
+
+func plannedUpdate(current ModelUser, user ProvisionedUser, roleIds []int32, roleNames map[int32]string) (UserChange, bool) {
+	body := currentUserUpdate(current)
+	var differences []string
+	compare := func(field string, target **string, value string) {
+		if value == "" || (*target != nil && **target == value) {
+			return
+		}
+		differences = append(differences, fmt.Sprintf("%s: %q -> %q", field, str(*target), value))
+		*target = ptr(value)
+	}
+	compare("first name", &body.FirstName, user.FirstName)
+	compare("last name", &body.LastName, user.LastName)
+	compare("saml provider", &body.SamlProviderId, user.SamlProviderId)
+	if user.EmailAddress != "" && (body.EmailAddress == nil || !strings.EqualFold(string(*body.EmailAddress), user.EmailAddress)) {
+		differences = append(differences, fmt.Sprintf("email: %q -> %q", nullStringValue(current.EmailAddress), user.EmailAddress))
+		body.EmailAddress = ptr(openapi_types.Email(user.EmailAddress))
+	}
+	if len(roleIds) > 0 && formatRoleIds(roleIds) != formatRoleIds(*body.Roles) {
+		differences = append(differences, fmt.Sprintf("roles: %s -> %s", formatRoles(*body.Roles, roleNames), formatRoles(roleIds, roleNames)))
+		body.Roles = ptr(roleIds)
+	}
+	if *body.IsDisabled != user.Disabled {
+		differences = append(differences, fmt.Sprintf("disabled: %t -> %t", *body.IsDisabled, user.Disabled))
+		body.IsDisabled = ptr(user.Disabled)
+	}
+	if len(differences) == 0 {
+		return UserChange{}, false
+	}
+	return UserChange{
+		Action:        UserProvisioningUpdate,
+		PrincipalName: *current.PrincipalName,
+		UserId:        current.Id,
+		Differences:   differences,
+		update:        &body,
+	}, true
+}
+
+func currentRoleIds(user ModelUser) []int32 {
+	ids := []int32{}
+	if user.Roles != nil {
+		for _, role := range *user.Roles {
+			if role.Id != nil {
+				ids = appendRoleId(ids, *role.Id)
+			}
+		}
+	}
+	return ids
+}
+
+func formatRoleIds(ids []int32) string {
+	sorted := make([]int, len(ids))
+	for i, id := range ids {
+		sorted[i] = int(id)
+	}
+	return "[" + formatIntList(sorted) + "]"
+}
+
+// Role names for the diff, in the case ListRoles returned them
+func formatRoles(ids []int32, names map[int32]string) string {
+	labels := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			labels = append(labels, name)
+		} else {
+			labels = append(labels, strconv.Itoa(int(id)))
+		}
+	}
+	sort.Strings(labels)
+	return "[" + strings.Join(labels, ", ") + "]"
+}
+
+func nullStringValue(value *NullString) string {
+	if value == nil || value.Valid == nil || !*value.Valid || value.String == nil {
+		return ""
+	}
+	return *value.String
+}
+
+func str(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// ApplyUserProvisioning makes the changes of a plan. Every change is attempted, the errors of those that
+// failed are joined.
+func (c *ClientWithResponses) ApplyUserProvisioning(ctx context.Context, plan *UserProvisioningPlan) error {
+	var errs []error
+	for _, change := range plan.Changes {
+		var err error
+		switch {
+		case change.create != nil:
+			var response *CreateUserResponse
+			if response, err = c.CreateUserWithResponse(ctx, nil, *change.create); err == nil && response.StatusCode() != http.StatusOK {
+				err = newStatusError("CreateUser", response.StatusCode(), response.Body)
+			}
+		case change.update != nil && change.UserId != nil:
+			var response *UpdateUserResponse
+			if response, err = c.UpdateUserWithResponse(ctx, *change.UserId, nil, *change.update); err == nil && response.StatusCode() != http.StatusOK {
+				err = newStatusError("UpdateUser", response.StatusCode(), response.Body)
+			}
+		default:
+			err = errors.New("change was not planned by PlanUserProvisioning")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error applying %s of %s: %w", change.Action, change.PrincipalName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ProvisionUsers plans and applies the changes that bring the users of the server to the declared set,
+// returning the plan that was applied
+func (c *ClientWithResponses) ProvisionUsers(ctx context.Context, declared []ProvisionedUser, options UserProvisioningOptions) (*UserProvisioningPlan, error) {
+	plan, err := c.PlanUserProvisioning(ctx, declared, options)
+	if err != nil {
+		return nil, err
+	}
+	return plan, c.ApplyUserProvisioning(ctx, plan)
+}
+
+// Role ids by lower case role name, and role names as the server spells them by role id
+func (c *ClientWithResponses) roleIds(ctx context.Context) (map[string]int32, map[int32]string, error) {
+	response, err := c.ListRolesWithResponse(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, nil, newStatusError("ListRoles", response.StatusCode(), response.Body)
+	}
+	roles := map[string]int32{}
+	names := map[int32]string{}
+	if response.JSON200.Data != nil && response.JSON200.Data.Roles != nil {
+		for _, role := range *response.JSON200.Data.Roles {
+			if role.Id != nil && role.Name != nil {
+				roles[strings.ToLower(*role.Name)] = *role.Id
+				names[*role.Id] = *role.Name
+			}
+		}
+	}
+	return roles, names, nil
+}
+
+// Users by lower case principal name
+func (c *ClientWithResponses) usersByPrincipal(ctx context.Context) (map[string]ModelUser, error) {
+	response, err := c.ListUsersWithResponse(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode() != http.StatusOK || response.JSON200 == nil {
+		return nil, newStatusError("ListUsers", response.StatusCode(), response.Body)
+	}
+	users := map[string]ModelUser{}
+	if response.JSON200.Data != nil && response.JSON200.Data.Users != nil {
+		for _, user := range *response.JSON200.Data.Users {
+			if user.PrincipalName != nil && user.Id != nil {
+				users[strings.ToLower(*user.PrincipalName)] = user
+			}
+		}
+	}
+	return users, nil
+}
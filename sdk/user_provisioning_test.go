@@ -0,0 +1,106 @@
+// Copyright 2023 Specter Ops, Inc.
+//
+// Licensed under the Apache License, Version 2.0
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func provisioningServer(t *testing.T, selfStatus int) *ClientWithResponses {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/roles":
+			fmt.Fprint(w, `{"data":{"roles":[{"id":1,"name":"Administrator"},{"id":3,"name":"Read-Only"}]}}`)
+		case "/api/v2/bloodhound-users":
+			fmt.Fprint(w, `{"data":{"users":[
+				{"id":"11111111-1111-1111-1111-111111111111","principal_name":"operator","roles":[{"id":1}]},
+				{"id":"22222222-2222-2222-2222-222222222222","principal_name":"bob","roles":[{"id":3}]}]}}`)
+		case "/api/v2/self":
+			if selfStatus != http.StatusOK {
+				w.WriteHeader(selfStatus)
+				return
+			}
+			fmt.Fprint(w, `{"data":{"id":"11111111-1111-1111-1111-111111111111","principal_name":"operator"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClientWithResponses(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestPlanUserProvisioningDisablesMissingUsersOnlyWhenAsked(t *testing.T) {
+	declared := []ProvisionedUser{{PrincipalName: "alice", Roles: []string{"Read-Only"}}}
+
+	plan, err := provisioningServer(t, http.StatusOK).PlanUserProvisioning(context.Background(), declared, UserProvisioningOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range plan.Changes {
+		if change.Action == UserProvisioningDisable {
+			t.Errorf("%s is disabled without DisableMissing", change.PrincipalName)
+		}
+	}
+
+	plan, err = provisioningServer(t, http.StatusOK).PlanUserProvisioning(context.Background(), declared, UserProvisioningOptions{DisableMissing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var disabled []string
+	for _, change := range plan.Changes {
+		if change.Action == UserProvisioningDisable {
+			disabled = append(disabled, change.PrincipalName)
+		}
+	}
+	if strings.Join(disabled, ",") != "bob" {
+		t.Errorf("disabled %v, want only bob and never the authenticated operator", disabled)
+	}
+}
+
+func TestPlanUserProvisioningFailsWithoutSelf(t *testing.T) {
+	declared := []ProvisionedUser{{PrincipalName: "alice"}}
+	_, err := provisioningServer(t, http.StatusTooManyRequests).PlanUserProvisioning(context.Background(), declared, UserProvisioningOptions{DisableMissing: true})
+	if err == nil {
+		t.Fatal("planning succeeded although the authenticated user is unknown")
+	}
+}
+
+func TestPlanUserProvisioningNamesRolesAsTheServerSpellsThem(t *testing.T) {
+	declared := []ProvisionedUser{{PrincipalName: "alice", Roles: []string{"read-only"}}}
+	client := provisioningServer(t, http.StatusOK)
+	// Map iteration order varies between runs, so a name picked from several spellings shows up within a few plans
+	for i := 0; i < 20; i++ {
+		plan, err := client.PlanUserProvisioning(context.Background(), declared, UserProvisioningOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan.Changes) != 1 || strings.Join(plan.Changes[0].Differences, "\n") != "roles: [Read-Only]" {
+			t.Fatalf("plan changes are %+v, want alice created with the Read-Only role", plan.Changes)
+		}
+	}
+}
@@ -18,8 +18,13 @@ package sdk
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -30,20 +35,118 @@ func GetLocalhostWithSubdomainHttpClient() (*http.Client, *http.Client) {
 		Timeout: 5 * time.Second,
 	}
 
-	customDialContext := func(ctx context.Context, network, addr string) (net.Conn, error) {
+	customTransport := &http.Transport{
+		DialContext: localhostSubdomainDialContext(dialer.DialContext),
+	}
+
+	return &http.Client{
+		Transport: customTransport,
+	}, nil
+
+}
+
+// Wrap dialContext so subdomain.localhost[:port] is dialed as localhost[:port] RFC 6761
+func localhostSubdomainDialContext(dialContext func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 		domainOnly := addr[strings.LastIndex(addr, ".")+1:]
 		if strings.HasPrefix(domainOnly, "localhost") {
 			addr = domainOnly
 		}
-		return dialer.DialContext(ctx, network, addr)
+		return dialContext(ctx, network, addr)
 	}
+}
 
-	customTransport := &http.Transport{
-		DialContext: customDialContext,
+// StatusError is returned by the SDK helpers when the server answers with an unexpected status code
+type StatusError struct {
+	Operation  string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: unexpected status code %d", e.Operation, e.StatusCode)
+}
+
+func newStatusError(operation string, statusCode int, body []byte) error {
+	return &StatusError{
+		Operation:  operation,
+		StatusCode: statusCode,
+		Body:       body,
 	}
+}
 
-	return &http.Client{
-		Transport: customTransport,
-	}, nil
+// Send the request built by newRequest with query added to it through the editors of client, for filters the generated
+// params cannot express. The query is final before the client's editors run, so HMAC signing covers it. The second
+// result is false when client does not wrap the generated *Client and nothing was sent.
+func doWithQuery(ctx context.Context, client ClientWithResponsesInterface, newRequest func(server string) (*http.Request, error), query url.Values) (*http.Response, bool, error) {
+	withResponses, ok := client.(*ClientWithResponses)
+	if !ok {
+		return nil, false, nil
+	}
+	generated, ok := withResponses.ClientInterface.(*Client)
+	if !ok {
+		return nil, false, nil
+	}
+	req, err := newRequest(generated.Server)
+	if err != nil {
+		return nil, true, err
+	}
+	values := req.URL.Query()
+	for key, list := range query {
+		for _, value := range list {
+			values.Add(key, value)
+		}
+	}
+	req.URL.RawQuery = values.Encode()
+	req = req.WithContext(ctx)
+	if err := generated.applyEditors(ctx, req, nil); err != nil {
+		return nil, true, err
+	}
+	response, err := generated.Client.Do(req)
+	return response, true, err
+}
+
+// Return a pointer to a copy of value, handy for the optional fields of generated params and bodies
+func ptr[T any](value T) *T {
+	return &value
+}
 
+// Return the keys of a map in sorted order, for deterministic output
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Write content to a temporary file first and rename it into place, so a crash never leaves a truncated file
+func writeFileAtomic(path string, content []byte) error {
+	// The temporary file is unique and in the same directory, so concurrent writers do not share it and the
+	// rename stays on one file system
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
 }